@@ -0,0 +1,66 @@
+// Package api2c2ptest provides utilities for testing code that uses api2c2p,
+// without needing the PEM key files that api2c2p.NewClient reads
+package api2c2ptest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	api2c2p "github.com/choonkeat/2c2p"
+)
+
+var (
+	stubKeyOnce sync.Once
+	stubKey     *rsa.PrivateKey
+	stubCert    *x509.Certificate
+	stubKeyErr  error
+)
+
+// StubKeys returns an in-memory RSA private key and matching self-signed certificate.
+// The pair is generated once per process and shared by every caller.
+func StubKeys() (*rsa.PrivateKey, *x509.Certificate, error) {
+	stubKeyOnce.Do(func() {
+		stubKey, stubKeyErr = rsa.GenerateKey(rand.Reader, 2048)
+		if stubKeyErr != nil {
+			return
+		}
+		template := x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject: pkix.Name{
+				CommonName: "api2c2ptest stub",
+			},
+			NotBefore: time.Now(),
+			NotAfter:  time.Now().AddDate(1, 0, 0),
+			KeyUsage:  x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		}
+		var derBytes []byte
+		derBytes, stubKeyErr = x509.CreateCertificate(rand.Reader, &template, &template, &stubKey.PublicKey, stubKey)
+		if stubKeyErr != nil {
+			return
+		}
+		stubCert, stubKeyErr = x509.ParseCertificate(derBytes)
+	})
+	return stubKey, stubCert, stubKeyErr
+}
+
+// NewMockClient returns a client for secretKey and merchantID that sends every request to url,
+// e.g. an httptest.Server. The stub keys from StubKeys stand in for both our key pair
+// and 2C2P's certificates, so it suits request-building tests but cannot talk to 2C2P.
+func NewMockClient(secretKey, merchantID, url string) (*api2c2p.Client, error) {
+	privateKey, cert, err := StubKeys()
+	if err != nil {
+		return nil, fmt.Errorf("generate stub keys: %w", err)
+	}
+	return api2c2p.NewClientWithKeys(api2c2p.Config{
+		SecretKey:         secretKey,
+		MerchantID:        merchantID,
+		PaymentGatewayURL: url,
+		FrontendURL:       url,
+	}, privateKey, cert, cert, cert)
+}
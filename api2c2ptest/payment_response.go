@@ -0,0 +1,25 @@
+package api2c2ptest
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+
+	api2c2p "github.com/choonkeat/2c2p"
+	"github.com/fullsailor/pkcs7"
+)
+
+// EncryptPaymentResponse returns resp as 2C2P posts it in the `paymentResponse` form value:
+// base64 PKCS7 enveloped XML, encrypted for cert, e.g. the PublicCert of a client under test
+func EncryptPaymentResponse(cert *x509.Certificate, resp api2c2p.PaymentResponseBackEnd) (string, error) {
+	data, err := xml.Marshal(resp)
+	if err != nil {
+		return "", fmt.Errorf("marshal payment response: %w", err)
+	}
+	encrypted, err := pkcs7.Encrypt(data, []*x509.Certificate{cert})
+	if err != nil {
+		return "", fmt.Errorf("encrypt payment response: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(encrypted), nil
+}
@@ -0,0 +1,34 @@
+package api2c2ptest
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	api2c2p "github.com/choonkeat/2c2p"
+)
+
+func TestEncryptPaymentResponse(t *testing.T) {
+	client, err := NewMockClient("test_secret", "JT01", "http://localhost")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	want := api2c2p.PaymentResponseBackEnd{
+		RespCode:              "00",
+		Status:                "A",
+		Amount:                "000000010010",
+		UniqueTransactionCode: "INV123",
+	}
+
+	encrypted, err := EncryptPaymentResponse(client.PublicCert, want)
+	if err != nil {
+		t.Fatalf("EncryptPaymentResponse failed: %v", err)
+	}
+	got, _, err := client.DecryptPaymentResponseBackend(&http.Request{PostForm: url.Values{"paymentResponse": {encrypted}}})
+	if err != nil {
+		t.Fatalf("DecryptPaymentResponseBackend failed: %v", err)
+	}
+	if got.UniqueTransactionCode != "INV123" || got.Amount != "000000010010" || !got.IsSuccess() {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+}
@@ -0,0 +1,73 @@
+package api2c2p
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+)
+
+// CaptureResponse represents the response from a capture (settle) request
+type CaptureResponse struct {
+	XMLName        xml.Name `xml:"PaymentProcessResponse"`
+	Version        string   `xml:"version"`
+	TimeStamp      string   `xml:"timeStamp"`
+	MerchantID     string   `xml:"merchantID"`
+	InvoiceNo      string   `xml:"invoiceNo,omitempty"`
+	ActionAmount   string   `xml:"actionAmount,omitempty"`
+	ProcessType    string   `xml:"processType"`
+	RespCode       string   `xml:"respCode"`
+	RespDesc       string   `xml:"respDesc"`
+	ApprovalCode   string   `xml:"approvalCode,omitempty"`
+	ReferenceNo    string   `xml:"referenceNo,omitempty"`
+	TransactionID  string   `xml:"transactionID,omitempty"`
+	TransactionRef string   `xml:"transactionRef,omitempty"`
+
+	// IdempotencyID is the idempotency ID sent with the request, e.g. generated by AutoIdempotency
+	IdempotencyID string `xml:"-"`
+}
+
+// Capture settles amount of a payment that was only authorized, i.e. created without
+// ImmediatePayment. The invoice is inquired first so that amount can be checked
+// against the authorized amount; a partial capture settles less than was authorized
+func (c *Client) Capture(ctx context.Context, invoiceNo string, amount Cents) (*CaptureResponse, error) {
+	if invoiceNo == "" {
+		return nil, fmt.Errorf("invoice number is required")
+	}
+	if amount <= 0 {
+		return nil, fmt.Errorf("capture amount must be greater than 0")
+	}
+	inquiry, err := c.Inquire(ctx, InquiryQuery{InvoiceNo: invoiceNo})
+	if err != nil {
+		return nil, fmt.Errorf("inquire invoice %s: %w", invoiceNo, err)
+	}
+	if authorized := inquiry.AmountCents(); amount > authorized {
+		return nil, fmt.Errorf("capture amount %s exceeds authorized amount %s", amount.ToDollars(), authorized.ToDollars())
+	}
+
+	req := &PaymentProcessRequest{
+		Version:      "4.3",
+		MerchantID:   c.MerchantID,
+		InvoiceNo:    invoiceNo,
+		ActionAmount: amount.ToDollars(),
+		ProcessType:  ProcessSettle,
+	}
+	if id := c.idempotencyID(""); id != "" {
+		req.IdempotencyID = &id
+	}
+
+	var resp CaptureResponse
+	if err := c.PerformPaymentProcess(ctx, req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to process capture request: %w", err)
+	}
+	if req.IdempotencyID != nil {
+		resp.IdempotencyID = *req.IdempotencyID
+	}
+	if !PaymentResponseCode(resp.RespCode).IsSuccess() {
+		return &resp, &APIError{
+			Endpoint: "capture",
+			RespCode: PaymentResponseCode(resp.RespCode),
+			RespDesc: resp.RespDesc,
+		}
+	}
+	return &resp, nil
+}
@@ -0,0 +1,87 @@
+package api2c2p
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCapture(t *testing.T) {
+	cfg := testConfig()
+	cfg.ServerJWTPublicKeyFile = "testdata/public_cert.pem" // we have to decrypt what we encrypted in this test
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	var sent []byte
+	var captured bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/paymentInquiry") {
+			token, err := client.generateJWTTokenForJSON([]byte(`{"respCode":"0000","respDesc":"Success","invoiceNo":"INV123","amount":25.00}`))
+			if err != nil {
+				t.Errorf("Failed to sign response: %v", err)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"payload": token})
+			return
+		}
+		captured = true
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("Failed to read request body: %v", err)
+			return
+		}
+		sent, err = client.verifyJWSAndDecryptJWE(string(body))
+		if err != nil {
+			t.Errorf("Failed to verify and decrypt request: %v", err)
+			return
+		}
+		signedJWE, err := client.encryptJWEAndSignJWS([]byte(`<PaymentProcessResponse>
+			<invoiceNo>INV123</invoiceNo>
+			<actionAmount>20.00</actionAmount>
+			<processType>S</processType>
+			<respCode>00</respCode>
+			<respDesc>Success</respDesc>
+			<approvalCode>123456</approvalCode>
+		</PaymentProcessResponse>`))
+		if err != nil {
+			t.Errorf("Failed to encrypt response: %v", err)
+			return
+		}
+		w.Write([]byte(signedJWE))
+	}))
+	defer ts.Close()
+	client.PaymentGatewayURL = ts.URL
+	client.FrontendURL = ts.URL
+
+	resp, err := client.Capture(ctx, "INV123", 2000)
+	if err != nil {
+		t.Fatalf("Capture failed: %v", err)
+	}
+	for _, want := range []string{
+		"<processType>S</processType>",
+		"<invoiceNo>INV123</invoiceNo>",
+		"<actionAmount>20.00</actionAmount>",
+		"<merchantID>JT01</merchantID>",
+	} {
+		if !bytes.Contains(sent, []byte(want)) {
+			t.Errorf("Expected request to contain %s, got %s", want, sent)
+		}
+	}
+	if resp.ProcessType != "S" || resp.ActionAmount != "20.00" || resp.ApprovalCode != "123456" {
+		t.Errorf("Expected decoded capture response, got %+v", resp)
+	}
+
+	captured = false
+	if _, err := client.Capture(ctx, "INV123", 2501); err == nil || !strings.Contains(err.Error(), "exceeds authorized amount 25.00") {
+		t.Errorf("Expected error for capturing more than authorized, got %v", err)
+	}
+	if captured {
+		t.Error("Expected no capture request when the amount exceeds the authorized amount")
+	}
+}
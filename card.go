@@ -0,0 +1,130 @@
+package api2c2p
+
+import (
+	"strconv"
+	"strings"
+)
+
+// MaskedPan is a card number with the middle digits masked, e.g. "411111XXXXXX1111"
+type MaskedPan string
+
+// CardBrand is the card network, as inferred from the BIN
+type CardBrand string
+
+// Card brands, see MaskedPan.Brand
+const (
+	CardBrandUnknown    CardBrand = ""
+	CardBrandVisa       CardBrand = "Visa"
+	CardBrandMastercard CardBrand = "Mastercard"
+	CardBrandAmex       CardBrand = "Amex"
+	CardBrandJCB        CardBrand = "JCB"
+	CardBrandUnionPay   CardBrand = "UnionPay"
+	CardBrandDiscover   CardBrand = "Discover"
+	CardBrandDiners     CardBrand = "Diners"
+)
+
+// CardScheme is a card brand parsed from 2C2P's paymentScheme or processBy, keeping the raw value
+type CardScheme struct {
+	Brand CardBrand
+	Raw   string
+}
+
+// cardSchemeBrands maps 2C2P payment scheme codes and names, see
+// docs/2c2p/reference-codes-payment-scheme.csv, to card brands
+var cardSchemeBrands = map[string]CardBrand{
+	"VI":              CardBrandVisa,
+	"VISA":            CardBrandVisa,
+	"EVI":             CardBrandVisa,
+	"MA":              CardBrandMastercard,
+	"MASTERCARD":      CardBrandMastercard,
+	"MASTER":          CardBrandMastercard,
+	"EMA":             CardBrandMastercard,
+	"AM":              CardBrandAmex,
+	"AMEX":            CardBrandAmex,
+	"JC":              CardBrandJCB,
+	"JCB":             CardBrandJCB,
+	"UP":              CardBrandUnionPay,
+	"CUP":             CardBrandUnionPay,
+	"UNIONPAY":        CardBrandUnionPay,
+	"CHINA UNION PAY": CardBrandUnionPay,
+	"DI":              CardBrandDiscover,
+	"DISCOVER":        CardBrandDiscover,
+	"DN":              CardBrandDiners,
+	"DINERS":          CardBrandDiners,
+}
+
+// ParseCardScheme maps a 2C2P payment scheme code or name, e.g. "VI" or "MASTERCARD",
+// to its card brand; Brand is CardBrandUnknown for anything else, e.g. "GRABPAY"
+func ParseCardScheme(raw string) CardScheme {
+	return CardScheme{
+		Brand: cardSchemeBrands[strings.ToUpper(strings.TrimSpace(raw))],
+		Raw:   raw,
+	}
+}
+
+// String returns the brand, or the raw value if the brand is unknown
+func (s CardScheme) String() string {
+	if s.Brand != CardBrandUnknown {
+		return string(s.Brand)
+	}
+	return s.Raw
+}
+
+// digits returns p without spaces or dashes, or "" unless it has the length of a card number
+func (p MaskedPan) digits() string {
+	s := strings.NewReplacer(" ", "", "-", "").Replace(string(p))
+	if len(s) < 12 || len(s) > 19 {
+		return ""
+	}
+	return s
+}
+
+// Bin returns the first 6 digits, the bank identification number, or "" if they are masked or malformed
+func (p MaskedPan) Bin() string {
+	s := p.digits()
+	if s == "" || !isDigits(s[:6]) {
+		return ""
+	}
+	return s[:6]
+}
+
+// Last4 returns the last 4 digits, or "" if they are masked or malformed
+func (p MaskedPan) Last4() string {
+	s := p.digits()
+	if s == "" || !isDigits(s[len(s)-4:]) {
+		return ""
+	}
+	return s[len(s)-4:]
+}
+
+// Brand infers the card network from the BIN ranges of Visa, Mastercard, Amex, JCB and UnionPay
+func (p MaskedPan) Brand() CardBrand {
+	bin := p.Bin()
+	if bin == "" {
+		return CardBrandUnknown
+	}
+	prefix2, _ := strconv.Atoi(bin[:2])
+	prefix4, _ := strconv.Atoi(bin[:4])
+	switch {
+	case bin[0] == '4':
+		return CardBrandVisa
+	case prefix2 >= 51 && prefix2 <= 55, prefix4 >= 2221 && prefix4 <= 2720:
+		return CardBrandMastercard
+	case prefix2 == 34, prefix2 == 37:
+		return CardBrandAmex
+	case prefix4 >= 3528 && prefix4 <= 3589:
+		return CardBrandJCB
+	case prefix2 == 62:
+		return CardBrandUnionPay
+	}
+	return CardBrandUnknown
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,93 @@
+package api2c2p
+
+import "testing"
+
+func TestMaskedPan(t *testing.T) {
+	testCases := []struct {
+		pan   MaskedPan
+		bin   string
+		last4 string
+		brand CardBrand
+	}{
+		{"411111XXXXXX1111", "411111", "1111", CardBrandVisa},
+		{"555555XXXXXX4444", "555555", "4444", CardBrandMastercard},
+		{"222300XXXXXX0010", "222300", "0010", CardBrandMastercard},
+		{"378282XXXXX0005", "378282", "0005", CardBrandAmex},
+		{"340000XXXXX0009", "340000", "0009", CardBrandAmex},
+		{"353011XXXXXX0000", "353011", "0000", CardBrandJCB},
+		{"621483XXXXXX7779", "621483", "7779", CardBrandUnionPay},
+		{"601100XXXXXX0004", "601100", "0004", CardBrandUnknown},
+		{"4111-11XX-XXXX-1111", "411111", "1111", CardBrandVisa},
+
+		// malformed
+		{"", "", "", CardBrandUnknown},
+		{"1111", "", "", CardBrandUnknown},
+		{"XXXXXXXXXXXX1111", "", "1111", CardBrandUnknown},
+		{"411111XXXXXXXXXX", "411111", "", CardBrandVisa},
+		{"411111XXXXXX11111111", "", "", CardBrandUnknown},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.pan), func(t *testing.T) {
+			if got := tc.pan.Bin(); got != tc.bin {
+				t.Errorf("Expected Bin %q, got %q", tc.bin, got)
+			}
+			if got := tc.pan.Last4(); got != tc.last4 {
+				t.Errorf("Expected Last4 %q, got %q", tc.last4, got)
+			}
+			if got := tc.pan.Brand(); got != tc.brand {
+				t.Errorf("Expected Brand %q, got %q", tc.brand, got)
+			}
+		})
+	}
+}
+
+func TestParseCardScheme(t *testing.T) {
+	testCases := []struct {
+		raw    string
+		brand  CardBrand
+		string string
+	}{
+		{"VI", CardBrandVisa, "Visa"},
+		{"MA", CardBrandMastercard, "Mastercard"},
+		{"AM", CardBrandAmex, "Amex"},
+		{"JC", CardBrandJCB, "JCB"},
+		{"UP", CardBrandUnionPay, "UnionPay"},
+		{"DI", CardBrandDiscover, "Discover"},
+		{"DN", CardBrandDiners, "Diners"},
+		{"EVI", CardBrandVisa, "Visa"},
+		{"CHINA UNION PAY", CardBrandUnionPay, "UnionPay"},
+		{"mastercard", CardBrandMastercard, "Mastercard"},
+		{"GP", CardBrandUnknown, "GP"},
+		{"CREDIT", CardBrandUnknown, "CREDIT"},
+		{"", CardBrandUnknown, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.raw, func(t *testing.T) {
+			scheme := ParseCardScheme(tc.raw)
+			if scheme.Brand != tc.brand {
+				t.Errorf("Expected Brand %q, got %q", tc.brand, scheme.Brand)
+			}
+			if scheme.Raw != tc.raw {
+				t.Errorf("Expected Raw %q, got %q", tc.raw, scheme.Raw)
+			}
+			if got := scheme.String(); got != tc.string {
+				t.Errorf("Expected String %q, got %q", tc.string, got)
+			}
+		})
+	}
+
+	backend := PaymentResponseBackEnd{CardType: "CREDIT", ProcessBy: "VI", PaymentScheme: "MA"}
+	if got := backend.CardScheme().Brand; got != CardBrandMastercard {
+		t.Errorf("Expected backend paymentScheme brand %q, got %q", CardBrandMastercard, got)
+	}
+	backend.PaymentScheme = ""
+	if got := backend.CardScheme().Brand; got != CardBrandVisa {
+		t.Errorf("Expected backend processBy brand %q, got %q", CardBrandVisa, got)
+	}
+	inquiry := PaymentInquiryResponse{ChannelCode: "JC"}
+	if got := inquiry.CardScheme().Brand; got != CardBrandJCB {
+		t.Errorf("Expected inquiry channelCode brand %q, got %q", CardBrandJCB, got)
+	}
+}
@@ -1,15 +1,28 @@
 package api2c2p
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
 	"encoding/pem"
 	"fmt"
+	"io"
+	"math/big"
 	"net/http"
 	"os"
+	"path"
+	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/go-jose/go-jose/v4"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 // Client represents a 2C2P API client
@@ -23,6 +36,18 @@ type Client struct {
 	// httpClient is the HTTP client used for making requests
 	httpClient *LoggingClient
 
+	// observer is notified of every API call made through do
+	observer Observer
+
+	// logger is shared with httpClient
+	logger Logger
+
+	// now is the clock for request timestamps
+	now func() time.Time
+
+	// closed is set by Close
+	closed atomic.Bool
+
 	// PaymentGatewayURL is the base URL for payment gateway API requests (e.g. payment inquiry)
 	// Default: https://sandbox-pgw.2c2p.com
 	PaymentGatewayURL string
@@ -31,13 +56,58 @@ type Client struct {
 	// Default: https://demo2.2c2p.com
 	FrontendURL string
 
-	// PrivateKeyFile is the path to the combined private key and certificate PEM file
-	PrivateKey *rsa.PrivateKey
+	// APIBasePath is the versioned path of payment gateway APIs, e.g. payment/4.3/paymentInquiry
+	// Default: payment/4.3
+	APIBasePath string
+
+	// ActionPath is the path of payment maintenance (refund/void) requests on FrontendURL
+	// Default: 2C2PFrontend/PaymentAction/2.0/action
+	ActionPath string
+
+	// ActionContentType is the Content-Type of refund/void requests, e.g. application/jose
+	// for environments that reject the documented text/plain
+	// Default: text/plain
+	ActionContentType string
+
+	// KeyID is sent as the JWS "kid" header of refund/void requests, identifying our key to 2C2P
+	// Default: KeyIDFromCert(PublicCert)
+	KeyID string
+
+	// Locales are the accepted request locales; an empty locale is always accepted
+	// Default: DefaultLocales
+	Locales []string
+
+	// JWEKeyAlgorithm, JWEContentEncryption and JWSSignatureAlgorithm are used for refund/void
+	// requests and responses. JWSSignatureAlgorithm applies to our signature only with an RSA key
+	// Default: RSA-OAEP, A256GCM and PS256
+	JWEKeyAlgorithm       jose.KeyAlgorithm
+	JWEContentEncryption  jose.ContentEncryption
+	JWSSignatureAlgorithm jose.SignatureAlgorithm
+
+	// AutoIdempotency fills a random UUID into the idempotency ID of payment token,
+	// refund and void requests that leave it empty; the ID used is returned on the response
+	AutoIdempotency bool
+
+	// AutoNonce fills GenerateNonce into the nonceStr of payment token requests that leave it empty
+	AutoNonce bool
+
+	// InvoiceNoGenerator is used by GenerateInvoiceNo
+	// Default: NewInvoiceNoGenerator("INV", Clock)
+	InvoiceNoGenerator InvoiceNoGenerator
+
+	// PrivateKey is loaded from the combined private key and certificate PEM file.
+	// RSA, ECDSA and Ed25519 keys are accepted, but JWE and PKCS7 decryption require RSA
+	PrivateKey crypto.PrivateKey
 	PublicCert *x509.Certificate
 
 	// ServerJWTPublicKeyFile is the path to the 2C2P's public key certificate (.cer file) for JWT
 	ServerJWTPublicCert *x509.Certificate
 
+	// ServerJWTPublicCerts are additional certificates accepted when verifying JWS responses,
+	// e.g. the old and new 2C2P certificates during a key rotation window.
+	// Requests are always encrypted for ServerJWTPublicCert
+	ServerJWTPublicCerts []*x509.Certificate
+
 	// ServerPKCS7PublicKeyFile is the path to the 2C2P's public key certificate (.cer file) for PKCS7
 	ServerPKCS7PublicCert *x509.Certificate
 }
@@ -47,59 +117,275 @@ type Config struct {
 	SecretKey                string
 	MerchantID               string
 	HttpClient               *http.Client
-	PaymentGatewayURL        string // URL for payment gateway APIs
-	FrontendURL              string // URL for frontend-related APIs
+	RoundTripper             http.RoundTripper // e.g. tracing or metrics, called by HttpClient under logging; Default: HttpClient.Transport
+	Logger                   Logger            // Default: NewStdLogger(log.Default())
+	Verbose                  bool              // also log request and response bodies (redacted) at DEBUG; Default: false
+	Observer                 Observer          // Default: no-op
+	PaymentGatewayURL        string            // URL for payment gateway APIs
+	FrontendURL              string            // URL for frontend-related APIs
+	APIBasePath              string            // Default: payment/4.3
+	ActionPath               string            // Default: 2C2PFrontend/PaymentAction/2.0/action
+	ActionContentType        string            // Default: text/plain
 	CombinedPEM              string
+	PrivateKeyPassphrase     string // for an encrypted private key in CombinedPEM
 	ServerJWTPublicKeyFile   string
+	ServerJWTPublicKeyFiles  []string // additional certificates for key rotation; the first is used if ServerJWTPublicKeyFile is empty
 	ServerPKCS7PublicKeyFile string
+	KeyID                    string                  // JWS "kid" header; Default: KeyIDFromCert of the CombinedPEM certificate
+	AutoIdempotency          bool                    // generate a UUID idempotency ID when the request has none
+	AutoNonce                bool                    // generate a nonceStr for payment token requests that have none
+	Locales                  []string                // Default: DefaultLocales
+	Clock                    func() time.Time        // for request timestamps; Default: time.Now
+	InvoiceNoGenerator       InvoiceNoGenerator      // Default: NewInvoiceNoGenerator("INV", Clock)
+	JWEKeyAlgorithm          jose.KeyAlgorithm       // Default: jose.RSA_OAEP
+	JWEContentEncryption     jose.ContentEncryption  // Default: jose.A256GCM
+	JWSSignatureAlgorithm    jose.SignatureAlgorithm // Default: jose.PS256
+
+	// PEM contents, e.g. injected via environment variables; each takes precedence over its file path above
+	CombinedPEMData          []byte
+	ServerJWTPublicKeyData   []byte
+	ServerPKCS7PublicKeyData []byte
+}
+
+// maxMerchantIDLength is the maximum length of a 2C2P merchant ID, merchantID "C 15" in
+// docs/2c2p/refund-request-parameter.csv, e.g. 702702000003987
+const maxMerchantIDLength = 15
+
+// validateCredentials trims SecretKey and MerchantID, e.g. of a trailing newline from an
+// environment variable, and rejects them when blank or, for MerchantID, too long
+func (cfg *Config) validateCredentials() error {
+	cfg.SecretKey = strings.TrimSpace(cfg.SecretKey)
+	cfg.MerchantID = strings.TrimSpace(cfg.MerchantID)
+	if cfg.SecretKey == "" {
+		return fmt.Errorf("secret key is required")
+	}
+	if cfg.MerchantID == "" {
+		return fmt.Errorf("merchant ID is required")
+	}
+	if len(cfg.MerchantID) > maxMerchantIDLength {
+		return fmt.Errorf("merchant ID %q is longer than %d characters", cfg.MerchantID, maxMerchantIDLength)
+	}
+	return nil
 }
 
 // NewClient creates a new 2C2P API client
 func NewClient(cfg Config) (*Client, error) {
-	privateKey, publicCert, err := loadPrivateKeyAndCert(cfg.CombinedPEM)
+	if err := cfg.validateCredentials(); err != nil {
+		return nil, err
+	}
+	combinedPEMFile := pemFileName(cfg.CombinedPEMData, cfg.CombinedPEM)
+	combinedPEM, err := pemDataOrFile(cfg.CombinedPEMData, cfg.CombinedPEM)
+	if err != nil {
+		return nil, &KeyConfigError{Role: KeyRoleCombinedPEM, File: combinedPEMFile, Err: err}
+	}
+	privateKey, publicCert, err := loadPrivateKeyAndCert(combinedPEM, cfg.PrivateKeyPassphrase)
+	if err != nil {
+		return nil, &KeyConfigError{Role: KeyRoleCombinedPEM, File: combinedPEMFile, Err: err}
+	}
+	serverJWTPublicKeyFiles := cfg.ServerJWTPublicKeyFiles
+	if cfg.ServerJWTPublicKeyFile == "" && len(cfg.ServerJWTPublicKeyData) == 0 && len(serverJWTPublicKeyFiles) > 0 {
+		cfg.ServerJWTPublicKeyFile, serverJWTPublicKeyFiles = serverJWTPublicKeyFiles[0], serverJWTPublicKeyFiles[1:]
+	}
+	serverJWTPublicKey, err := loadServerPublicCert(KeyRoleServerJWT, cfg.ServerJWTPublicKeyData, cfg.ServerJWTPublicKeyFile)
 	if err != nil {
 		return nil, err
 	}
-	serverJWTPublicKey, err := serverPublicCert(cfg.ServerJWTPublicKeyFile)
+	var serverJWTPublicKeys []*x509.Certificate
+	for _, file := range serverJWTPublicKeyFiles {
+		cert, err := loadServerPublicCert(KeyRoleServerJWT, nil, file)
+		if err != nil {
+			return nil, err
+		}
+		serverJWTPublicKeys = append(serverJWTPublicKeys, cert)
+	}
+	serverPKCS7PublicKey, err := loadServerPublicCert(KeyRoleServerPKCS7, cfg.ServerPKCS7PublicKeyData, cfg.ServerPKCS7PublicKeyFile)
 	if err != nil {
 		return nil, err
 	}
-	serverPKCS7PublicKey, err := serverPublicCert(cfg.ServerPKCS7PublicKeyFile)
+
+	client, err := NewClientWithKeys(cfg, privateKey, publicCert, serverJWTPublicKey, serverPKCS7PublicKey)
 	if err != nil {
 		return nil, err
 	}
+	client.ServerJWTPublicCerts = serverJWTPublicKeys
+	return client, nil
+}
+
+// NewClientWithKeys creates a new 2C2P API client from already parsed keys and certificates.
+// The key file paths in cfg are ignored.
+func NewClientWithKeys(cfg Config, privateKey crypto.PrivateKey, publicCert, serverJWTPublicCert, serverPKCS7PublicCert *x509.Certificate) (*Client, error) {
+	if err := cfg.validateCredentials(); err != nil {
+		return nil, err
+	}
+	return newClientWithKeys(cfg, privateKey, publicCert, serverJWTPublicCert, serverPKCS7PublicCert)
+}
 
+// newClientWithKeys is NewClientWithKeys without requiring credentials, for helpers that only use the keys
+func newClientWithKeys(cfg Config, privateKey crypto.PrivateKey, publicCert, serverJWTPublicCert, serverPKCS7PublicCert *x509.Certificate) (*Client, error) {
 	if cfg.PaymentGatewayURL == "" {
 		cfg.PaymentGatewayURL = "https://sandbox-pgw.2c2p.com"
 	}
 	if cfg.FrontendURL == "" {
 		cfg.FrontendURL = "https://demo2.2c2p.com"
 	}
+	if cfg.APIBasePath = strings.Trim(cfg.APIBasePath, "/"); cfg.APIBasePath == "" {
+		cfg.APIBasePath = "payment/4.3"
+	}
+	if cfg.ActionPath = strings.Trim(cfg.ActionPath, "/"); cfg.ActionPath == "" {
+		cfg.ActionPath = "2C2PFrontend/PaymentAction/2.0/action"
+	}
+	if cfg.ActionContentType == "" {
+		cfg.ActionContentType = "text/plain"
+	}
 	if cfg.HttpClient == nil {
 		cfg.HttpClient = &http.Client{}
 	}
-	loggingClient := NewLoggingClient(cfg.HttpClient, nil, true)
-	return &Client{
+	// Requests go through logging, then HttpClient (its Timeout, redirects and cookies), then
+	// RoundTripper, e.g. a tracing or metrics transport, which should delegate to the network
+	// transport. HttpClient is copied so the caller's client keeps its own Transport
+	if cfg.RoundTripper != nil {
+		httpClient := *cfg.HttpClient
+		httpClient.Transport = cfg.RoundTripper
+		cfg.HttpClient = &httpClient
+	}
+	if cfg.Observer == nil {
+		cfg.Observer = nopObserver{}
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = time.Now
+	}
+	if cfg.InvoiceNoGenerator == nil {
+		cfg.InvoiceNoGenerator = NewInvoiceNoGenerator("INV", cfg.Clock)
+	}
+	if cfg.Locales == nil {
+		cfg.Locales = DefaultLocales
+	}
+	if cfg.JWEKeyAlgorithm == "" {
+		cfg.JWEKeyAlgorithm = jose.RSA_OAEP
+	}
+	if cfg.JWEContentEncryption == "" {
+		cfg.JWEContentEncryption = jose.A256GCM
+	}
+	if cfg.JWSSignatureAlgorithm == "" {
+		cfg.JWSSignatureAlgorithm = jose.PS256
+	}
+	if err := validateJOSEAlgorithms(cfg.JWEKeyAlgorithm, cfg.JWEContentEncryption, cfg.JWSSignatureAlgorithm); err != nil {
+		return nil, err
+	}
+	if cfg.KeyID == "" && publicCert != nil {
+		cfg.KeyID = KeyIDFromCert(publicCert)
+	}
+	loggingClient := NewStructuredLoggingClient(cfg.HttpClient, cfg.Logger, cfg.Verbose)
+	client := &Client{
 		SecretKey:             cfg.SecretKey,
 		MerchantID:            cfg.MerchantID,
+		KeyID:                 cfg.KeyID,
+		AutoIdempotency:       cfg.AutoIdempotency,
+		AutoNonce:             cfg.AutoNonce,
+		InvoiceNoGenerator:    cfg.InvoiceNoGenerator,
+		Locales:               cfg.Locales,
+		JWEKeyAlgorithm:       cfg.JWEKeyAlgorithm,
+		JWEContentEncryption:  cfg.JWEContentEncryption,
+		JWSSignatureAlgorithm: cfg.JWSSignatureAlgorithm,
 		httpClient:            loggingClient,
+		observer:              cfg.Observer,
+		logger:                loggingClient.logger,
+		now:                   cfg.Clock,
 		PaymentGatewayURL:     cfg.PaymentGatewayURL,
 		FrontendURL:           cfg.FrontendURL,
+		APIBasePath:           cfg.APIBasePath,
+		ActionPath:            cfg.ActionPath,
+		ActionContentType:     cfg.ActionContentType,
 		PrivateKey:            privateKey,
 		PublicCert:            publicCert,
-		ServerJWTPublicCert:   serverJWTPublicKey,
-		ServerPKCS7PublicCert: serverPKCS7PublicKey,
-	}, nil
+		ServerJWTPublicCert:   serverJWTPublicCert,
+		ServerPKCS7PublicCert: serverPKCS7PublicCert,
+	}
+	if publicCert != nil && client.CertificateExpiresWithin(certificateExpiryWarning) {
+		client.logger.Error("certificate expires soon; generate a new key pair and upload it to 2C2P",
+			"notAfter", publicCert.NotAfter, "subject", publicCert.Subject.CommonName)
+	}
+	return client, nil
+}
+
+// certificateExpiryWarning is how early NewClientWithKeys warns about PublicCert expiring
+const certificateExpiryWarning = 30 * 24 * time.Hour
+
+// CertificateExpiry returns when PublicCert, our certificate registered with 2C2P, expires
+func (c *Client) CertificateExpiry() (time.Time, error) {
+	if c.PublicCert == nil {
+		return time.Time{}, fmt.Errorf("no certificate loaded")
+	}
+	return c.PublicCert.NotAfter, nil
+}
+
+// CertificateExpiresWithin reports whether PublicCert expires within d, or has expired.
+// It is false when no certificate is loaded
+func (c *Client) CertificateExpiresWithin(d time.Duration) bool {
+	notAfter, err := c.CertificateExpiry()
+	if err != nil {
+		return false
+	}
+	return c.now().Add(d).After(notAfter)
+}
+
+// KeyIDFromCert derives a stable key ID from the certificate: the hex SHA-256 fingerprint of its DER bytes
+func KeyIDFromCert(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// idempotencyID returns id, or a new UUID if id is empty and AutoIdempotency is set
+func (c *Client) idempotencyID(id string) string {
+	if id == "" && c.AutoIdempotency {
+		return uuid.NewString()
+	}
+	return id
+}
+
+// GenerateInvoiceNo returns a new invoice number from the client's InvoiceNoGenerator
+func (c *Client) GenerateInvoiceNo() string {
+	return c.InvoiceNoGenerator.GenerateInvoiceNo()
 }
 
 func (c *Client) paymentGatewayEndpoint(path string) string {
-	return fmt.Sprintf("%s/payment/4.3/%s", c.PaymentGatewayURL, path)
+	return fmt.Sprintf("%s/%s/%s", c.PaymentGatewayURL, c.APIBasePath, path)
 }
 
 func (c *Client) frontendEndpoint(path string) string {
 	return fmt.Sprintf("%s/%s", c.FrontendURL, path)
 }
 
+// directResponse is the unsigned body 2C2P returns instead of a JWT payload, e.g. for
+// request errors. Only the code and description are read from it
+type directResponse struct {
+	RespCode string `json:"respCode"`
+	RespDesc string `json:"respDesc"`
+}
+
+// decodePayloadResponse decodes the JWT `payload` of body into v. A body without payload
+// is returned as a directResponse instead, leaving v untouched
+func (c *Client) decodePayloadResponse(body io.Reader, v interface{}) (*directResponse, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	var response struct {
+		Payload string `json:"payload"`
+		directResponse
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if response.Payload == "" {
+		return &response.directResponse, nil
+	}
+	if err := c.decodeJWTTokenForJSON(response.Payload, v); err != nil {
+		return nil, fmt.Errorf("decode jwt token: %w", err)
+	}
+	return nil, nil
+}
+
 func (c *Client) generateJWTTokenForJSON(payload []byte) (string, error) {
 	var claims map[string]interface{}
 	if err := json.Unmarshal(payload, &claims); err != nil {
@@ -111,12 +397,16 @@ func (c *Client) generateJWTTokenForJSON(payload []byte) (string, error) {
 }
 
 func (c *Client) decodeJWTTokenForJSON(token string, v interface{}) error {
-	parsedToken, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return []byte(c.SecretKey), nil
-	})
+	}
+	parsedToken, err := jwt.Parse(token, keyFunc,
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), // only what 2C2P signs with, never "none"
+		jwt.WithJSONNumber(), // keep numbers exact until they reach v
+	)
 	if err != nil {
 		return err
 	}
@@ -135,24 +425,122 @@ func (c *Client) decodeJWTTokenForJSON(token string, v interface{}) error {
 }
 
 func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.closed.Load() {
+		return nil, ErrClientClosed
+	}
+	start := time.Now()
 	resp, err := c.httpClient.Do(req)
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	c.observer.ObserveRequest(endpointLabel(req.URL.Path), time.Since(start), status, err)
 	if err != nil {
 		return nil, fmt.Errorf("do request: %w", err)
 	}
 	return resp, nil
 }
 
+// Close zeroizes the private key material, where the key type allows it, forgets the
+// secret key and closes idle HTTP connections. The client is unusable after Close: API
+// calls and decryption return ErrClientClosed. Close must not be called concurrently
+// with other methods; calling it again is a no-op
+func (c *Client) Close() error {
+	if c.closed.Swap(true) {
+		return nil
+	}
+	zeroizePrivateKey(c.PrivateKey)
+	c.PrivateKey = nil
+	c.SecretKey = ""
+	c.httpClient.client.CloseIdleConnections()
+	return nil
+}
+
+// zeroizePrivateKey overwrites the secret values of key in place. Values the standard
+// library keeps unexported, e.g. rsa.PrecomputedValues internals, cannot be reached
+func zeroizePrivateKey(key crypto.PrivateKey) {
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		zeroizeBigInt(key.D)
+		for _, prime := range key.Primes {
+			zeroizeBigInt(prime)
+		}
+		zeroizeBigInt(key.Precomputed.Dp)
+		zeroizeBigInt(key.Precomputed.Dq)
+		zeroizeBigInt(key.Precomputed.Qinv)
+		for _, crt := range key.Precomputed.CRTValues {
+			zeroizeBigInt(crt.Exp)
+			zeroizeBigInt(crt.Coeff)
+			zeroizeBigInt(crt.R)
+		}
+	case *ecdsa.PrivateKey:
+		zeroizeBigInt(key.D)
+	case ed25519.PrivateKey:
+		clear(key)
+	}
+}
+
+func zeroizeBigInt(n *big.Int) {
+	if n == nil {
+		return
+	}
+	clear(n.Bits())
+	n.SetInt64(0)
+}
+
+// Observer receives metrics for every API call, e.g. to count calls and record latency
+type Observer interface {
+	// ObserveRequest is called after each request; endpoint is the last URL path segment,
+	// e.g. "paymentToken" or "paymentInquiry"; status is 0 when err is not nil
+	ObserveRequest(endpoint string, duration time.Duration, status int, err error)
+}
+
+type nopObserver struct{}
+
+func (nopObserver) ObserveRequest(string, time.Duration, int, error) {}
+
+func endpointLabel(urlPath string) string {
+	return path.Base(strings.TrimSuffix(urlPath, "/"))
+}
+
 //
 
-func serverPublicCert(serverPublicKeyFile string) (*x509.Certificate, error) {
-	// Read and parse 2C2P's public key certificate
-	certPEM, err := os.ReadFile(serverPublicKeyFile)
+// pemDataOrFile returns data if set, otherwise the contents of file
+func pemDataOrFile(data []byte, file string) ([]byte, error) {
+	if len(data) > 0 {
+		return data, nil
+	}
+	return os.ReadFile(file)
+}
+
+// pemFileName is the file that pemDataOrFile reads, or empty when data is used
+func pemFileName(data []byte, file string) string {
+	if len(data) > 0 {
+		return ""
+	}
+	return file
+}
+
+func loadServerPublicCert(role KeyRole, data []byte, file string) (*x509.Certificate, error) {
+	certPEM, err := pemDataOrFile(data, file)
 	if err != nil {
-		return nil, fmt.Errorf("read server public key file: %#v: %w", serverPublicKeyFile, err)
+		return nil, &KeyConfigError{Role: role, File: pemFileName(data, file), Err: err}
 	}
+	cert, err := serverPublicCert(certPEM)
+	if err != nil {
+		return nil, &KeyConfigError{Role: role, File: pemFileName(data, file), Err: err}
+	}
+	return cert, nil
+}
+
+func serverPublicCert(certPEM []byte) (*x509.Certificate, error) {
+	// Parse 2C2P's public key certificate
 	block, _ := pem.Decode(certPEM)
 	if block == nil {
-		return nil, fmt.Errorf("failed to decode server public key PEM")
+		return nil, fmt.Errorf("%w: not PEM encoded", ErrNoCertificate)
+	}
+	if block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("%w: found %s", ErrNoCertificate, block.Type)
 	}
 	cert, err := x509.ParseCertificate(block.Bytes)
 	if err != nil {
@@ -161,15 +549,12 @@ func serverPublicCert(serverPublicKeyFile string) (*x509.Certificate, error) {
 	return cert, nil
 }
 
-func loadPrivateKeyAndCert(combinedPEMFile string) (*rsa.PrivateKey, *x509.Certificate, error) {
-	// Read the combined PEM file
-	pemData, err := os.ReadFile(combinedPEMFile)
-	if err != nil {
-		return nil, nil, fmt.Errorf("read private key file: %w", err)
-	}
-
+// loadPrivateKeyAndCert parses the first private key and certificate of pemData,
+// decrypting the private key with passphrase if it is encrypted
+func loadPrivateKeyAndCert(pemData []byte, passphrase string) (crypto.PrivateKey, *x509.Certificate, error) {
 	// Parse private key
-	var privateKey *rsa.PrivateKey
+	var err error
+	var privateKey crypto.PrivateKey
 	var cert *x509.Certificate
 	for {
 		block, rest := pem.Decode(pemData)
@@ -177,20 +562,20 @@ func loadPrivateKeyAndCert(combinedPEMFile string) (*rsa.PrivateKey, *x509.Certi
 			break
 		}
 		switch block.Type {
-		case "RSA PRIVATE KEY", "PRIVATE KEY":
+		case "RSA PRIVATE KEY", "EC PRIVATE KEY", "PRIVATE KEY", "ENCRYPTED PRIVATE KEY":
 			if privateKey == nil {
-				if block.Type == "RSA PRIVATE KEY" {
-					privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
-				} else {
-					var key interface{}
-					key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
-					if err == nil {
-						var ok bool
-						privateKey, ok = key.(*rsa.PrivateKey)
-						if !ok {
-							err = fmt.Errorf("not an RSA private key")
-						}
-					}
+				der, err := decryptPEMBlock(block, passphrase)
+				if err != nil {
+					return nil, nil, fmt.Errorf("decrypt private key: %w", err)
+				}
+				switch block.Type {
+				case "RSA PRIVATE KEY":
+					privateKey, err = x509.ParsePKCS1PrivateKey(der)
+				case "EC PRIVATE KEY":
+					privateKey, err = x509.ParseECPrivateKey(der)
+				default:
+					// PKCS8 may hold RSA, ECDSA or Ed25519 keys
+					privateKey, err = x509.ParsePKCS8PrivateKey(der)
 				}
 				if err != nil {
 					return nil, nil, fmt.Errorf("parse private key: %w", err)
@@ -208,10 +593,10 @@ func loadPrivateKeyAndCert(combinedPEMFile string) (*rsa.PrivateKey, *x509.Certi
 	}
 
 	if privateKey == nil {
-		return nil, nil, fmt.Errorf("no private key found")
+		return nil, nil, ErrNoPrivateKey
 	}
 	if cert == nil {
-		return nil, nil, fmt.Errorf("no certificate found")
+		return nil, nil, ErrNoCertificate
 	}
 
 	return privateKey, cert, nil
@@ -1,5 +1,708 @@
 package api2c2p
 
-import "context"
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
 
 var ctx = context.Background()
+
+// testConfig returns the Config NewTestClient uses, with the testdata keys and example URLs,
+// for tests that need to adjust it before calling NewClient
+func testConfig() Config {
+	return Config{
+		SecretKey:                "test_secret",
+		MerchantID:               "JT01",
+		PaymentGatewayURL:        "https://pgw.example.com",
+		FrontendURL:              "https://frontend.example.com",
+		CombinedPEM:              "testdata/combined_private_public.pem",
+		ServerJWTPublicKeyFile:   "testdata/server.jwt.public_cert.pem",
+		ServerPKCS7PublicKeyFile: "testdata/server.pkcs7.public_cert.pem",
+	}
+}
+
+// NewTestClient starts a fake 2C2P server with handler and returns a client for it,
+// using the testdata keys. The server is closed when the test ends
+func NewTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+	cfg := testConfig()
+	cfg.PaymentGatewayURL = ts.URL
+	cfg.FrontendURL = ts.URL
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	return client
+}
+
+type fakeObservation struct {
+	endpoint string
+	duration time.Duration
+	status   int
+	err      error
+}
+
+type fakeObserver struct {
+	observations []fakeObservation
+}
+
+func (o *fakeObserver) ObserveRequest(endpoint string, duration time.Duration, status int, err error) {
+	o.observations = append(o.observations, fakeObservation{endpoint, duration, status, err})
+}
+
+func TestClientObserver(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	observer := &fakeObserver{}
+	cfg := testConfig()
+	cfg.PaymentGatewayURL = ts.URL
+	cfg.FrontendURL = ts.URL
+	cfg.Observer = observer
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	// response body is empty so decoding fails, but the call is still observed
+	client.PaymentInquiryByToken(ctx, &PaymentInquiryByTokenRequest{PaymentToken: "token123"})
+
+	if len(observer.observations) != 1 {
+		t.Fatalf("Expected 1 observation, got %d", len(observer.observations))
+	}
+	got := observer.observations[0]
+	if got.endpoint != "paymentInquiry" {
+		t.Errorf("Expected endpoint paymentInquiry, got %s", got.endpoint)
+	}
+	if got.status != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, got.status)
+	}
+	if got.err != nil {
+		t.Errorf("Expected no transport error, got %v", got.err)
+	}
+
+	// transport errors are observed with status 0
+	ts.Close()
+	client.PaymentInquiryByToken(ctx, &PaymentInquiryByTokenRequest{PaymentToken: "token123"})
+	if len(observer.observations) != 2 {
+		t.Fatalf("Expected 2 observations, got %d", len(observer.observations))
+	}
+	got = observer.observations[1]
+	if got.status != 0 || got.err == nil {
+		t.Errorf("Expected status 0 with error, got %d %v", got.status, got.err)
+	}
+}
+
+func TestEndpointLabel(t *testing.T) {
+	for path, want := range map[string]string{
+		"/payment/4.3/paymentToken":              "paymentToken",
+		"/payment/4.3/paymentInquiry/":           "paymentInquiry",
+		"/2C2PFrontend/PaymentAction/2.0/action": "action",
+	} {
+		if got := endpointLabel(path); got != want {
+			t.Errorf("Expected endpoint label %s for %s, got %s", want, path, got)
+		}
+	}
+}
+
+func TestEndpointPathOverrides(t *testing.T) {
+	testCases := []struct {
+		name        string
+		cfg         Config
+		wantGateway string
+		wantAction  string
+	}{
+		{
+			name:        "defaults",
+			wantGateway: "https://pgw.example.com/payment/4.3/paymentInquiry",
+			wantAction:  "https://frontend.example.com/2C2PFrontend/PaymentAction/2.0/action",
+		},
+		{
+			name: "overrides",
+			cfg: Config{
+				APIBasePath: "/payment/4.4/",
+				ActionPath:  "2C2PFrontend/PaymentAction/3.0/action",
+			},
+			wantGateway: "https://pgw.example.com/payment/4.4/paymentInquiry",
+			wantAction:  "https://frontend.example.com/2C2PFrontend/PaymentAction/3.0/action",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := tc.cfg
+			cfg.SecretKey = "test_secret"
+			cfg.MerchantID = "JT01"
+			cfg.PaymentGatewayURL = "https://pgw.example.com"
+			cfg.FrontendURL = "https://frontend.example.com"
+			cfg.CombinedPEM = "testdata/combined_private_public.pem"
+			cfg.ServerJWTPublicKeyFile = "testdata/server.jwt.public_cert.pem"
+			cfg.ServerPKCS7PublicKeyFile = "testdata/server.pkcs7.public_cert.pem"
+			client, err := NewClient(cfg)
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+
+			inquiryReq, err := client.newPaymentInquiryRequest(ctx, "JT01", &PaymentInquiryByInvoiceRequest{InvoiceNo: "INV123"})
+			if err != nil {
+				t.Fatalf("Failed to create inquiry request: %v", err)
+			}
+			if got := inquiryReq.URL.String(); got != tc.wantGateway {
+				t.Errorf("Expected inquiry URL %s, got %s", tc.wantGateway, got)
+			}
+
+			actionReq, err := client.NewPaymentProcessRequest(ctx, &PaymentProcessRequest{InvoiceNo: "INV123"})
+			if err != nil {
+				t.Fatalf("Failed to create action request: %v", err)
+			}
+			if got := actionReq.URL.String(); got != tc.wantAction {
+				t.Errorf("Expected action URL %s, got %s", tc.wantAction, got)
+			}
+		})
+	}
+}
+
+// writeCombinedPEM writes key (as keyType) and a self-signed certificate for it into a temp file
+func writeCombinedPEM(t *testing.T, key crypto.Signer, keyType string, keyDER []byte) string {
+	t.Helper()
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, &template, &template, key.Public(), key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	var buf bytes.Buffer
+	pem.Encode(&buf, &pem.Block{Type: keyType, Bytes: keyDER})
+	pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+	path := filepath.Join(t.TempDir(), "combined.pem")
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatalf("Failed to write PEM: %v", err)
+	}
+	return path
+}
+
+func TestNewClientNonRSAKeys(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %v", err)
+	}
+	ecDER, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal EC key: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %v", err)
+	}
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal Ed25519 key: %v", err)
+	}
+
+	testCases := []struct {
+		name       string
+		combined   string
+		wantAlg    string
+		wantKeyTyp string
+	}{
+		{"EC", writeCombinedPEM(t, ecKey, "EC PRIVATE KEY", ecDER), "ES256", "*ecdsa.PrivateKey"},
+		{"Ed25519", writeCombinedPEM(t, edKey, "PRIVATE KEY", edDER), "EdDSA", "ed25519.PrivateKey"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.CombinedPEM = tc.combined
+			client, err := NewClient(cfg)
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+			if got := fmt.Sprintf("%T", client.PrivateKey); got != tc.wantKeyTyp {
+				t.Errorf("Expected private key type %s, got %s", tc.wantKeyTyp, got)
+			}
+
+			// signing branches on the key type
+			signed, err := client.encryptJWEAndSignJWS([]byte("<PaymentProcessRequest/>"))
+			if err != nil {
+				t.Fatalf("Failed to sign: %v", err)
+			}
+			header, err := base64.RawURLEncoding.DecodeString(strings.Split(signed, ".")[0])
+			if err != nil {
+				t.Fatalf("Failed to decode JWS header: %v", err)
+			}
+			if !strings.Contains(string(header), `"alg":"`+tc.wantAlg+`"`) {
+				t.Errorf("Expected alg %s in header, got %s", tc.wantAlg, header)
+			}
+
+			// decryption is RSA only
+			_, _, err = client.DecryptPaymentResponseBackend(mockFormValuer{values: map[string]string{"paymentResponse": "AAAA"}})
+			if !errors.Is(err, ErrRSAKeyRequired) {
+				t.Errorf("Expected ErrRSAKeyRequired, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNewClientFromPEMData(t *testing.T) {
+	readFile := func(name string) []byte {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+		return data
+	}
+
+	// no file paths at all
+	client, err := NewClient(Config{
+		SecretKey:                "test_secret",
+		MerchantID:               "JT01",
+		CombinedPEMData:          readFile("testdata/combined_private_public.pem"),
+		ServerJWTPublicKeyData:   readFile("testdata/public_cert.pem"), // we have to decrypt what we encrypted in this test
+		ServerPKCS7PublicKeyData: readFile("testdata/server.pkcs7.public_cert.pem"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	// JWS/JWE round trip
+	signed, err := client.encryptJWEAndSignJWS([]byte("<PaymentProcessRequest/>"))
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	decrypted, err := client.verifyJWSAndDecryptJWE(signed)
+	if err != nil {
+		t.Fatalf("Failed to verify and decrypt: %v", err)
+	}
+	if string(decrypted) != "<PaymentProcessRequest/>" {
+		t.Errorf("Expected decrypted payload %q, got %q", "<PaymentProcessRequest/>", decrypted)
+	}
+
+	// PKCS7 backend response
+	response, _, err := client.DecryptPaymentResponseBackend(mockFormValuer{
+		values: map[string]string{
+			"paymentResponse": string(readFile("testdata/payment-response-1.txt")),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to decrypt payment response: %v", err)
+	}
+	if response.RespCode != "00" {
+		t.Errorf("Expected response code 00, got %s", response.RespCode)
+	}
+
+	// invalid data is reported, not silently ignored in favour of the path
+	cfg := testConfig()
+	cfg.CombinedPEMData = []byte("not a pem")
+	_, err = NewClient(cfg)
+	if err == nil {
+		t.Error("Expected error for invalid CombinedPEMData")
+	}
+}
+
+func TestNewClientKeyConfigError(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		return path
+	}
+	combined, err := os.ReadFile("testdata/combined_private_public.pem")
+	if err != nil {
+		t.Fatalf("Failed to read combined PEM: %v", err)
+	}
+	keyBlock, _ := pem.Decode(combined)
+	keyOnly := writeFile("key.pem", pem.EncodeToMemory(keyBlock))
+	certOnly := "testdata/public_cert.pem"
+	notPEM := writeFile("not.pem", []byte("not a certificate"))
+	missing := filepath.Join(dir, "missing.pem")
+
+	testCases := []struct {
+		name     string
+		combined string
+		jwt      string
+		pkcs7    string
+		wantRole KeyRole
+		wantFile string
+		wantErr  error
+	}{
+		{name: "missing combined PEM", combined: missing, wantRole: KeyRoleCombinedPEM, wantFile: missing, wantErr: fs.ErrNotExist},
+		{name: "combined PEM without private key", combined: certOnly, wantRole: KeyRoleCombinedPEM, wantFile: certOnly, wantErr: ErrNoPrivateKey},
+		{name: "combined PEM without certificate", combined: keyOnly, wantRole: KeyRoleCombinedPEM, wantFile: keyOnly, wantErr: ErrNoCertificate},
+		{name: "missing JWT certificate", jwt: missing, wantRole: KeyRoleServerJWT, wantFile: missing, wantErr: fs.ErrNotExist},
+		{name: "private key as JWT certificate", jwt: keyOnly, wantRole: KeyRoleServerJWT, wantFile: keyOnly, wantErr: ErrNoCertificate},
+		{name: "missing PKCS7 certificate", pkcs7: missing, wantRole: KeyRoleServerPKCS7, wantFile: missing, wantErr: fs.ErrNotExist},
+		{name: "PKCS7 certificate not PEM", pkcs7: notPEM, wantRole: KeyRoleServerPKCS7, wantFile: notPEM, wantErr: ErrNoCertificate},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := Config{
+				SecretKey:                "test_secret",
+				MerchantID:               "JT01",
+				CombinedPEM:              "testdata/combined_private_public.pem",
+				ServerJWTPublicKeyFile:   "testdata/server.jwt.public_cert.pem",
+				ServerPKCS7PublicKeyFile: "testdata/server.pkcs7.public_cert.pem",
+			}
+			if tc.combined != "" {
+				cfg.CombinedPEM = tc.combined
+			}
+			if tc.jwt != "" {
+				cfg.ServerJWTPublicKeyFile = tc.jwt
+			}
+			if tc.pkcs7 != "" {
+				cfg.ServerPKCS7PublicKeyFile = tc.pkcs7
+			}
+			_, err := NewClient(cfg)
+			var keyErr *KeyConfigError
+			if !errors.As(err, &keyErr) {
+				t.Fatalf("Expected *KeyConfigError, got %v", err)
+			}
+			if keyErr.Role != tc.wantRole || keyErr.File != tc.wantFile {
+				t.Errorf("Expected %s %s, got %s %s", tc.wantRole, tc.wantFile, keyErr.Role, keyErr.File)
+			}
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("Expected error to wrap %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+
+	// PEM data has no file to report
+	_, err = NewClient(Config{
+		SecretKey:       "test_secret",
+		MerchantID:      "JT01",
+		CombinedPEMData: pem.EncodeToMemory(keyBlock),
+	})
+	var keyErr *KeyConfigError
+	if !errors.As(err, &keyErr) || keyErr.File != "" || !errors.Is(err, ErrNoCertificate) {
+		t.Errorf("Expected KeyConfigError without file for PEM data, got %v", err)
+	}
+}
+
+func TestNewClientEncryptedPrivateKey(t *testing.T) {
+	combined, err := os.ReadFile("testdata/combined_private_public.pem")
+	if err != nil {
+		t.Fatalf("Failed to read combined PEM: %v", err)
+	}
+	want, _, err := loadPrivateKeyAndCert(combined, "")
+	if err != nil {
+		t.Fatalf("Failed to load unencrypted key: %v", err)
+	}
+
+	for _, combinedPEM := range []string{
+		"testdata/combined_encrypted_private_public.pem",        // PKCS8 PBES2, AES-256-CBC
+		"testdata/combined_legacy_encrypted_private_public.pem", // Proc-Type: 4,ENCRYPTED
+	} {
+		testCases := []struct {
+			name       string
+			passphrase string
+			wantErr    error
+		}{
+			{name: "correct passphrase", passphrase: "test_passphrase"},
+			{name: "incorrect passphrase", passphrase: "wrong_passphrase", wantErr: ErrIncorrectPassphrase},
+			{name: "no passphrase", wantErr: ErrPassphraseRequired},
+		}
+		for _, tc := range testCases {
+			t.Run(filepath.Base(combinedPEM)+"/"+tc.name, func(t *testing.T) {
+				cfg := testConfig()
+				cfg.CombinedPEM = combinedPEM
+				cfg.PrivateKeyPassphrase = tc.passphrase
+				client, err := NewClient(cfg)
+				if tc.wantErr != nil {
+					if !errors.Is(err, tc.wantErr) {
+						t.Errorf("Expected error %v, got %v", tc.wantErr, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("Failed to create client: %v", err)
+				}
+				if !want.(*rsa.PrivateKey).Equal(client.PrivateKey) {
+					t.Error("Expected decrypted private key to match the unencrypted key")
+				}
+			})
+		}
+	}
+}
+
+// roundTripperFunc is an http.RoundTripper calling itself
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestNewClientRoundTripper(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"respCode": "9015", "respDesc": "Existing invoice number"})
+	}))
+	defer ts.Close()
+
+	var paths []string
+	httpClient := &http.Client{Timeout: time.Minute}
+	cfg := testConfig()
+	cfg.PaymentGatewayURL = ts.URL
+	cfg.HttpClient = httpClient
+	cfg.RoundTripper = roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		paths = append(paths, r.URL.Path)
+		return http.DefaultTransport.RoundTrip(r)
+	})
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	if _, err := client.Inquire(ctx, InquiryQuery{InvoiceNo: "INV123"}); !IsResponseCode(err, "9015") {
+		t.Errorf("Expected response code 9015 through the RoundTripper, got %v", err)
+	}
+	if len(paths) != 1 || paths[0] != "/payment/4.3/paymentInquiry" {
+		t.Errorf("Expected RoundTripper to be called for /payment/4.3/paymentInquiry, got %v", paths)
+	}
+	if httpClient.Transport != nil {
+		t.Errorf("Expected the caller's HttpClient to be left unchanged, got Transport %v", httpClient.Transport)
+	}
+	if got := client.httpClient.client.Timeout; got != time.Minute {
+		t.Errorf("Expected HttpClient Timeout %v to be kept, got %v", time.Minute, got)
+	}
+}
+
+func TestNewClientValidatesCredentials(t *testing.T) {
+	testCases := []struct {
+		name       string
+		secretKey  string
+		merchantID string
+		wantErr    string
+	}{
+		{name: "blank secret key", secretKey: "", merchantID: "JT01", wantErr: "secret key is required"},
+		{name: "whitespace secret key", secretKey: " \n", merchantID: "JT01", wantErr: "secret key is required"},
+		{name: "blank merchant ID", secretKey: "test_secret", merchantID: "\t", wantErr: "merchant ID is required"},
+		{name: "merchant ID too long", secretKey: "test_secret", merchantID: "7027020000039871", wantErr: `merchant ID "7027020000039871" is longer than 15 characters`},
+		{name: "trimmed", secretKey: " test_secret\n", merchantID: " JT01\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.SecretKey = tc.secretKey
+			cfg.MerchantID = tc.merchantID
+			client, err := NewClient(cfg)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Errorf("Expected error %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+			if client.SecretKey != "test_secret" {
+				t.Errorf("Expected secret key %q, got %q", "test_secret", client.SecretKey)
+			}
+			if client.MerchantID != "JT01" {
+				t.Errorf("Expected merchant ID %q, got %q", "JT01", client.MerchantID)
+			}
+		})
+	}
+
+	// the merchant ID of the sandbox responses in testdata/payment-response-*.txt.xml
+	cfg := testConfig()
+	cfg.MerchantID = "702702000003987"
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("Expected 15 character merchant ID to be accepted, got %v", err)
+	}
+	if client.MerchantID != "702702000003987" {
+		t.Errorf("Expected merchant ID %q, got %q", "702702000003987", client.MerchantID)
+	}
+
+	if _, err := NewClientWithKeys(Config{MerchantID: "JT01"}, nil, nil, nil, nil); err == nil {
+		t.Error("Expected NewClientWithKeys to reject a blank secret key")
+	}
+}
+
+func TestCertificateExpiry(t *testing.T) {
+	_, nearCertPEM, nearCombinedPEM, err := GenerateMerchantKeyPair("near", 7*24*time.Hour, 0)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+	_, _, farCombinedPEM, err := GenerateMerchantKeyPair("far", 365*24*time.Hour, 0)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+
+	testCases := []struct {
+		name        string
+		combinedPEM []byte
+		wantWarning bool
+	}{
+		{name: "near expiry", combinedPEM: nearCombinedPEM, wantWarning: true},
+		{name: "far expiry", combinedPEM: farCombinedPEM},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			client, err := NewClient(Config{
+				SecretKey:                "test_secret",
+				MerchantID:               "JT01",
+				Logger:                   NewStdLogger(log.New(&buf, "", 0)),
+				CombinedPEMData:          tc.combinedPEM,
+				ServerJWTPublicKeyData:   nearCertPEM,
+				ServerPKCS7PublicKeyFile: "testdata/server.pkcs7.public_cert.pem",
+			})
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+
+			expiry, err := client.CertificateExpiry()
+			if err != nil {
+				t.Fatalf("CertificateExpiry failed: %v", err)
+			}
+			if !expiry.Equal(client.PublicCert.NotAfter) {
+				t.Errorf("Expected expiry %s, got %s", client.PublicCert.NotAfter, expiry)
+			}
+			if got := client.CertificateExpiresWithin(30 * 24 * time.Hour); got != tc.wantWarning {
+				t.Errorf("Expected CertificateExpiresWithin 30 days %v, got %v", tc.wantWarning, got)
+			}
+			if got := strings.Contains(buf.String(), "certificate expires soon"); got != tc.wantWarning {
+				t.Errorf("Expected warning logged %v, got log %q", tc.wantWarning, buf.String())
+			}
+		})
+	}
+
+	client, err := NewClientWithKeys(Config{SecretKey: "test_secret", MerchantID: "JT01"}, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if _, err := client.CertificateExpiry(); err == nil {
+		t.Error("Expected error without a certificate")
+	}
+	if client.CertificateExpiresWithin(time.Hour) {
+		t.Error("Expected no expiry without a certificate")
+	}
+}
+
+func TestClientClose(t *testing.T) {
+	client := NewTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Expected no request after Close, got %s %s", r.Method, r.URL)
+	})
+	privateKey, ok := client.PrivateKey.(*rsa.PrivateKey)
+	if !ok {
+		t.Fatalf("Expected RSA private key, got %T", client.PrivateKey)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := client.Close(); err != nil {
+		t.Errorf("Expected second Close to be a no-op, got %v", err)
+	}
+
+	if privateKey.D.Sign() != 0 || privateKey.Primes[0].Sign() != 0 {
+		t.Error("Expected private key to be zeroized")
+	}
+	if client.PrivateKey != nil || client.SecretKey != "" {
+		t.Error("Expected keys to be forgotten")
+	}
+
+	if _, err := client.Inquire(ctx, InquiryQuery{InvoiceNo: "INV123"}); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Expected ErrClientClosed from Inquire, got %v", err)
+	}
+	if _, err := client.Refund(ctx, "INV123", 100); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Expected ErrClientClosed from Refund, got %v", err)
+	}
+	if _, err := client.DecryptPKCS7([]byte("encrypted")); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Expected ErrClientClosed from DecryptPKCS7, got %v", err)
+	}
+	if _, err := client.DecryptActionResponse("token"); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Expected ErrClientClosed from DecryptActionResponse, got %v", err)
+	}
+}
+
+func TestDecodeJWTTokenForJSONRejectsOtherAlgorithms(t *testing.T) {
+	client := NewTestClient(t, nil)
+	claims := jwt.MapClaims{"respCode": "0000", "invoiceNo": "INV123"}
+
+	none, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	hs512, err := jwt.NewWithClaims(jwt.SigningMethodHS512, claims).SignedString([]byte(client.SecretKey))
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	hs256, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(client.SecretKey))
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+
+	testCases := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "alg none", token: none, wantErr: true},
+		{name: "HS512", token: hs512, wantErr: true},
+		{name: "HS256", token: hs256},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got PaymentInquiryResponse
+			err := client.decodeJWTTokenForJSON(tc.token, &got)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to decode: %v", err)
+			}
+			if got.InvoiceNo != "INV123" {
+				t.Errorf("Expected invoiceNo INV123, got %s", got.InvoiceNo)
+			}
+		})
+	}
+
+	// an unsigned inquiry response is not trusted
+	client = NewTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"payload": none})
+	})
+	if _, err := client.Inquire(ctx, InquiryQuery{InvoiceNo: "INV123"}); err == nil {
+		t.Error("Expected Inquire to reject an alg none response")
+	}
+}
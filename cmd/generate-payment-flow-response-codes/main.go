@@ -1,18 +1,28 @@
 package main
 
 import (
-	"encoding/csv"
 	"fmt"
 	"log"
 	"os"
 	"regexp"
 	"strings"
 	"text/template"
+
+	"github.com/choonkeat/2c2p/internal/responsecodes"
 )
 
 type PaymentFlowResponseCode struct {
 	Code        string
 	Description string
+
+	// RequiresAction is set for 1xxx codes, where the client must redirect, show a QR, etc.
+	RequiresAction bool
+
+	// Completed is set for 2xxx codes, where the payment flow has ended
+	Completed bool
+
+	// Failed is set for the "Other" code, where the transaction failed or was rejected
+	Failed bool
 }
 
 func toConstName(desc string, code string) string {
@@ -51,6 +61,51 @@ func (c PaymentFlowResponseCode) Description() string {
 	}
 }
 
+// RequiresAction reports whether the client must act to continue the payment flow,
+// e.g. load a redirect URL, open an app or display a QR code
+func (c PaymentFlowResponseCode) RequiresAction() bool {
+	switch c {
+	{{- range .}}
+	{{- if .RequiresAction}}
+	case "{{.Code}}":
+		return true
+	{{- end}}
+	{{- end}}
+	default:
+		return false
+	}
+}
+
+// IsTerminalSuccess reports whether the payment flow completed; the payment result
+// still comes from the backend notification or a payment inquiry
+func (c PaymentFlowResponseCode) IsTerminalSuccess() bool {
+	switch c {
+	{{- range .}}
+	{{- if .Completed}}
+	case "{{.Code}}":
+		return true
+	{{- end}}
+	{{- end}}
+	default:
+		return false
+	}
+}
+
+// IsTerminalFailure reports whether the payment flow failed or was rejected. Codes that are
+// not listed, e.g. "0000", are neither a terminal success nor a terminal failure
+func (c PaymentFlowResponseCode) IsTerminalFailure() bool {
+	switch c {
+	{{- range .}}
+	{{- if .Failed}}
+	case "{{.Code}}":
+		return true
+	{{- end}}
+	{{- end}}
+	default:
+		return false
+	}
+}
+
 // Known response codes
 const (
 	{{- range .}}
@@ -67,40 +122,22 @@ func main() {
 	}
 	defer file.Close()
 
-	reader := csv.NewReader(file)
-	codes, err := reader.ReadAll()
+	codes, err := responsecodes.ReadPaymentFlowCSV(file)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	// Format each code as a 4-digit string with leading zeros
-	for i, code := range codes {
-		for j, c := range code {
-			codes[i][j] = fmt.Sprintf("%04s", c)
-		}
-	}
-
-	// Parse rows
 	var paymentCodes []PaymentFlowResponseCode
-	for i, row := range codes {
-		if i == 0 || len(row) < 2 { // Skip header row and invalid rows
-			continue
-		}
-		code := strings.TrimSpace(strings.Fields(row[0])[0])
-		desc := strings.TrimSpace(strings.Join(strings.Split(row[2], "\n"), " "))
-		if code == "" || desc == "" {
-			continue
-		}
+	for _, c := range codes {
 		paymentCodes = append(paymentCodes, PaymentFlowResponseCode{
-			Code:        code,
-			Description: desc,
+			Code:           c.Code,
+			Description:    c.Description,
+			RequiresAction: strings.HasPrefix(c.Code, "1"),
+			Completed:      strings.HasPrefix(c.Code, "2"),
+			Failed:         c.Code == "Other",
 		})
 	}
 
-	if len(paymentCodes) == 0 {
-		log.Fatal("No response codes found in CSV")
-	}
-
 	// Generate Go code
 	tmpl, err := template.New("codes").Funcs(template.FuncMap{"toConstName": func(desc string, code string) string { return toConstName(desc, code) }}).Parse(outputTemplate)
 	if err != nil {
@@ -1,18 +1,85 @@
 package main
 
 import (
-	"encoding/csv"
 	"fmt"
 	"log"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"text/template"
+
+	"github.com/choonkeat/2c2p/internal/responsecodes"
 )
 
 type PaymentResponseCode struct {
 	Code        string
 	Description string
+	Category    string
+}
+
+// categoryRanges gives the category of every code in an inclusive range, following the
+// groups of the 2C2P payment response code table: 0xxx and 2xxx transaction status, 4xxx
+// card and bank responses, 5xxx alternative payment responses, 6xxx and 7xxx API request
+// errors, 9xxx request validation errors and 999x failed internal service calls
+var categoryRanges = []struct {
+	from, to int
+	category string
+}{
+	{0, 0, "ResponseCategorySuccess"},
+	{1, 1, "ResponseCategoryPending"},
+	{2, 998, "ResponseCategoryDeclined"},
+	{999, 999, "ResponseCategorySystemError"},
+	{2001, 2001, "ResponseCategoryPending"},
+	{2002, 2999, "ResponseCategoryDeclined"},
+	{4000, 5999, "ResponseCategoryDeclined"},
+	{6000, 7999, "ResponseCategoryInvalidRequest"},
+	{9000, 9989, "ResponseCategoryInvalidRequest"},
+	{9990, 9999, "ResponseCategorySystemError"},
+}
+
+// categoryCodes are the codes whose category differs from their range in categoryRanges,
+// mostly 4xxx and 5xxx where approvals, fraud, validation and system errors sit among declines
+var categoryCodes = map[string][]string{
+	"ResponseCategorySuccess": {
+		"4000", "4008", "4010", "4011", "4016", "4045", "4047", "4110", "4120", "4200",
+	},
+	"ResponseCategoryPending": {
+		"4009",
+	},
+	"ResponseCategoryFraud": {
+		"4004", "4007", "4033", "4034", "4035", "4041", "4043", "4059", "4063", "4067",
+	},
+	"ResponseCategoryInvalidRequest": {
+		"4003", "4012", "4013", "4014", "4020", "4026", "4030", "4072", "4076", "4077",
+		"4078", "4094", "4140", "4202", "5003", "5004", "5005", "5006", "5008", "5011",
+		"5012", "5013",
+	},
+	"ResponseCategorySystemError": {
+		"4022", "4050", "4068", "4086", "4090", "4091", "4095", "4096", "4097", "4098",
+		"4203", "5002", "5998",
+	},
+}
+
+// toCategory classifies a 4 digit response code by categoryCodes, then by categoryRanges
+func toCategory(code string) string {
+	for category, codes := range categoryCodes {
+		for _, c := range codes {
+			if c == code {
+				return category
+			}
+		}
+	}
+	n, err := strconv.Atoi(code)
+	if err != nil {
+		return "ResponseCategoryUnknown"
+	}
+	for _, r := range categoryRanges {
+		if r.from <= n && n <= r.to {
+			return r.category
+		}
+	}
+	return "ResponseCategoryUnknown"
 }
 
 func toConstName(desc string, code string) string {
@@ -50,6 +117,18 @@ func (c PaymentResponseCode) Description() string {
 	}
 }
 
+// Category classifies the response code, e.g. to decide whether to retry
+func (c PaymentResponseCode) Category() ResponseCategory {
+	switch c {
+	{{- range .}}
+	case "{{.Code}}":
+		return {{.Category}}
+	{{- end}}
+	default:
+		return ResponseCategoryUnknown
+	}
+}
+
 // Known response codes
 const (
 	{{- range .}}
@@ -66,40 +145,20 @@ func main() {
 	}
 	defer file.Close()
 
-	reader := csv.NewReader(file)
-	codes, err := reader.ReadAll()
+	codes, err := responsecodes.ReadPaymentCSV(file)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	// Format each code as a 4-digit string with leading zeros
-	for i, code := range codes {
-		for j, c := range code {
-			codes[i][j] = fmt.Sprintf("%04s", c)
-		}
-	}
-
-	// Parse rows
 	var paymentCodes []PaymentResponseCode
-	for i, row := range codes {
-		if i == 0 || len(row) < 2 { // Skip header row and invalid rows
-			continue
-		}
-		code := strings.TrimSpace(row[0])
-		desc := strings.TrimSpace(row[1])
-		if code == "" || desc == "" {
-			continue
-		}
+	for _, c := range codes {
 		paymentCodes = append(paymentCodes, PaymentResponseCode{
-			Code:        code,
-			Description: desc,
+			Code:        c.Code,
+			Description: c.Description,
+			Category:    toCategory(c.Code),
 		})
 	}
 
-	if len(paymentCodes) == 0 {
-		log.Fatal("No response codes found in CSV")
-	}
-
 	// Generate Go code
 	tmpl, err := template.New("codes").Funcs(template.FuncMap{"toConstName": func(desc string, code string) string { return toConstName(desc, code) }}).Parse(outputTemplate)
 	if err != nil {
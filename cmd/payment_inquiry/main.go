@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 
@@ -21,6 +23,7 @@ func main() {
 		combinedPem            = flag.String("combinedPem", "dist/combined_private_public.pem", "Path to combined private key and certificate PEM file generated by cmd/server-to-server-key/main.go")
 		serverJWTPublicKeyFile = flag.String("serverJWTPublicKey", "dist/sandbox-jwt-2c2p.demo.2.1(public).cer", "Path to 2C2P's public key certificate (.cer file)")
 		serverPKCS7PublicKey   = flag.String("serverPKCS7PublicKey", "dist/sandbox-pkcs7-demo2.2c2p.com(public).cer", "Path to 2C2P's public key certificate (.cer file)")
+		format                 = flag.String("format", "text", "Output format: text or json")
 	)
 	flag.Parse()
 
@@ -40,7 +43,12 @@ func main() {
 		os.Exit(1)
 	}
 
+	if *format != "text" && *format != "json" {
+		log.Fatalf("Unknown -format %q, expected text or json", *format)
+	}
+
 	client, err := api2c2p.NewClient(api2c2p.Config{
+		Verbose:                  true,
 		SecretKey:                *secretKey,
 		MerchantID:               *merchantID,
 		PaymentGatewayURL:        *paymentGatewayURL,
@@ -53,48 +61,55 @@ func main() {
 		log.Fatal(err)
 	}
 
-	var resp interface{}
-
-	ctx := context.Background()
-	if *paymentToken != "" {
-		resp, err = client.PaymentInquiryByToken(ctx, &api2c2p.PaymentInquiryByTokenRequest{
-			PaymentToken: *paymentToken,
-		})
-	} else {
-		resp, err = client.PaymentInquiryByInvoice(ctx, &api2c2p.PaymentInquiryByInvoiceRequest{
-			InvoiceNo: *invoiceNo,
-		})
+	query := api2c2p.InquiryQuery{
+		InvoiceNo:    *invoiceNo,
+		PaymentToken: *paymentToken,
 	}
-	if err != nil {
-		log.Printf("Error: %v", err)
-		log.Printf("Response: %#v", resp)
-		log.Fatal("Payment inquiry failed")
+	if err := run(context.Background(), client, query, *format, os.Stdout); err != nil {
+		log.Fatalf("Payment inquiry failed: %v", err)
 	}
+}
 
-	paymentResponse, ok := resp.(*api2c2p.PaymentInquiryResponse)
-	if !ok {
-		log.Fatal("Unexpected response type")
+// run inquires query and prints the response to w in format. A response whose respCode
+// is not a success, e.g. a decline, is printed and returned as an error, so main exits non-zero
+func run(ctx context.Context, client *api2c2p.Client, query api2c2p.InquiryQuery, format string, w io.Writer) error {
+	paymentResponse, err := client.Inquire(ctx, query)
+	if paymentResponse == nil {
+		return err
 	}
 
-	fmt.Printf("Response Code: %s\n", paymentResponse.RespCode)
-	fmt.Printf("Response Description: %s\n", api2c2p.PaymentResponseCode(paymentResponse.RespCode).Description())
-	fmt.Printf("Transaction Status: %s\n", paymentResponse.TransactionStatus)
-	fmt.Printf("Amount: %.2f\n", paymentResponse.Amount)
-	fmt.Printf("Currency Code: %s\n", paymentResponse.CurrencyCode)
-	fmt.Printf("Masked Pan: %s\n", paymentResponse.MaskedPan)
-	fmt.Printf("Payment Channel: %s\n", paymentResponse.PaymentChannel)
-	fmt.Printf("Payment Status: %s\n", paymentResponse.PaymentStatus)
-	fmt.Printf("Channel Response Code: %s\n", paymentResponse.ChannelResponseCode)
-	fmt.Printf("Channel Response Description: %s\n", paymentResponse.ChannelResponseDescription)
-	fmt.Printf("Approval Code: %s\n", paymentResponse.ApprovalCode)
-	fmt.Printf("ECI: %s\n", paymentResponse.ECI)
-	fmt.Printf("Transaction DateTime: %s\n", paymentResponse.TransactionDateTime)
-	fmt.Printf("Paid Agent: %s\n", paymentResponse.PaidAgent)
-	fmt.Printf("Paid Channel: %s\n", paymentResponse.PaidChannel)
-	fmt.Printf("Paid DateTime: %s\n", paymentResponse.PaidDateTime)
-	fmt.Printf("User Defined 1: %s\n", paymentResponse.UserDefined1)
-	fmt.Printf("User Defined 2: %s\n", paymentResponse.UserDefined2)
-	fmt.Printf("User Defined 3: %s\n", paymentResponse.UserDefined3)
-	fmt.Printf("User Defined 4: %s\n", paymentResponse.UserDefined4)
-	fmt.Printf("User Defined 5: %s\n", paymentResponse.UserDefined5)
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(paymentResponse); err != nil {
+			return err
+		}
+	} else {
+		printPaymentInquiryResponse(w, paymentResponse)
+	}
+	return err
+}
+
+func printPaymentInquiryResponse(w io.Writer, paymentResponse *api2c2p.PaymentInquiryResponse) {
+	fmt.Fprintf(w, "Response Code: %s\n", paymentResponse.RespCode)
+	fmt.Fprintf(w, "Response Description: %s\n", api2c2p.PaymentResponseCode(paymentResponse.RespCode).Description())
+	fmt.Fprintf(w, "Transaction Status: %s\n", paymentResponse.TransactionStatus)
+	fmt.Fprintf(w, "Amount: %.2f\n", paymentResponse.Amount)
+	fmt.Fprintf(w, "Currency Code: %s\n", paymentResponse.CurrencyCode)
+	fmt.Fprintf(w, "Masked Pan: %s\n", paymentResponse.MaskedPan)
+	fmt.Fprintf(w, "Payment Channel: %s\n", paymentResponse.PaymentChannel)
+	fmt.Fprintf(w, "Payment Status: %s\n", paymentResponse.PaymentStatus)
+	fmt.Fprintf(w, "Channel Response Code: %s\n", paymentResponse.ChannelResponseCode)
+	fmt.Fprintf(w, "Channel Response Description: %s\n", paymentResponse.ChannelResponseDescription)
+	fmt.Fprintf(w, "Approval Code: %s\n", paymentResponse.ApprovalCode)
+	fmt.Fprintf(w, "ECI: %s\n", paymentResponse.ECI)
+	fmt.Fprintf(w, "Transaction DateTime: %s\n", paymentResponse.TransactionDateTime)
+	fmt.Fprintf(w, "Paid Agent: %s\n", paymentResponse.PaidAgent)
+	fmt.Fprintf(w, "Paid Channel: %s\n", paymentResponse.PaidChannel)
+	fmt.Fprintf(w, "Paid DateTime: %s\n", paymentResponse.PaidDateTime)
+	fmt.Fprintf(w, "User Defined 1: %s\n", paymentResponse.UserDefined1)
+	fmt.Fprintf(w, "User Defined 2: %s\n", paymentResponse.UserDefined2)
+	fmt.Fprintf(w, "User Defined 3: %s\n", paymentResponse.UserDefined3)
+	fmt.Fprintf(w, "User Defined 4: %s\n", paymentResponse.UserDefined4)
+	fmt.Fprintf(w, "User Defined 5: %s\n", paymentResponse.UserDefined5)
 }
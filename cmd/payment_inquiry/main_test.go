@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	api2c2p "github.com/choonkeat/2c2p"
+	"github.com/choonkeat/2c2p/api2c2ptest"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestRun(t *testing.T) {
+	testCases := []struct {
+		name     string
+		respCode string
+		format   string
+		wantErr  bool
+		wantOut  string
+	}{
+		{name: "success", respCode: "0000", format: "text", wantOut: "Response Code: 0000"},
+		{name: "declined", respCode: "4005", format: "text", wantErr: true, wantOut: "Response Code: 4005"},
+		{name: "declined json", respCode: "4051", format: "json", wantErr: true, wantOut: `"respCode": "4051"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+					"invoiceNo": "INV123",
+					"respCode":  tc.respCode,
+				}).SignedString([]byte("test_secret"))
+				if err != nil {
+					t.Errorf("Error generating JWT token: %v", err)
+					return
+				}
+				json.NewEncoder(w).Encode(map[string]string{"payload": token})
+			}))
+			defer ts.Close()
+
+			client, err := api2c2ptest.NewMockClient("test_secret", "JT01", ts.URL)
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+
+			var out bytes.Buffer
+			err = run(context.Background(), client, api2c2p.InquiryQuery{InvoiceNo: "INV123"}, tc.format, &out)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Errorf("Expected error %v, got %v", tc.wantErr, err)
+			}
+			if tc.wantErr && !api2c2p.IsResponseCode(err, api2c2p.PaymentResponseCode(tc.respCode)) {
+				t.Errorf("Expected APIError with respCode %s, got %v", tc.respCode, err)
+			}
+			if !strings.Contains(out.String(), tc.wantOut) {
+				t.Errorf("Expected output to contain %q, got %s", tc.wantOut, out.String())
+			}
+		})
+	}
+}
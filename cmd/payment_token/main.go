@@ -29,6 +29,8 @@ func main() {
 		currencyCodeISO4217 = flag.String("currencyCode", "", "Currency code (ISO 4217)")
 
 		idempotencyID                    = flag.String("idempotencyID", "", "Unique value for retrying same requests")
+		nonceStr                         = flag.String("nonceStr", "", "Random value making the request unique (default: generated)")
+		locale                           = flag.String("locale", "", "Language of the payment page, e.g. en")
 		paymentChannelStr                = flag.String("paymentChannel", string(api2c2p.PaymentChannelCC), "Payment channel (comma-separated list)")
 		agentChannelStr                  = flag.String("agentChannel", "", "Agent channel (comma-separated list)")
 		request3DS                       = flag.String("request3DS", string(api2c2p.Request3DSYes), "Request 3DS (Y/N/F)")
@@ -59,21 +61,27 @@ func main() {
 		fxProviderCode                   = flag.String("fxProviderCode", "", "Forex provider code")
 		fxRateID                         = flag.String("fxRateID", "", "Forex rate ID")
 		originalAmount                   = flag.Float64("originalAmount", 0, "Original currency amount")
+		loyaltyRedeemAmount              = flag.Float64("loyaltyRedeemAmount", 0, "Amount to pay with loyalty points (optional)")
 		immediatePayment                 = flag.Bool("immediatePayment", false, "Trigger payment immediately")
 		iframeMode                       = flag.Bool("iframeMode", false, "Enable iframe mode")
-		userDefined1                     = flag.String("userDefined1", "", "Custom field 1")
-		userDefined2                     = flag.String("userDefined2", "", "Custom field 2")
-		userDefined3                     = flag.String("userDefined3", "", "Custom field 3")
-		userDefined4                     = flag.String("userDefined4", "", "Custom field 4")
-		userDefined5                     = flag.String("userDefined5", "", "Custom field 5")
+		frontendReturnURL                = flag.String("frontendReturnUrl", "", "URL the customer returns to after payment")
+		backendReturnURL                 = flag.String("backendReturnUrl", "", "URL receiving the backend payment notification")
 		statementDescriptor              = flag.String("statementDescriptor", "", "Dynamic statement description")
 		externalSubMerchantID            = flag.String("externalSubMerchantID", "", "External sub-merchant ID")
 
+		customerName     = flag.String("customerName", "", "Customer name to pre-fill the payment page")
+		customerEmail    = flag.String("customerEmail", "", "Customer email to pre-fill the payment page")
+		customerMobileNo = flag.String("customerMobileNo", "", "Customer mobile number to pre-fill the payment page")
+
 		subMerchantID          = flag.String("subMerchantID", "", "Sub-merchant ID")
 		subMerchantAmount      = flag.Float64("subMerchantAmount", 0, "Sub-merchant amount")
 		subMerchantInvoiceNo   = flag.String("subMerchantInvoiceNo", "", "Sub-merchant invoice number")
 		subMerchantDescription = flag.String("subMerchantDescription", "", "Sub-merchant description")
 	)
+	var userDefined [5]*string
+	for i := range userDefined {
+		userDefined[i] = flag.String(fmt.Sprintf("userDefined%d", i+1), "", fmt.Sprintf("Custom field %d", i+1))
+	}
 	flag.Parse()
 
 	if *secretKey == "" || *merchantID == "" || *invoiceNo == "" || *description == "" || *amountCents == 0 || *currencyCodeISO4217 == "" {
@@ -82,6 +90,8 @@ func main() {
 	}
 
 	client, err := api2c2p.NewClient(api2c2p.Config{
+		Verbose:                  true,
+		AutoNonce:                true,
 		SecretKey:                *secretKey,
 		MerchantID:               *merchantID,
 		PaymentGatewayURL:        *paymentGatewayURL,
@@ -134,6 +144,8 @@ func main() {
 	req := &api2c2p.PaymentTokenRequest{
 		MerchantID:                    *merchantID,
 		IdempotencyID:                 *idempotencyID,
+		NonceStr:                      *nonceStr,
+		Locale:                        *locale,
 		InvoiceNo:                     *invoiceNo,
 		Description:                   *description,
 		AmountCents:                   api2c2p.Cents(*amountCents),
@@ -148,7 +160,7 @@ func main() {
 		Tokenize:                      *tokenize,
 		CardTokens:                    cardTokens,
 		TokenizeOnly:                  *tokenizeOnly,
-		StoreCredentials:              *storeCredentials,
+		StoreCredentials:              api2c2p.StoreCredentials(*storeCredentials),
 		InterestType:                  api2c2p.PaymentTokenInterestType(*interestType),
 		InstallmentPeriodFilterMonths: installmentPeriodFilterMonths,
 		InstallmentBankFilter:         installmentBanks,
@@ -170,25 +182,44 @@ func main() {
 		OriginalAmount:                *originalAmount,
 		ImmediatePayment:              *immediatePayment,
 		IframeMode:                    *iframeMode,
-		UserDefined1:                  *userDefined1,
-		UserDefined2:                  *userDefined2,
-		UserDefined3:                  *userDefined3,
-		UserDefined4:                  *userDefined4,
-		UserDefined5:                  *userDefined5,
+		FrontendReturnURL:             *frontendReturnURL,
+		BackendReturnURL:              *backendReturnURL,
 		StatementDescriptor:           *statementDescriptor,
 		ExternalSubMerchantID:         *externalSubMerchantID,
 	}
 
+	for i, value := range userDefined {
+		if err := req.SetUserDefined(i+1, *value); err != nil {
+			log.Fatalf("Invalid custom field: %v", err)
+		}
+	}
+
+	if *customerName != "" || *customerEmail != "" || *customerMobileNo != "" {
+		req.WithUserInfo(api2c2p.PaymentTokenUserInfo{
+			Name:     *customerName,
+			Email:    *customerEmail,
+			MobileNo: *customerMobileNo,
+		})
+	}
+
+	if *loyaltyRedeemAmount != 0 {
+		req.WithLoyaltyPoints(*loyaltyRedeemAmount)
+	}
+
 	// Add sub-merchant if provided
 	if *subMerchantID != "" {
 		if *subMerchantInvoiceNo == "" || *subMerchantAmount == 0 || *subMerchantDescription == "" {
 			log.Printf("Sub-merchant requires all fields: ID, invoice number, amount, and description")
 			log.Fatal("Invalid sub-merchant")
 		}
+		amount, err := api2c2p.CentsFromFloat64(*subMerchantAmount)
+		if err != nil {
+			log.Fatalf("Invalid sub-merchant amount: %v", err)
+		}
 		req.SubMerchants = []api2c2p.PaymentTokenSubMerchant{
 			{
 				MerchantID:  *subMerchantID,
-				Amount:      *subMerchantAmount,
+				Amount:      amount,
 				InvoiceNo:   *subMerchantInvoiceNo,
 				Description: *subMerchantDescription,
 			},
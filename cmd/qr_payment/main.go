@@ -2,21 +2,23 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
 	"strconv"
-	"time"
+	"strings"
 
 	api2c2p "github.com/choonkeat/2c2p"
 )
 
 var (
 	// Server configuration
-	port      = flag.Int("port", 8080, "Port to run the server on")
-	serverURL = flag.String("serverURL", "http://localhost:8080", "Your server URL prefix (e.g., https://your-domain.com)")
+	port           = flag.Int("port", 8080, "Port to run the server on")
+	serverURL      = flag.String("serverURL", "http://localhost:8080", "Your server URL prefix (e.g., https://your-domain.com)")
+	trustedProxies = flag.String("trustedProxies", "", "Comma-separated IPs or CIDRs of reverse proxies whose X-Forwarded-For is believed (e.g., 127.0.0.1 behind ngrok)")
 
 	// 2C2P configuration
 	merchantID             = flag.String("merchantID", "", "2C2P Merchant ID")
@@ -169,6 +171,7 @@ func main() {
 
 	// Create 2C2P client
 	client, err := api2c2p.NewClient(api2c2p.Config{
+		Verbose:                  true,
 		SecretKey:                *secretKey,
 		MerchantID:               *merchantID,
 		PaymentGatewayURL:        *paymentGatewayURL,
@@ -208,12 +211,14 @@ func main() {
 		}
 
 		// Query payment status
-		status, err := client.PaymentInquiryByToken(r.Context(), &api2c2p.PaymentInquiryByTokenRequest{
+		status, err := client.Inquire(r.Context(), api2c2p.InquiryQuery{
 			PaymentToken: token,
 			Locale:       "en",
 		})
 		log.Printf("Payment status: %#v, %s", status, err)
-		if err != nil {
+		// a declined or pending payment is an *api2c2p.APIError with the status still returned
+		var apiErr *api2c2p.APIError
+		if err != nil && !errors.As(err, &apiErr) {
 			json.NewEncoder(w).Encode(map[string]string{
 				"status":  "error",
 				"message": "Error checking payment status: " + err.Error(),
@@ -227,12 +232,11 @@ func main() {
 			Message string `json:"message"`
 		}
 
-		switch status.RespCode {
-		case "0000":
+		switch code := api2c2p.PaymentResponseCode(status.RespCode); {
+		case code.IsSuccess():
 			response.Status = "success"
 			response.Message = "Payment successful!"
-		case "1005":
-		case "2001":
+		case status.RespCode == api2c2p.Flow10051DisplayGeneratedQrAndWaitForCustomerToScan, code == api2c2p.Code2001TransactionInProgress:
 			response.Status = "pending"
 			response.Message = "Waiting for payment..."
 		default:
@@ -296,11 +300,11 @@ func handleQRPayment(client *api2c2p.Client) http.HandlerFunc {
 		// Create payment token request
 		tokenReq := &api2c2p.PaymentTokenRequest{
 			MerchantID:          *merchantID,
-			InvoiceNo:           fmt.Sprintf("INV%d", time.Now().Unix()),
+			InvoiceNo:           client.GenerateInvoiceNo(),
 			Description:         req.Description,
 			AmountCents:         api2c2p.Cents(amount * 100),
 			CurrencyCodeISO4217: req.Currency,
-			PaymentChannel:      []api2c2p.PaymentTokenPaymentChannel{"QR"},
+			PaymentChannel:      []api2c2p.PaymentTokenPaymentChannel{api2c2p.PaymentChannelQR},
 			//
 			UserDefined1: "1",
 			UserDefined2: "2",
@@ -329,7 +333,7 @@ func handleQRPayment(client *api2c2p.Client) http.HandlerFunc {
 
 		// Create QR payment
 		qrResp, err := client.CreateQRPayment(r.Context(), &api2c2p.CreateQRPaymentParams{
-			ClientIP:           client.GetIPAddress(r),
+			ClientIP:           api2c2p.ClientIPFromRequest(r, strings.Split(*trustedProxies, ",")),
 			PaymentToken:       tokenResult.PaymentToken,
 			PaymentChannelCode: "PNQR",
 			ResponseReturnUrl:  fmt.Sprintf("%s/qr-payment-callback", *serverURL),
@@ -16,6 +16,7 @@ func main() {
 		secretKey              = flag.String("secretKey", "", "Secret Key")
 		invoiceNo              = flag.String("invoiceNo", "", "Invoice number of the transaction to refund")
 		amountCents            = flag.Int64("amountCents", 0, "Amount to refund in cents")
+		childMerchantID        = flag.String("childMerchantID", "", "Child merchant ID, for marketplace payments (optional)")
 		combinedPem            = flag.String("combinedPem", "dist/combined_private_public.pem", "Path to combined private key and certificate PEM file generated by cmd/server-to-server-key/main.go")
 		serverJWTPublicKeyFile = flag.String("serverJWTPublicKey", "dist/sandbox-jwt-2c2p.demo.2.1(public).cer", "Path to 2C2P's public key certificate (.cer file)")
 		serverPKCS7PublicKey   = flag.String("serverPKCS7PublicKey", "dist/sandbox-pkcs7-demo2.2c2p.com(public).cer", "Path to 2C2P's public key certificate (.cer file)")
@@ -32,6 +33,7 @@ func main() {
 
 	// Create client
 	client, err := api2c2p.NewClient(api2c2p.Config{
+		Verbose:                  true,
 		SecretKey:                *secretKey,
 		MerchantID:               *merchantID,
 		PaymentGatewayURL:        *paymentGatewayURL,
@@ -45,7 +47,9 @@ func main() {
 	}
 
 	// Process refund
-	resp, err := client.Refund(context.Background(), *invoiceNo, api2c2p.Cents(*amountCents))
+	resp, err := client.RefundWithOptions(context.Background(), *invoiceNo, api2c2p.Cents(*amountCents), api2c2p.RefundOptions{
+		ChildMerchantID: *childMerchantID,
+	})
 	if err != nil {
 		log.Fatalf("Failed to process refund: %v", err)
 	}
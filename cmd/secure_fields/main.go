@@ -1,9 +1,10 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"html/template"
 	"log"
 	"net/http"
 	"time"
@@ -24,10 +25,13 @@ var (
 	serverPKCS7PublicKey   = flag.String("serverPKCS7PublicKey", "dist/sandbox-pkcs7-demo2.2c2p.com(public).cer", "Path to 2C2P's public key certificate (.cer file)")
 	paymentGatewayURL      = flag.String("paymentGatewayURL", "https://sandbox-pgw.2c2p.com", "2C2P Payment Gateway URL")
 	frontendURL            = flag.String("frontendURL", "https://demo2.2c2p.com", "2C2P Frontend URL")
+	secureFieldsJSURL      = flag.String("secureFieldsJSURL", "", "Override the my2c2p-secureFields script URL")
+	securePayURL           = flag.String("securePayURL", "", "Override the my2c2p SecurePayment script URL")
 
 	// Form configuration
 	formAction       = flag.String("formAction", "/process-payment", "Form action URL")
 	isLoyaltyPayment = flag.Bool("isLoyaltyPayment", false, "Is loyalty payment")
+	apiVersion       = flag.String("apiVersion", api2c2p.DefaultSecureFieldsAPIVersion, "SecureFields API version")
 )
 
 // main starts a web server that demonstrates the 2C2P payment flow:
@@ -40,6 +44,7 @@ func main() {
 
 	// Create 2C2P client
 	client, err := api2c2p.NewClient(api2c2p.Config{
+		Verbose:                  true,
 		SecretKey:                *secretKey,
 		MerchantID:               *merchantID,
 		PaymentGatewayURL:        *paymentGatewayURL,
@@ -53,7 +58,11 @@ func main() {
 	}
 
 	// Generate the payment form HTML with secure fields
-	secureFieldsHTML := api2c2p.SecureFieldsFormHTML(*merchantID, *secretKey, *formAction, *sandbox)
+	secureFieldsHTML := api2c2p.SecureFieldsFormHTMLWithConfig(*merchantID, *secretKey, *formAction, api2c2p.SecureFieldsConfig{
+		Sandbox:      *sandbox,
+		JSURL:        *secureFieldsJSURL,
+		SecurePayURL: *securePayURL,
+	})
 
 	// Handler for the payment form page
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -63,7 +72,9 @@ func main() {
 	})
 
 	// Handler for processing payment form submission
-	http.HandleFunc("/process-payment", handlePaymentRequest)
+	http.HandleFunc("/process-payment", func(w http.ResponseWriter, r *http.Request) {
+		handlePaymentRequest(w, r, client)
+	})
 
 	// Handler for payment response from 2C2P
 	// Create a closure to pass the pre-loaded private key
@@ -72,9 +83,11 @@ func main() {
 	})
 
 	// Handler for backend payment notifications
-	http.HandleFunc("/payment-notify", func(w http.ResponseWriter, r *http.Request) {
-		handlePaymentNotification(w, r, client)
+	notificationHandler := client.NotificationHandler(func(response api2c2p.PaymentResponseBackEnd, decrypted []byte) error {
+		return handlePaymentNotification(context.Background(), client, response, decrypted)
 	})
+	notificationHandler.SeenStore = api2c2p.NewMemorySeenStore(24 * time.Hour) // 2C2P may deliver the same notification again
+	http.Handle("/payment-notify", notificationHandler)
 
 	// Start the server
 	addr := fmt.Sprintf(":%d", *port)
@@ -89,7 +102,7 @@ func main() {
 // 2. Creates a payment request XML
 // 3. Signs the request with HMAC
 // 4. Redirects to 2C2P payment page
-func handlePaymentRequest(w http.ResponseWriter, r *http.Request) {
+func handlePaymentRequest(w http.ResponseWriter, r *http.Request, client *api2c2p.Client) {
 	log.Println(r.Method, r.URL.String())
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -102,9 +115,8 @@ func handlePaymentRequest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Prepare payment request parameters
-	timestamp := fmt.Sprintf("%d", time.Now().Unix())
-	invoiceNo := fmt.Sprintf("INV%s", timestamp)
 	paymentDetails := api2c2p.SecureFieldsPaymentDetails{
+		APIVersion:       *apiVersion,
 		AmountCents:      1234,
 		CurrencyCode:     "702", // SGD
 		IsLoyaltyPayment: *isLoyaltyPayment,
@@ -119,28 +131,18 @@ func handlePaymentRequest(w http.ResponseWriter, r *http.Request) {
 		UserDefined5:     "5",
 	}
 
-	// Create HMAC signature string
-	payload := api2c2p.CreateSecureFieldsPaymentPayload(*frontendURL, *merchantID, *secretKey, timestamp, invoiceNo, paymentDetails, r)
+	// Sign and encode the payment request
+	payload, err := client.BuildSecurePaymentForm(paymentDetails, r.PostFormValue("encryptedCardInfo"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error creating payment request: %v", err), http.StatusInternalServerError)
+		return
+	}
 	log.Printf("Payment request FormURL: %s", payload.FormURL)
 	log.Printf("Payment request FormFields: %#v", payload.FormFields)
 
 	// Render auto-submitting form to 2C2P
 	w.Header().Set("Content-Type", "text/html")
-	tmpl := template.Must(template.New("result").Parse(`<!DOCTYPE html>
-<html>
-<body>
-	<form action="{{.FormURL}}" method="POST" name="paymentRequestForm">
-		<p>Processing payment request. Please do not close the browser, press back or refresh the page.</p>
-		{{range $key, $value := .FormFields}}
-			<input type="hidden" name="{{$key}}" value="{{$value}}">
-		{{end}}
-	</form>
-	<script>document.paymentRequestForm.submit();</script>
-</body>
-</html>`))
-
-	err := tmpl.Execute(w, payload)
-	if err != nil {
+	if err := api2c2p.RenderAutoSubmitForm(w, payload.FormURL, payload.FormFields); err != nil {
 		http.Error(w, fmt.Sprintf("Error rendering template: %v", err), http.StatusInternalServerError)
 	}
 }
@@ -156,129 +158,35 @@ func handlePaymentResponse(w http.ResponseWriter, r *http.Request, client *api2c
 		return
 	}
 
-	// Decrypt and parse the payment response
+	// Decrypt the payment response; the notification handler confirms it with Inquire
 	response, decrypted, err := client.DecryptPaymentResponseBackend(r)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error decrypting payment notification: %v", err), http.StatusBadRequest)
+		http.Error(w, fmt.Sprintf("Error decrypting payment response: %v", err), http.StatusBadRequest)
 		return
 	}
 
 	// Display payment result to customer
-	renderPaymentResult(w, response, string(decrypted))
+	log.Printf("Payment response: %s", decrypted)
+	w.Header().Set("Content-Type", "text/html")
+	if err := api2c2p.RenderPaymentResult(w, response); err != nil {
+		http.Error(w, "Error rendering template: "+err.Error(), http.StatusInternalServerError)
+	}
 }
 
 // handlePaymentNotification processes backend notifications from 2C2P
 // These notifications are used to update the payment status in your system
-func handlePaymentNotification(w http.ResponseWriter, r *http.Request, client *api2c2p.Client) {
-	log.Println(r.Method, r.URL.String())
-	if err := r.ParseForm(); err != nil {
-		http.Error(w, "Error parsing form: "+err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	// Decrypt and parse the payment response
-	response, decrypted, err := client.DecryptPaymentResponseBackend(r)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Error decrypting payment notification: %v", err), http.StatusBadRequest)
-		return
-	}
-
+func handlePaymentNotification(ctx context.Context, client *api2c2p.Client, response api2c2p.PaymentResponseBackEnd, decrypted []byte) error {
 	log.Printf("Payment notification received: RespCode=%s XML=%s", string(response.RespCode), string(decrypted))
 
-	inquiryResponse, err := client.PaymentInquiryByInvoice(r.Context(), &api2c2p.PaymentInquiryByInvoiceRequest{
+	inquiryResponse, err := client.Inquire(ctx, api2c2p.InquiryQuery{
 		InvoiceNo: response.UniqueTransactionCode,
 		Locale:    "en",
 	})
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Error inquiring payment: %v", err), http.StatusInternalServerError)
-		return
+	// a declined payment is an *api2c2p.APIError, which is still a result to record
+	var apiErr *api2c2p.APIError
+	if err != nil && !errors.As(err, &apiErr) {
+		return fmt.Errorf("inquire payment: %w", err)
 	}
 	log.Printf("Payment inquiry result: %#v", inquiryResponse)
-
-	w.WriteHeader(http.StatusOK)
-}
-
-// Helper functions
-
-func renderPaymentResult(w http.ResponseWriter, response api2c2p.PaymentResponseBackEnd, rawResponse string) {
-	w.Header().Set("Content-Type", "text/html")
-
-	type templateData struct {
-		StatusClass string
-		StatusText  string
-		FailReason  template.HTML
-		TransCode   string
-		Amount      string
-		CardNumber  string
-		CardType    string
-		Bank        string
-		RespCode    string
-		DateTime    string
-		RawResponse string
-	}
-
-	isSuccess := response.Status == "A" && response.RespCode == "00"
-
-	statusClass := "failure"
-	if isSuccess {
-		statusClass = "success"
-	}
-
-	statusText := "Failed"
-	if isSuccess {
-		statusText = "Success"
-	}
-
-	var failReasonHTML template.HTML
-	if response.FailReason != "" {
-		failReasonHTML = template.HTML(fmt.Sprintf(`<p class="failure">Reason: %s</p>`, template.HTMLEscapeString(response.FailReason)))
-	}
-
-	tmpl := template.Must(template.New("result").Parse(`<!DOCTYPE html>
-<html>
-<head>
-	<style>
-		.payment-details { margin: 20px; }
-		.payment-details dt { font-weight: bold; margin-top: 10px; }
-		.success { color: green; }
-		.failure { color: red; }
-	</style>
-</head>
-<body>
-	<h1>Payment Result</h1>
-	<div class="payment-details">
-		<h2>Payment Status: <span class="{{.StatusClass}}">{{.StatusText}}</span></h2>
-		{{.FailReason}}
-		<dl>
-			<dt>Transaction Code:</dt><dd>{{.TransCode}}</dd>
-			<dt>Amount:</dt><dd>{{.Amount}}</dd>
-			<dt>Card:</dt><dd>{{.CardNumber}} ({{.CardType}})</dd>
-			<dt>Bank:</dt><dd>{{.Bank}}</dd>
-			<dt>Response Code:</dt><dd>{{.RespCode}}</dd>
-			<dt>DateTime:</dt><dd>{{.DateTime}}</dd>
-		</dl>
-	</div>
-	<h3>Raw Response</h3>
-	<pre>{{.RawResponse}}</pre>
-</body>
-</html>`))
-
-	data := templateData{
-		StatusClass: statusClass,
-		StatusText:  statusText,
-		FailReason:  failReasonHTML,
-		TransCode:   response.UniqueTransactionCode,
-		Amount:      response.Amount,
-		CardNumber:  response.PAN,
-		CardType:    response.CardType,
-		Bank:        response.BankName,
-		RespCode:    string(response.RespCode),
-		DateTime:    response.DateTime,
-		RawResponse: rawResponse,
-	}
-
-	if err := tmpl.Execute(w, data); err != nil {
-		http.Error(w, "Error rendering template: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
+	return nil
 }
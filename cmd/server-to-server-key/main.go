@@ -3,7 +3,7 @@ package main
 // This program generates a self-signed certificate and private key
 // Equivalent OpenSSL commands:
 //
-// 1. Generate private key (2048 bits):
+// 1. Generate private key (2048 bits, or -bits 3072 / 4096):
 //    $ openssl genrsa -out private.pem 2048
 //
 // 2. Generate self-signed certificate (valid for 10 years):
@@ -18,23 +18,21 @@ package main
 //    $ cat private.pem public_cert.pem > combined_private_public.pem
 
 import (
-	"crypto/rand"
-	"crypto/rsa"
-	"crypto/x509"
-	"crypto/x509/pkix"
-	"encoding/pem"
 	"flag"
 	"fmt"
 	"log"
-	"math/big"
 	"os"
+	"path/filepath"
 	"time"
+
+	api2c2p "github.com/choonkeat/2c2p"
 )
 
 func main() {
 	var (
 		outDir = flag.String("out", "dist", "output directory")
 		cn     = flag.String("cn", "2C2P Test CA", "Common Name for the certificate")
+		bits   = flag.Int("bits", api2c2p.DefaultKeySize, fmt.Sprintf("RSA key size, one of %v", api2c2p.KeySizes))
 	)
 	flag.Parse()
 
@@ -43,69 +41,21 @@ func main() {
 		log.Fatalf("Failed to create output directory: %v", err)
 	}
 
-	// Generate RSA key pair
-	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	// Generate RSA key pair and self-signed certificate, valid for 10 years
+	_, certPEM, combinedPEM, err := api2c2p.GenerateMerchantKeyPair(*cn, 10*365*24*time.Hour, *bits)
 	if err != nil {
-		log.Fatalf("Failed to generate private key: %v", err)
-	}
-
-	// Create certificate template
-	template := x509.Certificate{
-		SerialNumber: big.NewInt(1),
-		Subject: pkix.Name{
-			CommonName: *cn,
-		},
-		NotBefore: time.Now(),
-		NotAfter:  time.Now().AddDate(10, 0, 0), // Valid for 10 years
-		KeyUsage:  x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
-		ExtKeyUsage: []x509.ExtKeyUsage{
-			x509.ExtKeyUsageServerAuth,
-			x509.ExtKeyUsageClientAuth,
-		},
-		BasicConstraintsValid: true,
-	}
-
-	// Create certificate
-	derBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, &privateKey.PublicKey, privateKey)
-	if err != nil {
-		log.Fatalf("Failed to create certificate: %v", err)
+		log.Fatalf("Failed to generate key pair: %v", err)
 	}
 
 	// Write public certificate
-	certOut, err := os.Create(*outDir + "/public_cert.pem")
-	if err != nil {
-		log.Fatalf("Failed to open public_cert.pem for writing: %v", err)
-	}
-	if err := pem.Encode(certOut, &pem.Block{Type: "CERTIFICATE", Bytes: derBytes}); err != nil {
-		log.Fatalf("Failed to write data to public_cert.pem: %v", err)
-	}
-	if err := certOut.Close(); err != nil {
-		log.Fatalf("Error closing public_cert.pem: %v", err)
+	if err := os.WriteFile(filepath.Join(*outDir, "public_cert.pem"), certPEM, 0644); err != nil {
+		log.Fatalf("Failed to write public_cert.pem: %v", err)
 	}
 	fmt.Printf("wrote %s/public_cert.pem\n", *outDir)
 
 	// Write combined private key and certificate
-	combinedOut, err := os.Create(*outDir + "/combined_private_public.pem")
-	if err != nil {
-		log.Fatalf("Failed to open combined_private_public.pem for writing: %v", err)
-	}
-
-	// Write private key
-	privBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
-	if err != nil {
-		log.Fatalf("Failed to marshal private key: %v", err)
-	}
-	if err := pem.Encode(combinedOut, &pem.Block{Type: "PRIVATE KEY", Bytes: privBytes}); err != nil {
-		log.Fatalf("Failed to write data to combined_private_public.pem: %v", err)
-	}
-
-	// Write certificate
-	if err := pem.Encode(combinedOut, &pem.Block{Type: "CERTIFICATE", Bytes: derBytes}); err != nil {
-		log.Fatalf("Failed to write data to combined_private_public.pem: %v", err)
-	}
-
-	if err := combinedOut.Close(); err != nil {
-		log.Fatalf("Error closing combined_private_public.pem: %v", err)
+	if err := os.WriteFile(filepath.Join(*outDir, "combined_private_public.pem"), combinedPEM, 0600); err != nil {
+		log.Fatalf("Failed to write combined_private_public.pem: %v", err)
 	}
 	fmt.Printf("wrote %s/combined_private_public.pem\n", *outDir)
 }
@@ -32,6 +32,7 @@ func main() {
 
 	// Create client
 	client, err := api2c2p.NewClient(api2c2p.Config{
+		Verbose:                  true,
 		SecretKey:                *secretKey,
 		MerchantID:               *merchantID,
 		PaymentGatewayURL:        *paymentGatewayURL,
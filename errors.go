@@ -0,0 +1,128 @@
+package api2c2p
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrRSAKeyRequired is returned by operations that 2C2P only supports with RSA keys,
+// i.e. JWE (RSA-OAEP) and PKCS7 decryption, when the client has a non-RSA private key
+var ErrRSAKeyRequired = errors.New("RSA key required for this operation")
+
+// ErrClientClosed is returned by a Client after Close
+var ErrClientClosed = errors.New("client is closed")
+
+// Errors wrapped by KeyConfigError describing what was wrong with a PEM file
+var (
+	ErrNoPrivateKey  = errors.New("no private key found")
+	ErrNoCertificate = errors.New("no certificate found")
+
+	// ErrPassphraseRequired means the private key is encrypted but Config.PrivateKeyPassphrase is empty
+	ErrPassphraseRequired = errors.New("private key is encrypted, a passphrase is required")
+	// ErrIncorrectPassphrase means Config.PrivateKeyPassphrase does not decrypt the private key
+	ErrIncorrectPassphrase = errors.New("incorrect private key passphrase")
+)
+
+// KeyRole identifies a key or certificate configured on the client
+type KeyRole string
+
+const (
+	// KeyRoleCombinedPEM is our private key and certificate, Config.CombinedPEM
+	KeyRoleCombinedPEM KeyRole = "combined private key and certificate"
+	// KeyRoleServerJWT is 2C2P's JWT certificate, Config.ServerJWTPublicKeyFile(s)
+	KeyRoleServerJWT KeyRole = "server JWT certificate"
+	// KeyRoleServerPKCS7 is 2C2P's PKCS7 certificate, Config.ServerPKCS7PublicKeyFile
+	KeyRoleServerPKCS7 KeyRole = "server PKCS7 certificate"
+)
+
+// KeyConfigError is returned by NewClient when a key or certificate cannot be loaded.
+// Err may be a file error (e.g. errors.Is(err, fs.ErrNotExist)), ErrNoPrivateKey,
+// ErrNoCertificate, ErrPassphraseRequired, ErrIncorrectPassphrase, or a parse error
+// for a PEM block of the wrong type
+type KeyConfigError struct {
+	// Role is the key or certificate that failed
+	Role KeyRole
+
+	// File is the path it was read from, or empty when given as PEM data
+	File string
+
+	// Err is the underlying cause
+	Err error
+}
+
+func (e *KeyConfigError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("load %s: %v", e.Role, e.Err)
+	}
+	return fmt.Sprintf("load %s %q: %v", e.Role, e.File, e.Err)
+}
+
+func (e *KeyConfigError) Unwrap() error {
+	return e.Err
+}
+
+// ErrEmptyPaymentToken is returned by PaymentToken when 2C2P responds with a success code
+// but neither a payment token nor a web payment URL, e.g. when the merchant is misconfigured
+var ErrEmptyPaymentToken = errors.New("payment token response has no paymentToken or webPaymentUrl")
+
+// APIError is returned when 2C2P responds with a non-successful response code.
+// Use errors.As to inspect RespCode, or IsResponseCode for a single code
+type APIError struct {
+	// Endpoint is the API that failed, e.g. "paymentToken", "paymentInquiry", "refund", "voidCancel"
+	Endpoint string
+
+	// RespCode is the response code returned by 2C2P
+	RespCode PaymentResponseCode
+
+	// FlowCode is also set when RespCode is a payment flow code rather than a payment
+	// response code, i.e. "Other" for a failed paymentInquiry
+	FlowCode PaymentFlowResponseCode
+
+	// RespDesc is the response description returned by 2C2P
+	RespDesc string
+
+	// Err is the underlying cause, if any
+	Err error
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s failed: %s (%s)", e.Endpoint, e.RespCode, e.RespDesc)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// IsResponseCode reports whether err is, or wraps, an *APIError with the given code
+func IsResponseCode(err error, code PaymentResponseCode) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.RespCode == code
+}
+
+// PaymentResponseFormat is the encoding of a backend payment response
+type PaymentResponseFormat string
+
+const (
+	// PaymentResponseFormatPKCS7 is base64 PKCS7 enveloped data
+	PaymentResponseFormatPKCS7 PaymentResponseFormat = "PKCS7"
+	// PaymentResponseFormatJWS is a compact JWS containing a JWE
+	PaymentResponseFormatJWS PaymentResponseFormat = "JWS"
+)
+
+// PaymentResponseFormatError is returned by DecryptPaymentResponseBackend when
+// the response could not be decrypted, or for JWS, verified
+type PaymentResponseFormatError struct {
+	// Format is the format the response was detected as
+	Format PaymentResponseFormat
+
+	// Err is the underlying cause
+	Err error
+}
+
+func (e *PaymentResponseFormatError) Error() string {
+	return fmt.Sprintf("error decrypting %s response: %v", e.Format, e.Err)
+}
+
+func (e *PaymentResponseFormatError) Unwrap() error {
+	return e.Err
+}
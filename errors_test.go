@@ -0,0 +1,63 @@
+package api2c2p
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestPaymentTokenAPIError(t *testing.T) {
+	client := NewTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"respCode": "4005",
+			"respDesc": "Do not honor",
+		}).SignedString([]byte("test_secret"))
+		if err != nil {
+			t.Errorf("Failed to sign response: %v", err)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"payload": token})
+	})
+
+	_, err := client.PaymentToken(ctx, &PaymentTokenRequest{
+		InvoiceNo:           "INV123",
+		Description:         "Test payment",
+		AmountCents:         100,
+		CurrencyCodeISO4217: "SGD",
+	})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected *APIError, got %T: %v", err, err)
+	}
+	if apiErr.Endpoint != "paymentToken" {
+		t.Errorf("Expected endpoint paymentToken, got %s", apiErr.Endpoint)
+	}
+	if apiErr.RespCode != Code4005DoNotHonor {
+		t.Errorf("Expected response code %s, got %s", Code4005DoNotHonor, apiErr.RespCode)
+	}
+	if apiErr.RespDesc != "Do not honor" {
+		t.Errorf("Expected response description %q, got %q", "Do not honor", apiErr.RespDesc)
+	}
+}
+
+func TestIsResponseCode(t *testing.T) {
+	cause := errors.New("cause")
+	err := fmt.Errorf("checkout: %w", &APIError{Endpoint: "paymentToken", RespCode: Code4005DoNotHonor, Err: cause})
+
+	if !IsResponseCode(err, Code4005DoNotHonor) {
+		t.Errorf("Expected IsResponseCode to match %s", Code4005DoNotHonor)
+	}
+	if IsResponseCode(err, Code0000Successful) {
+		t.Errorf("Expected IsResponseCode not to match %s", Code0000Successful)
+	}
+	if IsResponseCode(cause, Code4005DoNotHonor) {
+		t.Error("Expected IsResponseCode to be false for a non-API error")
+	}
+	if !errors.Is(err, cause) {
+		t.Error("Expected APIError to unwrap to its cause")
+	}
+}
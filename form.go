@@ -0,0 +1,66 @@
+package api2c2p
+
+import (
+	"html/template"
+	"io"
+)
+
+var autoSubmitFormTemplate = template.Must(template.New("autoSubmitForm").Parse(`<!DOCTYPE html>
+<html>
+<body>
+	<form action="{{.URL}}" method="POST" name="paymentRequestForm">
+		<p>Processing payment request. Please do not close the browser, press back or refresh the page.</p>
+		{{range $key, $value := .Fields}}
+			<input type="hidden" name="{{$key}}" value="{{$value}}">
+		{{end}}
+	</form>
+	<script>document.paymentRequestForm.submit();</script>
+</body>
+</html>`))
+
+var paymentResultTemplate = template.Must(template.New("paymentResult").Parse(`<!DOCTYPE html>
+<html>
+<head>
+	<style>
+		.payment-details { margin: 20px; }
+		.payment-details dt { font-weight: bold; margin-top: 10px; }
+		.success { color: green; }
+		.failure { color: red; }
+	</style>
+</head>
+<body>
+	<h1>Payment Result</h1>
+	<div class="payment-details">
+		{{if .IsSuccess}}
+		<h2>Payment Status: <span class="success">Success</span></h2>
+		{{else}}
+		<h2>Payment Status: <span class="failure">Failed</span></h2>
+		{{end}}
+		{{if .FailReason}}<p class="failure">Reason: {{.FailReason}}</p>{{end}}
+		<dl>
+			<dt>Transaction Code:</dt><dd>{{.UniqueTransactionCode}}</dd>
+			<dt>Amount:</dt><dd>{{.Amount}}</dd>
+			<dt>Card:</dt><dd>{{.PAN}} ({{.CardType}})</dd>
+			<dt>Bank:</dt><dd>{{.BankName}}</dd>
+			<dt>Response Code:</dt><dd>{{.RespCode}}</dd>
+			<dt>DateTime:</dt><dd>{{.DateTime}}</dd>
+		</dl>
+	</div>
+</body>
+</html>`))
+
+// RenderAutoSubmitForm writes an HTML page that POSTs fields to url as soon as it loads,
+// e.g. the FormURL and FormFields of a SecureFieldsPaymentPayload. The url and field
+// names and values are HTML-escaped, and fields are written in key order.
+func RenderAutoSubmitForm(w io.Writer, url string, fields map[string]string) error {
+	return autoSubmitFormTemplate.Execute(w, struct {
+		URL    string
+		Fields map[string]string
+	}{url, fields})
+}
+
+// RenderPaymentResult writes a page showing the outcome of resp to the customer.
+// Every field, FailReason included, is rendered as escaped text since they come from 2C2P.
+func RenderPaymentResult(w io.Writer, resp PaymentResponseBackEnd) error {
+	return paymentResultTemplate.Execute(w, resp)
+}
@@ -0,0 +1,60 @@
+package api2c2p
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRenderAutoSubmitForm(t *testing.T) {
+	var buf bytes.Buffer
+	err := RenderAutoSubmitForm(&buf, `https://example.com/pay?a=1&b="2"`, map[string]string{
+		"paymentRequest": `<PaymentRequest>"quoted" & more</PaymentRequest>`,
+		`"><script>`:     "x",
+	})
+	if err != nil {
+		t.Fatalf("Failed to render form: %v", err)
+	}
+	html := buf.String()
+
+	for _, want := range []string{
+		`action="https://example.com/pay?a=1&amp;b=%222%22"`,
+		`name="paymentRequest" value="&lt;PaymentRequest&gt;&#34;quoted&#34; &amp; more&lt;/PaymentRequest&gt;"`,
+		`name="&#34;&gt;&lt;script&gt;"`,
+		`<script>document.paymentRequestForm.submit();</script>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("Expected form to contain %s, got %s", want, html)
+		}
+	}
+	if strings.Count(html, "<script>") != 1 {
+		t.Errorf("Expected only the submit script, got %s", html)
+	}
+}
+
+func TestRenderPaymentResult(t *testing.T) {
+	var buf bytes.Buffer
+	err := RenderPaymentResult(&buf, PaymentResponseBackEnd{
+		RespCode:   "99",
+		Status:     "F",
+		FailReason: `<script>alert("x")</script>`,
+		BankName:   `<b>Bank</b>`,
+	})
+	if err != nil {
+		t.Fatalf("Failed to render payment result: %v", err)
+	}
+	html := buf.String()
+
+	for _, want := range []string{
+		`Reason: &lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;`,
+		`<dd>&lt;b&gt;Bank&lt;/b&gt;</dd>`,
+		`<span class="failure">Failed</span>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("Expected result page to contain %s, got %s", want, html)
+		}
+	}
+	if strings.Contains(html, "<script>") {
+		t.Errorf("Expected no script in result page, got %s", html)
+	}
+}
@@ -7,8 +7,68 @@ import (
 	"strings"
 )
 
+// ClientIPFromRequest returns the end user's IP for DoPaymentParams.ClientIP and CreateQRPaymentParams.ClientIP.
+// X-Forwarded-For and X-Real-Ip are only believed when the request comes from one of trustedProxies
+// (IPs or CIDRs, e.g. "10.0.0.0/8"), and X-Forwarded-For is read right to left up to the first
+// address that is not a trusted proxy, so a client cannot spoof its IP by sending the headers itself.
+// Otherwise the IP of r.RemoteAddr is returned
+func ClientIPFromRequest(r *http.Request, trustedProxies []string) string {
+	remoteIP := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		remoteIP = host
+	}
+	trusted := parseTrustedProxies(trustedProxies)
+	if !isTrustedProxy(trusted, net.ParseIP(remoteIP)) {
+		return remoteIP
+	}
+
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		addresses := strings.Split(strings.Join(forwarded, ","), ",")
+		clientIP := remoteIP
+		for i := len(addresses) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(addresses[i]))
+			if ip == nil {
+				break // garbage is not to be believed, nor anything to the left of it
+			}
+			clientIP = ip.String()
+			if !isTrustedProxy(trusted, ip) {
+				break
+			}
+		}
+		return clientIP
+	}
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-Ip"))); ip != nil {
+		return ip.String()
+	}
+	return remoteIP
+}
+
+func parseTrustedProxies(trustedProxies []string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, proxy := range trustedProxies {
+		if _, ipNet, err := net.ParseCIDR(proxy); err == nil {
+			nets = append(nets, ipNet)
+		} else if ip := net.ParseIP(proxy); ip != nil {
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+		}
+	}
+	return nets
+}
+
+func isTrustedProxy(trusted []*net.IPNet, ip net.IP) bool {
+	for _, ipNet := range trusted {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
 // https://husobee.github.io/golang/ip-address/2015/12/17/remote-ip-go.html
 
+// GetIPAddress returns the rightmost public address in X-Forwarded-For or X-Real-Ip, or r.RemoteAddr.
+//
+// Deprecated: the headers are believed from anyone, so clients can spoof them; use ClientIPFromRequest
 func (c *Client) GetIPAddress(r *http.Request) string {
 	for _, h := range []string{"X-Forwarded-For", "X-Real-Ip"} {
 		addresses := strings.Split(r.Header.Get(h), ",")
@@ -0,0 +1,69 @@
+package api2c2p
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIPFromRequest(t *testing.T) {
+	trusted := []string{"10.0.0.0/8", "192.0.2.1"}
+	testCases := []struct {
+		name       string
+		remoteAddr string
+		xff        []string
+		xRealIP    string
+		want       string
+	}{
+		{name: "direct", remoteAddr: "203.0.113.7:5000", want: "203.0.113.7"},
+		{
+			name:       "spoofed headers from untrusted client",
+			remoteAddr: "203.0.113.7:5000",
+			xff:        []string{"1.2.3.4"},
+			xRealIP:    "5.6.7.8",
+			want:       "203.0.113.7",
+		},
+		{name: "one trusted proxy", remoteAddr: "10.0.0.2:5000", xff: []string{"203.0.113.7"}, want: "203.0.113.7"},
+		{
+			name:       "chain of trusted proxies",
+			remoteAddr: "10.0.0.2:5000",
+			xff:        []string{"203.0.113.7, 192.0.2.1, 10.0.0.3"},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "client prepends a spoofed address",
+			remoteAddr: "10.0.0.2:5000",
+			xff:        []string{"1.2.3.4, 203.0.113.7"},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "multiple headers",
+			remoteAddr: "10.0.0.2:5000",
+			xff:        []string{"1.2.3.4", "203.0.113.7, 10.0.0.3"},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "garbage in chain",
+			remoteAddr: "10.0.0.2:5000",
+			xff:        []string{"203.0.113.7, not-an-ip, 10.0.0.3"},
+			want:       "10.0.0.3",
+		},
+		{name: "x-real-ip from trusted proxy", remoteAddr: "192.0.2.1:5000", xRealIP: "203.0.113.7", want: "203.0.113.7"},
+		{name: "ipv6", remoteAddr: "[2001:db8::1]:5000", want: "2001:db8::1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tc.remoteAddr
+			for _, xff := range tc.xff {
+				r.Header.Add("X-Forwarded-For", xff)
+			}
+			if tc.xRealIP != "" {
+				r.Header.Set("X-Real-Ip", tc.xRealIP)
+			}
+			if got := ClientIPFromRequest(r, trusted); got != tc.want {
+				t.Errorf("Expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
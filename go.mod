@@ -7,6 +7,5 @@ require (
 	github.com/go-jose/go-jose/v4 v4.0.4
 	github.com/golang-jwt/jwt/v5 v5.2.1
 	github.com/google/uuid v1.6.0
+	golang.org/x/crypto v0.32.0
 )
-
-require golang.org/x/crypto v0.32.0 // indirect
@@ -0,0 +1,93 @@
+package api2c2p
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// PreparedRequest is a signed request exactly as it would be sent, for comparing against the
+// 2C2P docs when a request is rejected. See InspectPaymentToken and InspectRefund
+type PreparedRequest struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+
+	// Claims are the decoded JWT claims of a payment token request; like FieldMap, numbers are json.Number
+	Claims map[string]any
+
+	// Payload is the XML encrypted into the JWE of a refund request. Only 2C2P can decrypt
+	// the JWE itself, so this is the plaintext before encryption
+	Payload []byte
+}
+
+func newPreparedRequest(httpReq *http.Request) (*PreparedRequest, error) {
+	body, err := io.ReadAll(httpReq.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read request body: %w", err)
+	}
+	return &PreparedRequest{
+		Method: httpReq.Method,
+		URL:    httpReq.URL.String(),
+		Header: httpReq.Header,
+		Body:   body,
+	}, nil
+}
+
+// InspectPaymentToken returns the request PaymentToken would send for req, without sending it.
+// req is defaulted and validated the same way, including AutoIdempotency
+func (c *Client) InspectPaymentToken(ctx context.Context, req *PaymentTokenRequest) (*PreparedRequest, error) {
+	req, err := c.preparePaymentToken(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := c.newPaymentTokenRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	prepared, err := newPreparedRequest(httpReq)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode our own JWT back, which also checks it verifies with SecretKey
+	var body struct {
+		Payload string `json:"payload"`
+	}
+	if err := json.Unmarshal(prepared.Body, &body); err != nil {
+		return nil, fmt.Errorf("unmarshal request body: %w", err)
+	}
+	var claims json.RawMessage
+	if err := c.decodeJWTTokenForJSON(body.Payload, &claims); err != nil {
+		return nil, fmt.Errorf("decode jwt token: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(claims))
+	decoder.UseNumber()
+	if err := decoder.Decode(&prepared.Claims); err != nil {
+		return nil, fmt.Errorf("unmarshal jwt claims: %w", err)
+	}
+	return prepared, nil
+}
+
+// InspectRefund returns the request RefundWithOptions would send, without sending it
+func (c *Client) InspectRefund(ctx context.Context, invoiceNo string, amount Cents, opts RefundOptions) (*PreparedRequest, error) {
+	req := c.newRefundRequest(invoiceNo, amount, opts)
+	payload, err := marshalPaymentProcessRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := c.NewPaymentProcessRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	prepared, err := newPreparedRequest(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	prepared.Payload = payload
+	return prepared, nil
+}
@@ -0,0 +1,88 @@
+package api2c2p
+
+import (
+	"encoding/xml"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInspectPaymentToken(t *testing.T) {
+	client := NewTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Expected no request to be sent, got %s %s", r.Method, r.URL)
+	})
+
+	req := &PaymentTokenRequest{
+		InvoiceNo:           "INV123",
+		Description:         "Test payment",
+		AmountCents:         1234,
+		CurrencyCodeISO4217: "SGD",
+		RecurringCount:      12,
+	}
+	prepared, err := client.InspectPaymentToken(ctx, req)
+	if err != nil {
+		t.Fatalf("Failed to inspect payment token: %v", err)
+	}
+
+	if want := client.PaymentGatewayURL + "/payment/4.3/paymentToken"; prepared.URL != want {
+		t.Errorf("Expected URL %s, got %s", want, prepared.URL)
+	}
+	if prepared.Method != http.MethodPost {
+		t.Errorf("Expected method POST, got %s", prepared.Method)
+	}
+	if got := prepared.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Expected Content-Type application/json, got %s", got)
+	}
+	if !strings.HasPrefix(string(prepared.Body), `{"payload":"eyJ`) {
+		t.Errorf("Expected body with JWT payload, got %s", prepared.Body)
+	}
+	if prepared.Claims["merchantID"] != "JT01" {
+		t.Errorf("Expected merchantID claim JT01, got %v", prepared.Claims["merchantID"])
+	}
+	if want := req.FieldMap(); !reflect.DeepEqual(prepared.Claims, want) {
+		t.Errorf("Expected claims %v, got %v", want, prepared.Claims)
+	}
+
+	if _, err := client.InspectPaymentToken(ctx, &PaymentTokenRequest{Recurring: true}); err == nil {
+		t.Error("Expected invalid request to be rejected")
+	}
+}
+
+func TestInspectRefund(t *testing.T) {
+	client := NewTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Expected no request to be sent, got %s %s", r.Method, r.URL)
+	})
+
+	prepared, err := client.InspectRefund(ctx, "INV123", 1050, RefundOptions{ChildMerchantID: "CHILD01"})
+	if err != nil {
+		t.Fatalf("Failed to inspect refund: %v", err)
+	}
+
+	if want := client.FrontendURL + "/2C2PFrontend/PaymentAction/2.0/action"; prepared.URL != want {
+		t.Errorf("Expected URL %s, got %s", want, prepared.URL)
+	}
+	if got := prepared.Header.Get("Content-Type"); got != "text/plain" {
+		t.Errorf("Expected Content-Type text/plain, got %s", got)
+	}
+	if parts := strings.Split(string(prepared.Body), "."); len(parts) != 3 {
+		t.Errorf("Expected compact JWS body, got %s", prepared.Body)
+	}
+
+	var payload PaymentProcessRequest
+	if err := xml.Unmarshal(prepared.Payload, &payload); err != nil {
+		t.Fatalf("Failed to unmarshal payload: %v", err)
+	}
+	if payload.InvoiceNo != "INV123" {
+		t.Errorf("Expected invoiceNo INV123, got %s", payload.InvoiceNo)
+	}
+	if payload.ProcessType != "R" {
+		t.Errorf("Expected processType R, got %s", payload.ProcessType)
+	}
+	if got := payload.ActionAmount.String(); got != "10.50" {
+		t.Errorf("Expected actionAmount 10.50, got %s", got)
+	}
+	if payload.ChildMerchantID == nil || *payload.ChildMerchantID != "CHILD01" {
+		t.Errorf("Expected childMerchantID CHILD01, got %v", payload.ChildMerchantID)
+	}
+}
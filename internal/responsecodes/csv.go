@@ -0,0 +1,84 @@
+// Package responsecodes extracts 2C2P response codes from the CSV tables in docs/2c2p,
+// for the code generators and for tests checking the generated files are up to date
+package responsecodes
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Code is a response code and its description
+type Code struct {
+	Code        string
+	Description string
+}
+
+// ReadPaymentCSV reads docs/2c2p/response-code-payment.csv, with code and description columns
+func ReadPaymentCSV(r io.Reader) ([]Code, error) {
+	rows, err := readPaddedCSV(r)
+	if err != nil {
+		return nil, err
+	}
+	var codes []Code
+	for i, row := range rows {
+		if i == 0 || len(row) < 2 { // Skip header row and invalid rows
+			continue
+		}
+		code := strings.TrimSpace(row[0])
+		desc := strings.TrimSpace(row[1])
+		if code == "" || desc == "" {
+			continue
+		}
+		codes = append(codes, Code{Code: code, Description: desc})
+	}
+	if len(codes) == 0 {
+		return nil, fmt.Errorf("no response codes found in CSV")
+	}
+	return codes, nil
+}
+
+// ReadPaymentFlowCSV reads docs/2c2p/response-code-payment-flow.csv, with code, action and
+// description columns; the description may span lines
+func ReadPaymentFlowCSV(r io.Reader) ([]Code, error) {
+	rows, err := readPaddedCSV(r)
+	if err != nil {
+		return nil, err
+	}
+	var codes []Code
+	for i, row := range rows {
+		if i == 0 || len(row) < 3 { // Skip header row and invalid rows
+			continue
+		}
+		fields := strings.Fields(row[0])
+		if len(fields) == 0 {
+			continue
+		}
+		code := strings.TrimSpace(fields[0])
+		desc := strings.TrimSpace(strings.Join(strings.Split(row[2], "\n"), " "))
+		if code == "" || desc == "" {
+			continue
+		}
+		codes = append(codes, Code{Code: code, Description: desc})
+	}
+	if len(codes) == 0 {
+		return nil, fmt.Errorf("no response codes found in CSV")
+	}
+	return codes, nil
+}
+
+// readPaddedCSV reads all rows, formatting each cell as at least 4 characters
+// with leading zeros, e.g. code "0" becomes "0000"
+func readPaddedCSV(r io.Reader) ([][]string, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	for i, row := range rows {
+		for j, cell := range row {
+			rows[i][j] = fmt.Sprintf("%04s", cell)
+		}
+	}
+	return rows, nil
+}
@@ -0,0 +1,55 @@
+package api2c2p
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// maxInvoiceNoLength is the longest invoiceNo 2C2P accepts
+const maxInvoiceNoLength = 50
+
+// InvoiceNoGenerator generates merchant invoice numbers that are unique per request
+type InvoiceNoGenerator interface {
+	GenerateInvoiceNo() string
+}
+
+// NewInvoiceNoGenerator returns an InvoiceNoGenerator of prefix followed by the Unix time of clock,
+// a random tag identifying this generator and a sequence number, e.g. INV1707210770A1B2C3D41.
+// The sequence keeps numbers from one generator unique within the same second, and the tag
+// keeps them apart from other processes. prefix is shortened to keep the result within 50 characters.
+// It panics if crypto/rand fails, which it can before Go 1.24
+func NewInvoiceNoGenerator(prefix string, clock func() time.Time) InvoiceNoGenerator {
+	if clock == nil {
+		clock = time.Now
+	}
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("api2c2p: NewInvoiceNoGenerator: crypto/rand: %v", err))
+	}
+	return &invoiceNoGenerator{
+		prefix: prefix,
+		tag:    strings.ToUpper(hex.EncodeToString(b)),
+		now:    clock,
+	}
+}
+
+type invoiceNoGenerator struct {
+	prefix string
+	tag    string
+	now    func() time.Time
+	seq    atomic.Uint64
+}
+
+func (g *invoiceNoGenerator) GenerateInvoiceNo() string {
+	suffix := strconv.FormatInt(g.now().Unix(), 10) + g.tag + strconv.FormatUint(g.seq.Add(1), 10)
+	prefix := g.prefix
+	if n := maxInvoiceNoLength - len(suffix); len(prefix) > n {
+		prefix = prefix[:n]
+	}
+	return prefix + suffix
+}
@@ -0,0 +1,62 @@
+package api2c2p
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestInvoiceNoGenerator(t *testing.T) {
+	clock := func() time.Time { return time.Unix(1707210770, 0) }
+	generator := NewInvoiceNoGenerator("INV", clock)
+
+	const workers, perWorker = 20, 500
+	results := make(chan string, workers*perWorker)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perWorker; j++ {
+				results <- generator.GenerateInvoiceNo()
+			}
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	seen := map[string]bool{}
+	for invoiceNo := range results {
+		if seen[invoiceNo] {
+			t.Fatalf("Expected unique invoice numbers, got %s twice", invoiceNo)
+		}
+		seen[invoiceNo] = true
+		if !strings.HasPrefix(invoiceNo, "INV1707210770") {
+			t.Errorf("Expected invoice number to start with INV1707210770, got %s", invoiceNo)
+		}
+		if len(invoiceNo) > maxInvoiceNoLength {
+			t.Errorf("Expected invoice number within %d characters, got %s", maxInvoiceNoLength, invoiceNo)
+		}
+	}
+	if len(seen) != workers*perWorker {
+		t.Errorf("Expected %d invoice numbers, got %d", workers*perWorker, len(seen))
+	}
+
+	other := NewInvoiceNoGenerator("INV", clock)
+	if got := other.GenerateInvoiceNo(); seen[got] {
+		t.Errorf("Expected another generator to not repeat %s", got)
+	}
+
+	long := NewInvoiceNoGenerator(strings.Repeat("P", 60), clock)
+	if got := long.GenerateInvoiceNo(); len(got) != maxInvoiceNoLength {
+		t.Errorf("Expected long prefix to be shortened to %d characters, got %s", maxInvoiceNoLength, got)
+	}
+}
+
+func TestClientGenerateInvoiceNo(t *testing.T) {
+	client := NewTestClient(t, nil)
+	if a, b := client.GenerateInvoiceNo(), client.GenerateInvoiceNo(); a == b || !strings.HasPrefix(a, "INV") {
+		t.Errorf("Expected distinct invoice numbers with INV prefix, got %s and %s", a, b)
+	}
+}
@@ -0,0 +1,65 @@
+package api2c2p
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"slices"
+	"time"
+)
+
+// DefaultKeySize is the RSA key size GenerateMerchantKeyPair uses when none is given
+const DefaultKeySize = 2048
+
+// KeySizes are the RSA key sizes GenerateMerchantKeyPair accepts
+var KeySizes = []int{2048, 3072, 4096}
+
+// GenerateMerchantKeyPair generates an RSA private key of keySize bits (0 for DefaultKeySize)
+// and a self-signed certificate with common name cn, valid for validFor, for server-to-server
+// (refund/void) requests. The certificate is shared with 2C2P, and combinedPEM (private key
+// followed by certificate) is what Config.CombinedPEM expects
+func GenerateMerchantKeyPair(cn string, validFor time.Duration, keySize int) (privatePEM, certPEM, combinedPEM []byte, err error) {
+	if keySize == 0 {
+		keySize = DefaultKeySize
+	}
+	if !slices.Contains(KeySizes, keySize) {
+		return nil, nil, nil, fmt.Errorf("unsupported key size %d, expected one of %v", keySize, KeySizes)
+	}
+	privateKey, err := rsa.GenerateKey(rand.Reader, keySize)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("generate private key: %w", err)
+	}
+
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{
+			CommonName: cn,
+		},
+		NotBefore: now,
+		NotAfter:  now.Add(validFor),
+		KeyUsage:  x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{
+			x509.ExtKeyUsageServerAuth,
+			x509.ExtKeyUsageClientAuth,
+		},
+		BasicConstraintsValid: true,
+	}
+	derBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("create certificate: %w", err)
+	}
+	privBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("marshal private key: %w", err)
+	}
+
+	privatePEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privBytes})
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: derBytes})
+	combinedPEM = append(append([]byte{}, privatePEM...), certPEM...)
+	return privatePEM, certPEM, combinedPEM, nil
+}
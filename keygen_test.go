@@ -0,0 +1,70 @@
+package api2c2p
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"testing"
+	"time"
+)
+
+func TestGenerateMerchantKeyPair(t *testing.T) {
+	privatePEM, certPEM, combinedPEM, err := GenerateMerchantKeyPair("merchant.example.com", 24*time.Hour, 0)
+	if err != nil {
+		t.Fatalf("GenerateMerchantKeyPair failed: %v", err)
+	}
+	if !bytes.Equal(combinedPEM, append(append([]byte{}, privatePEM...), certPEM...)) {
+		t.Error("Expected combined PEM to be the private key followed by the certificate")
+	}
+
+	privateKey, cert, err := loadPrivateKeyAndCert(combinedPEM, "")
+	if err != nil {
+		t.Fatalf("Failed to load combined PEM: %v", err)
+	}
+	rsaKey, ok := privateKey.(*rsa.PrivateKey)
+	if !ok {
+		t.Fatalf("Expected *rsa.PrivateKey, got %T", privateKey)
+	}
+	if !rsaKey.PublicKey.Equal(cert.PublicKey) {
+		t.Error("Expected certificate to hold the generated public key")
+	}
+	if size := rsaKey.N.BitLen(); size != DefaultKeySize {
+		t.Errorf("Expected %d bit key, got %d", DefaultKeySize, size)
+	}
+	if cert.Subject.CommonName != "merchant.example.com" {
+		t.Errorf("Expected CN merchant.example.com, got %s", cert.Subject.CommonName)
+	}
+	if validFor := cert.NotAfter.Sub(cert.NotBefore); validFor != 24*time.Hour {
+		t.Errorf("Expected certificate valid for 24h, got %s", validFor)
+	}
+
+	// the generated key pair is usable as a client key
+	if _, err := NewClient(Config{
+		SecretKey:                "test_secret",
+		MerchantID:               "JT01",
+		CombinedPEMData:          combinedPEM,
+		ServerJWTPublicKeyData:   certPEM,
+		ServerPKCS7PublicKeyFile: "testdata/server.pkcs7.public_cert.pem",
+	}); err != nil {
+		t.Errorf("Failed to create client with generated key pair: %v", err)
+	}
+}
+
+func TestGenerateMerchantKeyPairKeySize(t *testing.T) {
+	_, _, combinedPEM, err := GenerateMerchantKeyPair("merchant.example.com", time.Hour, 3072)
+	if err != nil {
+		t.Fatalf("GenerateMerchantKeyPair failed: %v", err)
+	}
+	privateKey, _, err := loadPrivateKeyAndCert(combinedPEM, "")
+	if err != nil {
+		t.Fatalf("Failed to load combined PEM: %v", err)
+	}
+	if size := privateKey.(*rsa.PrivateKey).N.BitLen(); size != 3072 {
+		t.Errorf("Expected 3072 bit key, got %d", size)
+	}
+
+	for _, keySize := range []int{1024, 2000, 8192} {
+		if _, _, _, err := GenerateMerchantKeyPair("merchant.example.com", time.Hour, keySize); err == nil {
+			t.Errorf("Expected error for key size %d", keySize)
+		}
+	}
+}
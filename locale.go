@@ -0,0 +1,17 @@
+package api2c2p
+
+import (
+	"fmt"
+	"slices"
+)
+
+// DefaultLocales are the ISO 639 language codes supported by the 2C2P payment page and APIs
+var DefaultLocales = []string{"en", "th", "zh", "ja", "ko", "id", "ms", "my", "vi"}
+
+// validateLocale returns an error if locale is not one of c.Locales; empty uses the server default
+func (c *Client) validateLocale(locale string) error {
+	if locale == "" || slices.Contains(c.Locales, locale) {
+		return nil
+	}
+	return fmt.Errorf("unsupported locale %q, expected one of %v", locale, c.Locales)
+}
@@ -0,0 +1,51 @@
+package api2c2p
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateLocale(t *testing.T) {
+	cfg := testConfig()
+	cfg.PaymentGatewayURL = "http://127.0.0.1:0" // requests must not be sent
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	testCases := []struct {
+		name    string
+		locale  string
+		locales []string
+		wantErr bool
+	}{
+		{name: "valid", locale: "th"},
+		{name: "empty", locale: ""},
+		{name: "invalid", locale: "english", wantErr: true},
+		{name: "case sensitive", locale: "EN", wantErr: true},
+		{name: "overridden", locale: "fil", locales: append([]string{"fil"}, DefaultLocales...)},
+		{name: "not in override", locale: "en", locales: []string{"th"}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client.Locales = DefaultLocales
+			if tc.locales != nil {
+				client.Locales = tc.locales
+			}
+			err := client.validateLocale(tc.locale)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Expected error %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+
+	// requests with an unsupported locale fail before being sent
+	client.Locales = DefaultLocales
+	if _, err := client.Inquire(ctx, InquiryQuery{InvoiceNo: "INV123", Locale: "english"}); err == nil || !strings.Contains(err.Error(), `unsupported locale "english"`) {
+		t.Errorf("Expected unsupported locale error from Inquire, got %v", err)
+	}
+	if _, err := client.PaymentToken(ctx, &PaymentTokenRequest{InvoiceNo: "INV123", Locale: "english"}); err == nil || !strings.Contains(err.Error(), `unsupported locale "english"`) {
+		t.Errorf("Expected unsupported locale error from PaymentToken, got %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package api2c2p
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Logger is a structured logger. keyvals are alternating key and value pairs,
+// e.g. Info("request completed", "status", 200); *slog.Logger satisfies it as-is
+type Logger interface {
+	Debug(msg string, keyvals ...any)
+	Info(msg string, keyvals ...any)
+	Error(msg string, keyvals ...any)
+}
+
+// stdLogger adapts a stdlib *log.Logger to Logger
+type stdLogger struct {
+	logger *log.Logger
+}
+
+// NewStdLogger adapts a stdlib *log.Logger to Logger, printing each entry as
+// `[LEVEL] msg key=value key=value`. A nil logger uses log.Default()
+func NewStdLogger(logger *log.Logger) Logger {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return stdLogger{logger: logger}
+}
+
+func (l stdLogger) Debug(msg string, keyvals ...any) { l.print("DEBUG", msg, keyvals) }
+func (l stdLogger) Info(msg string, keyvals ...any)  { l.print("INFO", msg, keyvals) }
+func (l stdLogger) Error(msg string, keyvals ...any) { l.print("ERROR", msg, keyvals) }
+
+func (l stdLogger) print(level, msg string, keyvals []any) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[%s] %s", level, msg)
+	for i := 0; i < len(keyvals); i += 2 {
+		if i+1 < len(keyvals) {
+			fmt.Fprintf(&sb, " %v=%v", keyvals[i], keyvals[i+1])
+		} else {
+			fmt.Fprintf(&sb, " %v=MISSING", keyvals[i])
+		}
+	}
+	l.logger.Print(sb.String())
+}
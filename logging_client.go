@@ -2,31 +2,86 @@ package api2c2p
 
 import (
 	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"regexp"
+	"strings"
 	"time"
 )
 
 // LoggingClient wraps an http.Client to provide logging capabilities
 type LoggingClient struct {
 	client  *http.Client
-	logger  *log.Logger
+	logger  Logger
 	verbose bool
+
+	// Redactor is applied to every request and response body before it is logged.
+	// Set to nil to log bodies as-is. Default: DefaultRedactor
+	Redactor func([]byte) []byte
+
+	// MaxLogBodyBytes caps how much of each body is buffered for logging; the rest
+	// streams to the caller untouched, and the value cut off at the cap is not logged.
+	// Values <= 0 disable the cap. Default: DefaultMaxLogBodyBytes
+	MaxLogBodyBytes int
+}
+
+// DefaultMaxLogBodyBytes is the default LoggingClient.MaxLogBodyBytes
+const DefaultMaxLogBodyBytes = 64 << 10
+
+// truncatedMarker is appended to logged bodies that exceed MaxLogBodyBytes
+const truncatedMarker = "...[truncated]"
+
+var (
+	// JSON `"key":"value"` and form `key=value` encodings of sensitive fields
+	redactJSONFieldPattern = regexp.MustCompile(`("(?:encryptedCardInfo|paymentRequest|paymentResponse)"\s*:\s*")[^"]*(")`)
+	redactFormFieldPattern = regexp.MustCompile(`((?:^|&)(?:encryptedCardInfo|paymentRequest|paymentResponse)=)[^&]*`)
+	// header.payload.signature; only the signature is masked so the claims remain debuggable
+	redactJWTPattern = regexp.MustCompile(`(eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]*\.)[A-Za-z0-9_-]+`)
+	// 13-19 digit runs look like card numbers; keep first 6 and last 4 like MaskedCardInfo
+	redactPANPattern = regexp.MustCompile(`\b(\d{6})\d{3,9}(\d{4})\b`)
+	// the token cut off at the end of a truncated body, e.g. part of a JSON string, form value
+	// or card number, which a redactor can no longer recognize
+	partialFieldPattern = regexp.MustCompile(`[^\s"',:;&<>{}\[\]]+$`)
+)
+
+// DefaultRedactor masks encrypted card data, payment request/response blobs,
+// JWT signatures and PAN-like digit runs in a logged body
+func DefaultRedactor(body []byte) []byte {
+	body = redactJSONFieldPattern.ReplaceAll(body, []byte("${1}[REDACTED]${2}"))
+	body = redactFormFieldPattern.ReplaceAll(body, []byte("${1}[REDACTED]"))
+	body = redactJWTPattern.ReplaceAll(body, []byte("${1}[REDACTED]"))
+	body = redactPANPattern.ReplaceAll(body, []byte("${1}******${2}"))
+	return body
 }
 
-// NewLoggingClient creates a new LoggingClient
+// NewLoggingClient creates a new LoggingClient printing to a stdlib logger.
+// A nil logger logs to log.Default()
+//
+// Deprecated: use NewStructuredLoggingClient, with NewStdLogger for a *log.Logger
 func NewLoggingClient(client *http.Client, logger *log.Logger, verbose bool) *LoggingClient {
+	return NewStructuredLoggingClient(client, NewStdLogger(logger), verbose)
+}
+
+// NewStructuredLoggingClient creates a new LoggingClient. A nil logger logs to log.Default();
+// wrap an existing *log.Logger with NewStdLogger
+func NewStructuredLoggingClient(client *http.Client, logger Logger, verbose bool) *LoggingClient {
 	if client == nil {
 		client = http.DefaultClient
 	}
 	if logger == nil {
-		logger = log.Default()
+		logger = NewStdLogger(nil)
 	}
 	return &LoggingClient{
-		client:  client,
-		logger:  logger,
-		verbose: verbose,
+		client:   client,
+		logger:   logger,
+		verbose:  verbose,
+		Redactor: DefaultRedactor,
+
+		MaxLogBodyBytes: DefaultMaxLogBodyBytes,
 	}
 }
 
@@ -41,50 +96,116 @@ func (c *LoggingClient) Do(req *http.Request) (*http.Response, error) {
 	duration := time.Since(start)
 
 	if err != nil {
-		c.logger.Printf("[ERROR] Request failed: %v", err)
+		c.logger.Error("request failed", "method", req.Method, "url", req.URL, "duration", duration, "error", err)
 		return resp, err
 	}
 
+	// Decompress before logging, so both the log and the caller see the plain body
+	if err := decompressBody(resp); err != nil {
+		c.logger.Error("decompress response body", "method", req.Method, "url", req.URL, "error", err)
+		return nil, err
+	}
+
 	// Log response
+	c.logger.Info("request completed", "method", req.Method, "url", req.URL, "status", resp.StatusCode, "duration", duration)
 	if c.verbose {
-		c.logResponse(resp, duration)
-	} else {
-		c.logger.Printf("[INFO] %s %s -> %s (%.3fs)", req.Method, req.URL, resp.Status, duration.Seconds())
+		c.logResponse(req, resp)
 	}
 
 	return resp, nil
 }
 
 func (c *LoggingClient) logRequest(req *http.Request) {
-	c.logger.Printf("[REQUEST] %s %s", req.Method, req.URL)
-	c.logger.Printf("[REQUEST HEADERS] %v", req.Header)
+	keyvals := []any{"method", req.Method, "url", req.URL, "headers", req.Header}
 
-	if req.Body != nil {
+	if req.Body != nil && req.GetBody != nil {
+		// GetBody returns a fresh reader, so req.Body is left unread for the actual request
 		if body, err := req.GetBody(); err == nil {
-			buf := new(bytes.Buffer)
-			if _, err := io.Copy(buf, body); err == nil {
-				c.logger.Printf("[REQUEST BODY] %s", buf.String())
-				// Reset the body for the actual request
-				req.Body = io.NopCloser(bytes.NewBuffer(buf.Bytes()))
+			prefix, err := c.readLogPrefix(body)
+			body.Close()
+			if err == nil {
+				keyvals = append(keyvals, "body", c.formatLogBody(prefix))
 			}
 		}
 	}
+	c.logger.Debug("request", keyvals...)
 }
 
-func (c *LoggingClient) logResponse(resp *http.Response, duration time.Duration) {
-	c.logger.Printf("[RESPONSE] %s (%.3fs)", resp.Status, duration.Seconds())
-	c.logger.Printf("[RESPONSE HEADERS] %v", resp.Header)
+func (c *LoggingClient) logResponse(req *http.Request, resp *http.Response) {
+	keyvals := []any{"method", req.Method, "url", req.URL, "status", resp.StatusCode, "headers", resp.Header}
 
 	if resp.Body != nil {
-		// Create a copy of the body
-		bodyBytes, err := io.ReadAll(resp.Body)
+		// Only buffer a prefix; the remainder is read by the caller as usual
+		prefix, err := c.readLogPrefix(resp.Body)
+		// Replace the body for downstream consumers
+		resp.Body = prefixedReadCloser{
+			Reader: io.MultiReader(bytes.NewReader(prefix), resp.Body),
+			Closer: resp.Body,
+		}
 		if err != nil {
-			c.logger.Printf("[ERROR] Failed to read response body: %v", err)
+			c.logger.Error("read response body", "method", req.Method, "url", req.URL, "error", err)
 			return
 		}
-		// Replace the body for downstream consumers
-		resp.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
-		// Log the body
-		c.logger.Printf("[RESPONSE BODY] %s", string(bodyBytes))
+		keyvals = append(keyvals, "body", c.formatLogBody(prefix))
+	}
+	c.logger.Debug("response", keyvals...)
+}
+
+// readLogPrefix reads up to MaxLogBodyBytes+1 bytes so formatLogBody can tell if it was truncated
+func (c *LoggingClient) readLogPrefix(r io.Reader) ([]byte, error) {
+	if c.MaxLogBodyBytes <= 0 {
+		return io.ReadAll(r)
+	}
+	return io.ReadAll(io.LimitReader(r, int64(c.MaxLogBodyBytes)+1))
+}
+
+func (c *LoggingClient) formatLogBody(prefix []byte) string {
+	if c.MaxLogBodyBytes > 0 && len(prefix) > c.MaxLogBodyBytes {
+		// a secret cut off mid-value no longer matches the Redactor, so drop it first
+		truncated := partialFieldPattern.ReplaceAll(prefix[:c.MaxLogBodyBytes], nil)
+		return string(c.redact(truncated)) + truncatedMarker
+	}
+	return string(c.redact(prefix))
+}
+
+// decompressBody replaces a gzip or deflate encoded body with its decoded content.
+// http.Transport only does this itself when it asked for gzip
+func decompressBody(resp *http.Response) error {
+	var newReader func(io.Reader) (io.ReadCloser, error)
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "gzip":
+		newReader = func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) }
+	case "deflate":
+		newReader = zlib.NewReader
+	default:
+		return nil
+	}
+
+	decoded, err := newReader(resp.Body)
+	if err == io.EOF {
+		decoded = http.NoBody // empty body
+	} else if err != nil {
+		resp.Body.Close()
+		return fmt.Errorf("decompress response: %w", err)
+	}
+	resp.Body = prefixedReadCloser{Reader: decoded, Closer: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
+
+// prefixedReadCloser replays an already read prefix before the rest of the original body
+type prefixedReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func (c *LoggingClient) redact(body []byte) []byte {
+	if c.Redactor == nil {
+		return body
 	}
+	// copy so a redactor that edits in place cannot corrupt the real body
+	return c.Redactor(bytes.Clone(body))
 }
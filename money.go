@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
+	"math"
+	"math/big"
 	"strconv"
 	"strings"
 )
@@ -11,17 +13,31 @@ import (
 // Cents represents monetary value in cents
 type Cents int64
 
-// ZeroPrefixed12DCents returns a string representation of the Cents value with leading zeros
+// MaxZeroPrefixed12DCents is the largest amount that fits the 12 digit amt field
+const MaxZeroPrefixed12DCents Cents = 999999999999
+
+// ZeroPrefixed12DCents returns a string representation of the Cents value with leading zeros.
+// Amounts outside 0 to MaxZeroPrefixed12DCents do not fit in 12 digits; use CheckedZeroPrefixed12DCents
 func (c Cents) ZeroPrefixed12DCents() string {
 	return fmt.Sprintf("%012d", c)
 }
 
+// CheckedZeroPrefixed12DCents is ZeroPrefixed12DCents, returning an error if c is negative
+// or greater than MaxZeroPrefixed12DCents
+func (c Cents) CheckedZeroPrefixed12DCents() (string, error) {
+	if c < 0 || c > MaxZeroPrefixed12DCents {
+		return "", fmt.Errorf("amount %d cents does not fit in 12 digits, expected 0 to %d", c, MaxZeroPrefixed12DCents)
+	}
+	return c.ZeroPrefixed12DCents(), nil
+}
+
 // Format: 12 digits with 5 decimal places (e.g., 000000002500.90000)
 func (c Cents) MarshalJSON() ([]byte, error) {
 	return []byte(fmt.Sprintf("\"%012d.%02d000\"", c/100, c%100)), nil
 }
 
-// UnmarshalJSON decodes "000000000012.34000" into 1234
+// UnmarshalJSON decodes "000000000012.34000" into 1234. Digits after the
+// second decimal place are ignored; fewer than 5 decimal places is an error
 func (c *Cents) UnmarshalJSON(data []byte) error {
 	var s string
 	if err := json.Unmarshal(data, &s); err != nil {
@@ -39,15 +55,60 @@ func (c *Cents) UnmarshalJSON(data []byte) error {
 	if err != nil {
 		return fmt.Errorf("strconv.ParseInt: %v", err)
 	}
+	if whole > math.MaxInt64/100 || whole < math.MinInt64/100 {
+		return fmt.Errorf("amount %s out of range", s)
+	}
 
 	// Parse second part
+	if len(split[1]) < 5 {
+		return fmt.Errorf("invalid format: expected 5 decimal places, got %q", split[1])
+	}
 	decimal, err := strconv.ParseInt(split[1][:5], 10, 64)
 	if err != nil {
 		return fmt.Errorf("strconv.ParseInt: %v", err)
 	}
+	if strings.TrimLeft(split[1], "0123456789") != "" {
+		return fmt.Errorf("invalid format: decimal places %q are not digits", split[1])
+	}
+
+	// Combine whole and decimal parts, e.g. "-0.50000" is -50
+	cents := whole*100 + decimal/1000
+	if strings.HasPrefix(split[0], "-") {
+		cents = whole*100 - decimal/1000
+	}
+	*c = Cents(cents)
+	return nil
+}
+
+// CentsFromFloat64 converts a float64 amount, e.g. 25.5, to Cents, rounding to the nearest cent.
+// It is for migrating float64 amounts such as the former PaymentTokenSubMerchant.Amount
+func CentsFromFloat64(amount float64) (Cents, error) {
+	cents := math.Round(amount * 100)
+	if math.IsNaN(cents) || cents > math.MaxInt64 || cents < math.MinInt64 {
+		return 0, fmt.Errorf("amount %v out of range", amount)
+	}
+	return Cents(cents), nil
+}
+
+// currencyExponents are the ISO 4217 minor units of the currencies in
+// docs/2c2p/reference-codes-currency.csv that differ from 2 decimal places
+var currencyExponents = map[string]int{
+	"JPY": 0,
+	"KRW": 0,
+	"VND": 0,
+}
 
-	// Combine whole and decimal parts
-	*c = Cents(whole*100 + decimal/1000)
+// checkCurrencyExponent returns an error if c has more decimal places than currencyCode allows,
+// e.g. 10050 cents is 100.50 and cannot be paid in JPY
+func (c Cents) checkCurrencyExponent(currencyCode string) error {
+	exponent, ok := currencyExponents[strings.ToUpper(currencyCode)]
+	if !ok {
+		return nil
+	}
+	unit := Cents(math.Pow10(2 - exponent))
+	if c%unit != 0 {
+		return fmt.Errorf("amount %s has more than %d decimal places for %s", c.ToDollars(), exponent, currencyCode)
+	}
 	return nil
 }
 
@@ -89,3 +150,39 @@ func (d *Dollars) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
 	d.cents = Cents(f * 100)
 	return nil
 }
+
+// Decimal holds a decimal number exactly as 2C2P sent it, for fields (e.g. D 12.5)
+// whose precision does not survive a round trip through float64
+type Decimal struct {
+	raw string
+}
+
+// String returns the decimal as received, e.g. "123456789012.12345"
+func (d Decimal) String() string {
+	return d.raw
+}
+
+// Float64 returns the decimal as a float64, which may lose precision
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.raw, 64)
+	return f
+}
+
+// Rat returns the exact value of the decimal, or nil if it is empty or malformed
+func (d Decimal) Rat() *big.Rat {
+	r, ok := new(big.Rat).SetString(d.raw)
+	if !ok {
+		return nil
+	}
+	return r
+}
+
+// UnmarshalJSON accepts both a JSON number and a JSON string
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	d.raw = n.String()
+	return nil
+}
@@ -0,0 +1,132 @@
+package api2c2p
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// PaymentNotificationHandler is an http.Handler for the backend notification 2C2P posts
+// to the merchant's backend return URL, e.g. mounted at "/payment-notify".
+// A notification is only checked by decrypting it with our private key: PKCS7 enveloped data
+// is not signed, and 2C2P does not document which fields its `hashValue` covers. Confirm the
+// payment with Inquire before fulfilling an order
+type PaymentNotificationHandler struct {
+	client *Client
+
+	// OnPayment receives the decrypted notification and its raw XML. Returning an error
+	// responds 500 so that 2C2P delivers the notification again
+	OnPayment func(response PaymentResponseBackEnd, decrypted []byte) error
+
+	// SeenStore, if set, acknowledges repeated deliveries of a notification without
+	// calling OnPayment again. Notifications are keyed by uniqueTransactionCode and respCode
+	SeenStore SeenStore
+}
+
+// SeenStore remembers which notifications have been handled
+type SeenStore interface {
+	Seen(key string) (bool, error)
+	Mark(key string) error
+}
+
+// NotificationHandler returns a handler that decrypts backend notifications before passing
+// them to onPayment. Malformed notifications are rejected with 400
+func (c *Client) NotificationHandler(onPayment func(response PaymentResponseBackEnd, decrypted []byte) error) *PaymentNotificationHandler {
+	return &PaymentNotificationHandler{
+		client:    c,
+		OnPayment: onPayment,
+	}
+}
+
+func (h *PaymentNotificationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error parsing form", http.StatusBadRequest)
+		return
+	}
+
+	response, decrypted, err := h.client.DecryptPaymentResponseBackend(r)
+	if err != nil {
+		h.client.logger.Error("decrypt payment notification", "error", err)
+		http.Error(w, "Error decrypting payment notification", http.StatusBadRequest)
+		return
+	}
+
+	key := response.UniqueTransactionCode + ":" + string(response.RespCode)
+	if h.SeenStore != nil {
+		seen, err := h.SeenStore.Seen(key)
+		if err != nil {
+			h.client.logger.Error("check payment notification seen", "invoiceNo", response.UniqueTransactionCode, "error", err)
+			http.Error(w, "Error handling payment notification", http.StatusInternalServerError)
+			return
+		}
+		if seen {
+			h.client.logger.Info("duplicate payment notification", "invoiceNo", response.UniqueTransactionCode, "respCode", response.RespCode)
+			WriteNotificationAck(w, response)
+			return
+		}
+	}
+
+	if err := h.OnPayment(response, decrypted); err != nil {
+		h.client.logger.Error("handle payment notification", "invoiceNo", response.UniqueTransactionCode, "error", err)
+		http.Error(w, "Error handling payment notification", http.StatusInternalServerError)
+		return
+	}
+	if h.SeenStore != nil {
+		if err := h.SeenStore.Mark(key); err != nil {
+			// OnPayment succeeded; a redelivery will be dispatched again
+			h.client.logger.Error("mark payment notification seen", "invoiceNo", response.UniqueTransactionCode, "error", err)
+		}
+	}
+	WriteNotificationAck(w, response)
+}
+
+// WriteNotificationAck acknowledges a backend notification so that 2C2P stops delivering it.
+// 2C2P only looks for HTTP 200 and redelivers on any other status or a timeout; no body,
+// echo of resp or signature is expected, so none is written
+func WriteNotificationAck(w http.ResponseWriter, resp PaymentResponseBackEnd) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// MemorySeenStore is an in-memory SeenStore that forgets keys after a TTL.
+// It is not shared between processes
+type MemorySeenStore struct {
+	ttl  time.Duration
+	now  func() time.Time
+	mu   sync.Mutex
+	seen map[string]time.Time // key to expiry
+}
+
+// NewMemorySeenStore creates a MemorySeenStore remembering keys for ttl
+func NewMemorySeenStore(ttl time.Duration) *MemorySeenStore {
+	return &MemorySeenStore{
+		ttl:  ttl,
+		now:  time.Now,
+		seen: map[string]time.Time{},
+	}
+}
+
+// Seen reports whether key was marked within the TTL
+func (s *MemorySeenStore) Seen(key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expiry, ok := s.seen[key]
+	return ok && s.now().Before(expiry), nil
+}
+
+// Mark remembers key for the TTL, and forgets expired keys
+func (s *MemorySeenStore) Mark(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for k, expiry := range s.seen {
+		if !now.Before(expiry) {
+			delete(s.seen, k)
+		}
+	}
+	s.seen[key] = now.Add(s.ttl)
+	return nil
+}
@@ -0,0 +1,198 @@
+package api2c2p
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fullsailor/pkcs7"
+)
+
+// newNotificationRequest encrypts xmlData for client and posts it as the `paymentResponse` form value
+func newNotificationRequest(t *testing.T, client *Client, xmlData string) *http.Request {
+	t.Helper()
+	encrypted, err := pkcs7.Encrypt([]byte(xmlData), []*x509.Certificate{client.PublicCert})
+	if err != nil {
+		t.Fatalf("Failed to encrypt notification: %v", err)
+	}
+	form := url.Values{"paymentResponse": {base64.StdEncoding.EncodeToString(encrypted)}}
+	r := httptest.NewRequest(http.MethodPost, "/payment-notify", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+func TestNotificationHandler(t *testing.T) {
+	client, err := NewClient(testConfig())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	fixture, err := os.ReadFile("testdata/payment-response-1.txt.xml")
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	testCases := []struct {
+		name        string
+		xmlData     string
+		callbackErr error
+		wantStatus  int
+		wantCalled  bool
+	}{
+		{
+			name:       "sandbox notification",
+			xmlData:    string(fixture),
+			wantStatus: http.StatusOK,
+			wantCalled: true,
+		},
+		{
+			name:        "callback error",
+			xmlData:     string(fixture),
+			callbackErr: errors.New("database unavailable"),
+			wantStatus:  http.StatusInternalServerError,
+			wantCalled:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var called bool
+			var got PaymentResponseBackEnd
+			handler := client.NotificationHandler(func(response PaymentResponseBackEnd, decrypted []byte) error {
+				called = true
+				got = response
+				if string(decrypted) != tc.xmlData {
+					t.Errorf("Expected decrypted XML %q, got %q", tc.xmlData, decrypted)
+				}
+				return tc.callbackErr
+			})
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, newNotificationRequest(t, client, tc.xmlData))
+
+			if w.Code != tc.wantStatus {
+				t.Errorf("Expected status %d, got %d", tc.wantStatus, w.Code)
+			}
+			if called != tc.wantCalled {
+				t.Fatalf("Expected callback called %v, got %v", tc.wantCalled, called)
+			}
+			if called && got.UniqueTransactionCode != "INV1738780109" {
+				t.Errorf("Expected uniqueTransactionCode INV1738780109, got %s", got.UniqueTransactionCode)
+			}
+			if called && !got.IsSuccess() {
+				t.Errorf("Expected successful payment, got respCode %s status %s", got.RespCode, got.Status)
+			}
+		})
+	}
+}
+
+func TestNotificationHandlerRejectsMalformed(t *testing.T) {
+	client, err := NewClient(testConfig())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	handler := client.NotificationHandler(func(PaymentResponseBackEnd, []byte) error {
+		t.Error("Expected callback not to be called")
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/payment-notify", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/payment-notify", strings.NewReader("paymentResponse=invalid"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestNotificationHandlerDeduplicates(t *testing.T) {
+	client, err := NewClient(testConfig())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	fixture, err := os.ReadFile("testdata/payment-response-1.txt.xml")
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	calls := 0
+	callbackErr := errors.New("database unavailable")
+	handler := client.NotificationHandler(func(PaymentResponseBackEnd, []byte) error {
+		calls++
+		return callbackErr
+	})
+	handler.SeenStore = NewMemorySeenStore(time.Hour)
+
+	// a failed delivery is not marked, so the retry is dispatched
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, newNotificationRequest(t, client, string(fixture)))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+
+	callbackErr = nil
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, newNotificationRequest(t, client, string(fixture)))
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("Expected callback to run 2 times, got %d", calls)
+	}
+
+	// a different respCode for the same invoice is a new notification
+	failed := strings.Replace(string(fixture), "<respCode>00</respCode>", "<respCode>99</respCode>", 1)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, newNotificationRequest(t, client, failed))
+	if calls != 3 {
+		t.Errorf("Expected callback to run 3 times, got %d", calls)
+	}
+}
+
+func TestWriteNotificationAck(t *testing.T) {
+	w := httptest.NewRecorder()
+	WriteNotificationAck(w, PaymentResponseBackEnd{UniqueTransactionCode: "INV123", RespCode: "00"})
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("Expected empty body, got %q", w.Body.String())
+	}
+}
+
+func TestMemorySeenStore(t *testing.T) {
+	now := time.Date(2025, 2, 6, 7, 0, 0, 0, time.UTC)
+	store := NewMemorySeenStore(time.Minute)
+	store.now = func() time.Time { return now }
+
+	if seen, _ := store.Seen("INV1:00"); seen {
+		t.Error("Expected INV1:00 not seen before Mark")
+	}
+	store.Mark("INV1:00")
+	if seen, _ := store.Seen("INV1:00"); !seen {
+		t.Error("Expected INV1:00 seen after Mark")
+	}
+
+	now = now.Add(time.Minute)
+	if seen, _ := store.Seen("INV1:00"); seen {
+		t.Error("Expected INV1:00 forgotten after TTL")
+	}
+	store.Mark("INV2:00")
+	if _, ok := store.seen["INV1:00"]; ok {
+		t.Error("Expected expired INV1:00 to be removed on Mark")
+	}
+}
@@ -31,6 +31,49 @@ func (c PaymentFlowResponseCode) Description() string {
 	}
 }
 
+// RequiresAction reports whether the client must act to continue the payment flow,
+// e.g. load a redirect URL, open an app or display a QR code
+func (c PaymentFlowResponseCode) RequiresAction() bool {
+	switch c {
+	case "1000":
+		return true
+	case "1001":
+		return true
+	case "1002":
+		return true
+	case "1003":
+		return true
+	case "1004":
+		return true
+	case "1005":
+		return true
+	default:
+		return false
+	}
+}
+
+// IsTerminalSuccess reports whether the payment flow completed; the payment result
+// still comes from the backend notification or a payment inquiry
+func (c PaymentFlowResponseCode) IsTerminalSuccess() bool {
+	switch c {
+	case "2000":
+		return true
+	default:
+		return false
+	}
+}
+
+// IsTerminalFailure reports whether the payment flow failed or was rejected. Codes that are
+// not listed, e.g. "0000", are neither a terminal success nor a terminal failure
+func (c PaymentFlowResponseCode) IsTerminalFailure() bool {
+	switch c {
+	case "Other":
+		return true
+	default:
+		return false
+	}
+}
+
 // Known response codes
 const (
 	Flow1000LoadRedirectUrlWithIframeWebview                              PaymentFlowResponseCode = "1000"  // Load redirect URL with IFrame / Webview.
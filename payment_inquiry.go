@@ -17,24 +17,17 @@ Example Usage:
 	    "https://sandbox-pgw.2c2p.com", // or https://pgw.2c2p.com for production
 	)
 
-	tokenRequest := &api2c2p.PaymentInquiryByTokenRequest{
-	    MerchantID:   "your_merchant_id",
+	tokenResponse, err := client.Inquire(ctx, api2c2p.InquiryQuery{
 	    PaymentToken: "payment_token",
 	    Locale:       "en", // Optional
-	}
-
-	invoiceRequest := &api2c2p.PaymentInquiryByInvoiceRequest{
-	    MerchantID: "your_merchant_id",
-	    InvoiceNo:  "your_invoice_number",
-	    Locale:     "en", // Optional
-	}
-
-	tokenResponse, err := client.PaymentInquiryByToken(ctx, tokenRequest)
+	})
 	if err != nil {
 	    fmt.Printf("Error: %v\n", err)
 	}
 
-	invoiceResponse, err := client.PaymentInquiryByInvoice(ctx, invoiceRequest)
+	invoiceResponse, err := client.Inquire(ctx, api2c2p.InquiryQuery{
+	    InvoiceNo: "your_invoice_number",
+	})
 	if err != nil {
 	    fmt.Printf("Error: %v\n", err)
 	}
@@ -49,7 +42,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
+	"sync"
 )
 
 // PaymentInquiryByTokenRequest represents the request payload for payment inquiry by payment token
@@ -125,9 +120,10 @@ type PaymentInquiryResponse struct {
 	ApprovalCode string `json:"approvalCode"`
 
 	// AccountNo is the account number (N 19, M)
-	AccountNo string `json:"accountNo"`
+	AccountNo MaskedPan `json:"accountNo"`
 
 	// CustomerToken is the customer token (AN 20, O)
+	// It is set when the card was saved, e.g. by TokenizeCard, and can be charged with ChargeStoredCard
 	CustomerToken string `json:"customerToken"`
 
 	// CustomerTokenExpiry is the customer token expiry (AN 8, O)
@@ -197,7 +193,7 @@ type PaymentInquiryResponse struct {
 	TransactionStatus string `json:"transactionStatus"`
 
 	// MaskedPan is the masked PAN (C 19, C)
-	MaskedPan string `json:"maskedPan"`
+	MaskedPan MaskedPan `json:"maskedPan"`
 
 	// PaymentChannel is the payment channel (C 20, M)
 	PaymentChannel string `json:"paymentChannel"`
@@ -223,6 +219,9 @@ type PaymentInquiryResponse struct {
 	// LoyaltyPoints is the loyalty points (D 12.5, O)
 	LoyaltyPoints float64 `json:"loyaltyPoints,omitempty"`
 
+	// LoyaltyPointsDecimal is LoyaltyPoints exactly as received, without float64 rounding
+	LoyaltyPointsDecimal Decimal `json:"-"`
+
 	// PaymentScheme is the payment scheme (C 30, C)
 	PaymentScheme string `json:"paymentScheme"`
 
@@ -230,14 +229,55 @@ type PaymentInquiryResponse struct {
 	IdempotencyID string `json:"idempotencyID"`
 }
 
-// IsSuccess returns true if the response code indicates success
+// UnmarshalJSON decodes the response, populating the Decimal fields alongside their float64 counterparts
+func (r *PaymentInquiryResponse) UnmarshalJSON(data []byte) error {
+	type plain PaymentInquiryResponse
+	if err := json.Unmarshal(data, (*plain)(r)); err != nil {
+		return err
+	}
+	var precise struct {
+		LoyaltyPoints *Decimal `json:"loyaltyPoints"`
+	}
+	if err := json.Unmarshal(data, &precise); err != nil {
+		return err
+	}
+	if precise.LoyaltyPoints != nil {
+		r.LoyaltyPointsDecimal = *precise.LoyaltyPoints
+	}
+	return nil
+}
+
+// IsSuccess reports whether respCode is a successful payment response code, e.g. "0000".
+// A declined payment, or the "Other" flow code of a failed inquiry, is not a success
 func (r *PaymentInquiryResponse) IsSuccess() bool {
-	switch r.RespCode {
-	case FlowOtherTransactionFailedOrRejectedPerformPaymentInquiryToGetPayment:
-		return false
-	default:
-		return true
+	return PaymentResponseCode(r.RespCode).IsSuccess()
+}
+
+// AmountCents returns Amount rounded to cents. The documented response has no refunded
+// amount, so the refunds on an invoice cannot be totalled from an inquiry
+func (r *PaymentInquiryResponse) AmountCents() Cents {
+	return Cents(math.Round(r.Amount * 100))
+}
+
+// HasFX reports whether the payment was converted to another currency, e.g. by dynamic currency conversion
+func (r *PaymentInquiryResponse) HasFX() bool {
+	return r.FxCurrencyCode != "" && r.FxAmount > 0
+}
+
+// FXConvertedCents returns FxAmount, in FxCurrencyCode, rounded to cents; zero when HasFX is false
+func (r *PaymentInquiryResponse) FXConvertedCents() Cents {
+	if !r.HasFX() {
+		return 0
 	}
+	return Cents(math.Round(r.FxAmount * 100))
+}
+
+// CardScheme returns the card brand from paymentScheme, or channelCode if that is empty
+func (r *PaymentInquiryResponse) CardScheme() CardScheme {
+	if r.PaymentScheme != "" {
+		return ParseCardScheme(r.PaymentScheme)
+	}
+	return ParseCardScheme(r.ChannelCode)
 }
 
 func (c *Client) newPaymentInquiryRequest(ctx context.Context, merchantID string, payload interface{}) (*http.Request, error) {
@@ -272,16 +312,51 @@ func (c *Client) newPaymentInquiryRequest(ctx context.Context, merchantID string
 	return req, nil
 }
 
-// PaymentInquiryByToken checks the status of a payment using a payment token
-func (c *Client) PaymentInquiryByToken(ctx context.Context, req *PaymentInquiryByTokenRequest) (*PaymentInquiryResponse, error) {
-	if req.PaymentToken == "" {
-		return nil, fmt.Errorf("payment token is required")
+// InquiryQuery identifies the payment to inquire: set exactly one of InvoiceNo or PaymentToken
+type InquiryQuery struct {
+	InvoiceNo    string
+	PaymentToken string
+
+	// Locale is the language code for the response (Optional)
+	Locale string
+
+	// MerchantID is the 2C2P merchant ID
+	// Default: Client.MerchantID
+	MerchantID string
+}
+
+// Inquire checks the status of a payment by invoice number or payment token. When respCode
+// is not a success, e.g. a declined payment, the response is returned with an *APIError
+func (c *Client) Inquire(ctx context.Context, query InquiryQuery) (*PaymentInquiryResponse, error) {
+	if query.InvoiceNo != "" && query.PaymentToken != "" {
+		return nil, fmt.Errorf("only one of invoice number or payment token may be set")
+	}
+	if query.MerchantID == "" {
+		query.MerchantID = c.MerchantID
+	}
+	if err := c.validateLocale(query.Locale); err != nil {
+		return nil, err
 	}
-	if req.MerchantID == "" {
-		req.MerchantID = c.MerchantID
+
+	var payload interface{}
+	switch {
+	case query.InvoiceNo != "":
+		payload = &PaymentInquiryByInvoiceRequest{
+			InvoiceNo:  query.InvoiceNo,
+			Locale:     query.Locale,
+			MerchantID: query.MerchantID,
+		}
+	case query.PaymentToken != "":
+		payload = &PaymentInquiryByTokenRequest{
+			PaymentToken: query.PaymentToken,
+			Locale:       query.Locale,
+			MerchantID:   query.MerchantID,
+		}
+	default:
+		return nil, fmt.Errorf("invoice number or payment token is required")
 	}
 
-	httpReq, err := c.newPaymentInquiryRequest(ctx, req.MerchantID, req)
+	httpReq, err := c.newPaymentInquiryRequest(ctx, query.MerchantID, payload)
 	if err != nil {
 		return nil, err
 	}
@@ -293,87 +368,133 @@ func (c *Client) PaymentInquiryByToken(ctx context.Context, req *PaymentInquiryB
 	}
 	defer resp.Body.Close()
 
-	// Try to decode response
-	var jwtResponse struct {
-		Payload string `json:"payload"`
+	// Decode response; errors may come back unsigned
+	var inquiryResp PaymentInquiryResponse
+	direct, err := c.decodePayloadResponse(resp.Body, &inquiryResp)
+	if err != nil {
+		return nil, err
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&jwtResponse); err != nil || jwtResponse.Payload == "" {
-		// Try decoding as direct response
-		var response struct {
-			RespCode PaymentFlowResponseCode `json:"respCode"`
-			RespDesc string                  `json:"respDesc"`
+	if direct != nil {
+		inquiryResp = PaymentInquiryResponse{
+			RespCode: PaymentFlowResponseCode(direct.RespCode),
+			RespDesc: direct.RespDesc,
 		}
-		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-			return nil, fmt.Errorf("decode response: %w", err)
-		}
-		return &PaymentInquiryResponse{
-			RespCode: response.RespCode,
-			RespDesc: response.RespDesc,
-		}, nil
-	}
-
-	// If we got a JWT response, decode it
-	var inquiryResp PaymentInquiryResponse
-	if err := c.decodeJWTTokenForJSON(jwtResponse.Payload, &inquiryResp); err != nil {
-		return nil, fmt.Errorf("decode jwt token: %w", err)
 	}
 
-	// Check response code
+	// Check response code, as for PaymentToken and Refund
 	if inquiryResp.IsSuccess() {
 		return &inquiryResp, nil
 	}
-	return &inquiryResp, fmt.Errorf("payment inquiry failed: %s (%s)", inquiryResp.RespCode, inquiryResp.RespDesc)
+	apiErr := &APIError{
+		Endpoint: "paymentInquiry",
+		RespCode: PaymentResponseCode(inquiryResp.RespCode),
+		RespDesc: inquiryResp.RespDesc,
+	}
+	if inquiryResp.RespCode == FlowOtherTransactionFailedOrRejectedPerformPaymentInquiryToGetPayment {
+		apiErr.FlowCode = inquiryResp.RespCode
+	}
+	return &inquiryResp, apiErr
+}
+
+// PaymentInquiryByToken checks the status of a payment using a payment token
+//
+// Deprecated: use Inquire with InquiryQuery.PaymentToken
+func (c *Client) PaymentInquiryByToken(ctx context.Context, req *PaymentInquiryByTokenRequest) (*PaymentInquiryResponse, error) {
+	if req.PaymentToken == "" {
+		return nil, fmt.Errorf("payment token is required")
+	}
+	return c.Inquire(ctx, InquiryQuery{
+		PaymentToken: req.PaymentToken,
+		Locale:       req.Locale,
+		MerchantID:   req.MerchantID,
+	})
 }
 
 // PaymentInquiryByInvoice checks the status of a payment using an invoice number
+//
+// Deprecated: use Inquire with InquiryQuery.InvoiceNo
 func (c *Client) PaymentInquiryByInvoice(ctx context.Context, req *PaymentInquiryByInvoiceRequest) (*PaymentInquiryResponse, error) {
 	if req.InvoiceNo == "" {
 		return nil, fmt.Errorf("invoice number is required")
 	}
-	if req.MerchantID == "" {
-		req.MerchantID = c.MerchantID
-	}
+	return c.Inquire(ctx, InquiryQuery{
+		InvoiceNo:  req.InvoiceNo,
+		Locale:     req.Locale,
+		MerchantID: req.MerchantID,
+	})
+}
 
-	httpReq, err := c.newPaymentInquiryRequest(ctx, req.MerchantID, req)
-	if err != nil {
-		return nil, err
-	}
+// PaymentInquiryResult pairs an invoice number with the outcome of its payment inquiry
+type PaymentInquiryResult struct {
+	InvoiceNo string
 
-	// Make request
-	resp, err := c.do(httpReq)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	// Response may be set alongside Err, e.g. when Err is an *APIError
+	Response *PaymentInquiryResponse
+	Err      error
+}
 
-	// Try to decode response
-	var jwtResponse struct {
-		Payload string `json:"payload"`
+// PaymentInquiryBatch inquires invoiceNos with at most concurrency requests in flight,
+// e.g. for reconciliation jobs. Results are in the same order as invoiceNos and
+// per-invoice failures are reported in PaymentInquiryResult.Err without stopping the batch.
+// The returned error is only set when ctx is done before all invoices are inquired.
+func (c *Client) PaymentInquiryBatch(ctx context.Context, invoiceNos []string, concurrency int) ([]PaymentInquiryResult, error) {
+	if concurrency < 1 {
+		concurrency = 1
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&jwtResponse); err != nil || jwtResponse.Payload == "" {
-		// Try decoding as direct response
-		var response struct {
-			RespCode PaymentFlowResponseCode `json:"respCode"`
-			RespDesc string                  `json:"respDesc"`
+	results := make([]PaymentInquiryResult, len(invoiceNos))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency && w < len(invoiceNos); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				resp, err := c.Inquire(ctx, InquiryQuery{InvoiceNo: invoiceNos[i]})
+				results[i] = PaymentInquiryResult{InvoiceNo: invoiceNos[i], Response: resp, Err: err}
+			}
+		}()
+	}
+
+	var err error
+	for i := range invoiceNos {
+		if err = ctx.Err(); err == nil {
+			select {
+			case indexes <- i:
+				continue
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
 		}
-		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-			return nil, fmt.Errorf("decode response: %w", err)
+		// not dispatched; report the remaining invoices as cancelled
+		for j := i; j < len(invoiceNos); j++ {
+			results[j] = PaymentInquiryResult{InvoiceNo: invoiceNos[j], Err: err}
 		}
-		return &PaymentInquiryResponse{
-			RespCode: response.RespCode,
-			RespDesc: response.RespDesc,
-		}, nil
+		break
 	}
+	close(indexes)
+	wg.Wait()
+	return results, err
+}
 
-	// If we got a JWT response, decode it
-	var inquiryResp PaymentInquiryResponse
-	if err := c.decodeJWTTokenForJSON(jwtResponse.Payload, &inquiryResp); err != nil {
-		return nil, fmt.Errorf("decode jwt token: %w", err)
+// pingInvoiceNo is an invoice number Ping inquires about, expecting it not to exist
+const pingInvoiceNo = "2C2P-PING"
+
+// Ping checks connectivity and credentials without creating a transaction, by inquiring
+// about an invoice that does not exist. A nil error means 2C2P accepted our merchant ID
+// and signed request, and answered "not found" (or, improbably, found the invoice).
+// Any other response code, e.g. "Invalid merchant ID", is returned as an *APIError;
+// a response we cannot verify or decode is returned as is
+func (c *Client) Ping(ctx context.Context) error {
+	resp, err := c.Inquire(ctx, InquiryQuery{InvoiceNo: pingInvoiceNo})
+	if resp == nil {
+		return fmt.Errorf("ping: %w", err)
 	}
-
-	// Check response code
-	if inquiryResp.IsSuccess() {
-		return &inquiryResp, nil
+	switch PaymentResponseCode(resp.RespCode) {
+	case Code2002TransactionNotFound, Code4071InquiryRecordNotExist, Code4140TransactionDoesNotExist:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("ping: %w", err)
 	}
-	return &inquiryResp, fmt.Errorf("payment inquiry failed: %s (%s)", inquiryResp.RespCode, inquiryResp.RespDesc)
+	return nil
 }
@@ -1,16 +1,24 @@
 package api2c2p
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
 	"net/http"
-	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/choonkeat/2c2p/testutil"
+	"github.com/golang-jwt/jwt/v5"
 )
 
 func TestPaymentInquiry(t *testing.T) {
-	var ts *httptest.Server
+	var client *Client
 
 	// Example request data from documentation
 	request := &PaymentInquiryByInvoiceRequest{
@@ -65,12 +73,12 @@ func TestPaymentInquiry(t *testing.T) {
 		PaidAgent:                     "OCBC",
 		PaidChannel:                   "VI",
 		PaidDateTime:                  "311220235959",
-		RespCode:                      Flow1000LoadRedirectUrlWithIframeWebview,
-		RespDesc:                      "Transaction is successful.",
+		RespCode:                      PaymentFlowResponseCode(Code0000Successful),
+		RespDesc:                      "Success",
 	}
 
 	// Create test server
-	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	client = NewTestClient(t, func(w http.ResponseWriter, r *http.Request) {
 		// Verify request method
 		if r.Method != http.MethodPost {
 			t.Errorf("Expected POST request, got %s", r.Method)
@@ -98,19 +106,7 @@ func TestPaymentInquiry(t *testing.T) {
 		}
 
 		// Create JWT token from response data
-		mockClient, err := NewClient(Config{
-			SecretKey:                "your_secret_key",
-			MerchantID:               "JT01",
-			PaymentGatewayURL:        ts.URL,
-			FrontendURL:              "https://frontend.example.com",
-			CombinedPEM:              "testdata/combined_private_public.pem",
-			ServerJWTPublicKeyFile:   "testdata/server.jwt.public_cert.pem",
-			ServerPKCS7PublicKeyFile: "testdata/server.pkcs7.public_cert.pem",
-		})
-		if err != nil {
-			t.Fatalf("Failed to create client: %v", err)
-		}
-		token, err := mockClient.generateJWTTokenForJSON(responseData)
+		token, err := client.generateJWTTokenForJSON(responseData)
 		if err != nil {
 			t.Errorf("Error generating JWT token: %v", err)
 			return
@@ -121,22 +117,7 @@ func TestPaymentInquiry(t *testing.T) {
 		json.NewEncoder(w).Encode(map[string]string{
 			"payload": token,
 		})
-	}))
-	defer ts.Close()
-
-	// Create client with test server URL
-	client, err := NewClient(Config{
-		SecretKey:                "your_secret_key",
-		MerchantID:               "JT01",
-		PaymentGatewayURL:        ts.URL,
-		FrontendURL:              "https://frontend.example.com",
-		CombinedPEM:              "testdata/combined_private_public.pem",
-		ServerJWTPublicKeyFile:   "testdata/server.jwt.public_cert.pem",
-		ServerPKCS7PublicKeyFile: "testdata/server.pkcs7.public_cert.pem",
 	})
-	if err != nil {
-		t.Fatalf("Failed to create client: %v", err)
-	}
 
 	// Make request
 	response, err := client.PaymentInquiryByInvoice(ctx, request)
@@ -163,15 +144,7 @@ func TestPaymentInquiry(t *testing.T) {
 }
 
 func TestNewPaymentInquiryRequest(t *testing.T) {
-	client, err := NewClient(Config{
-		SecretKey:                "test_secret",
-		MerchantID:               "JT01",
-		PaymentGatewayURL:        "https://pgw.example.com",
-		FrontendURL:              "https://frontend.example.com",
-		CombinedPEM:              "testdata/combined_private_public.pem",
-		ServerJWTPublicKeyFile:   "testdata/server.jwt.public_cert.pem",
-		ServerPKCS7PublicKeyFile: "testdata/server.pkcs7.public_cert.pem",
-	})
+	client, err := NewClient(testConfig())
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
 	}
@@ -207,7 +180,7 @@ func TestNewPaymentInquiryRequest(t *testing.T) {
 }
 
 func TestPaymentInquiryByToken(t *testing.T) {
-	var ts *httptest.Server
+	var client *Client
 
 	// Example request data
 	request := &PaymentInquiryByTokenRequest{
@@ -262,12 +235,12 @@ func TestPaymentInquiryByToken(t *testing.T) {
 		PaidAgent:                     "OCBC",
 		PaidChannel:                   "VI",
 		PaidDateTime:                  "311220235959",
-		RespCode:                      Flow10051DisplayGeneratedQrAndWaitForCustomerToScan,
-		RespDesc:                      "Transaction is successful.",
+		RespCode:                      PaymentFlowResponseCode(Code0000Successful),
+		RespDesc:                      "Success",
 	}
 
 	// Create test server
-	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	client = NewTestClient(t, func(w http.ResponseWriter, r *http.Request) {
 		// Verify request method
 		if r.Method != http.MethodPost {
 			t.Errorf("Expected POST request, got %s", r.Method)
@@ -295,19 +268,7 @@ func TestPaymentInquiryByToken(t *testing.T) {
 		}
 
 		// Create JWT token from response data
-		mockClient, err := NewClient(Config{
-			SecretKey:                "your_secret_key",
-			MerchantID:               "JT01",
-			PaymentGatewayURL:        ts.URL,
-			FrontendURL:              "https://frontend.example.com",
-			CombinedPEM:              "testdata/combined_private_public.pem",
-			ServerJWTPublicKeyFile:   "testdata/server.jwt.public_cert.pem",
-			ServerPKCS7PublicKeyFile: "testdata/server.pkcs7.public_cert.pem",
-		})
-		if err != nil {
-			t.Fatalf("Failed to create client: %v", err)
-		}
-		token, err := mockClient.generateJWTTokenForJSON(responseData)
+		token, err := client.generateJWTTokenForJSON(responseData)
 		if err != nil {
 			t.Errorf("Error generating JWT token: %v", err)
 			return
@@ -318,22 +279,7 @@ func TestPaymentInquiryByToken(t *testing.T) {
 		json.NewEncoder(w).Encode(map[string]string{
 			"payload": token,
 		})
-	}))
-	defer ts.Close()
-
-	// Create client with test server URL
-	client, err := NewClient(Config{
-		SecretKey:                "your_secret_key",
-		MerchantID:               "JT01",
-		PaymentGatewayURL:        ts.URL,
-		FrontendURL:              "https://frontend.example.com",
-		CombinedPEM:              "testdata/combined_private_public.pem",
-		ServerJWTPublicKeyFile:   "testdata/server.jwt.public_cert.pem",
-		ServerPKCS7PublicKeyFile: "testdata/server.pkcs7.public_cert.pem",
 	})
-	if err != nil {
-		t.Fatalf("Failed to create client: %v", err)
-	}
 
 	// Make request
 	response, err := client.PaymentInquiryByToken(ctx, request)
@@ -360,15 +306,7 @@ func TestPaymentInquiryByToken(t *testing.T) {
 }
 
 func TestNewPaymentInquiryByTokenRequest(t *testing.T) {
-	client, err := NewClient(Config{
-		SecretKey:                "test_secret",
-		MerchantID:               "JT01",
-		PaymentGatewayURL:        "https://pgw.example.com",
-		FrontendURL:              "https://frontend.example.com",
-		CombinedPEM:              "testdata/combined_private_public.pem",
-		ServerJWTPublicKeyFile:   "testdata/server.jwt.public_cert.pem",
-		ServerPKCS7PublicKeyFile: "testdata/server.pkcs7.public_cert.pem",
-	})
+	client, err := NewClient(testConfig())
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
 	}
@@ -402,3 +340,480 @@ func TestNewPaymentInquiryByTokenRequest(t *testing.T) {
 		},
 	})
 }
+
+func TestPaymentInquiryResponseLoyaltyPointsPrecision(t *testing.T) {
+	client, err := NewClient(testConfig())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	// 17 significant digits, more than a float64 can hold
+	const points = "123456789012.12345"
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"invoiceNo":     "INV123",
+		"loyaltyPoints": json.Number(points),
+	}).SignedString([]byte(client.SecretKey))
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+
+	var response PaymentInquiryResponse
+	if err := client.decodeJWTTokenForJSON(token, &response); err != nil {
+		t.Fatalf("Failed to decode token: %v", err)
+	}
+
+	if got := response.LoyaltyPointsDecimal.String(); got != points {
+		t.Errorf("Expected LoyaltyPointsDecimal %s, got %s", points, got)
+	}
+	want, _ := new(big.Rat).SetString(points)
+	if got := response.LoyaltyPointsDecimal.Rat(); got == nil || got.Cmp(want) != 0 {
+		t.Errorf("Expected LoyaltyPointsDecimal.Rat() %s, got %v", want.FloatString(5), got)
+	}
+	if response.LoyaltyPoints != response.LoyaltyPointsDecimal.Float64() {
+		t.Errorf("Expected LoyaltyPoints %v to match Float64() %v", response.LoyaltyPoints, response.LoyaltyPointsDecimal.Float64())
+	}
+	if response.InvoiceNo != "INV123" {
+		t.Errorf("Expected InvoiceNo INV123, got %s", response.InvoiceNo)
+	}
+}
+
+func TestPaymentInquiryBatch(t *testing.T) {
+	const concurrency = 5
+	var client *Client
+	var inFlight, maxInFlight int32
+	client = NewTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+
+		var reqBody struct {
+			Payload string `json:"payload"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			t.Errorf("Error decoding request body: %v", err)
+			return
+		}
+		var req PaymentInquiryByInvoiceRequest
+		if err := client.decodeJWTTokenForJSON(reqBody.Payload, &req); err != nil {
+			t.Errorf("Error decoding request payload: %v", err)
+			return
+		}
+
+		// every 10th invoice is rejected
+		respCode := PaymentFlowResponseCode(Code0000Successful)
+		if strings.HasSuffix(req.InvoiceNo, "0") {
+			respCode = FlowOtherTransactionFailedOrRejectedPerformPaymentInquiryToGetPayment
+		}
+		responseData, err := json.Marshal(PaymentInquiryResponse{InvoiceNo: req.InvoiceNo, RespCode: respCode})
+		if err != nil {
+			t.Errorf("Error marshaling response: %v", err)
+			return
+		}
+		token, err := client.generateJWTTokenForJSON(responseData)
+		if err != nil {
+			t.Errorf("Error generating JWT token: %v", err)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"payload": token})
+	})
+
+	var invoiceNos []string
+	for i := 1; i <= 50; i++ {
+		invoiceNos = append(invoiceNos, fmt.Sprintf("INV%03d", i))
+	}
+	results, err := client.PaymentInquiryBatch(ctx, invoiceNos, concurrency)
+	if err != nil {
+		t.Fatalf("PaymentInquiryBatch failed: %v", err)
+	}
+	if len(results) != len(invoiceNos) {
+		t.Fatalf("Expected %d results, got %d", len(invoiceNos), len(results))
+	}
+	for i, result := range results {
+		if result.InvoiceNo != invoiceNos[i] {
+			t.Errorf("Expected result %d for invoice %s, got %s", i, invoiceNos[i], result.InvoiceNo)
+		}
+		if result.Response == nil || result.Response.InvoiceNo != invoiceNos[i] {
+			t.Errorf("Expected response for invoice %s, got %+v", invoiceNos[i], result.Response)
+		}
+		wantErr := strings.HasSuffix(invoiceNos[i], "0")
+		if gotErr := result.Err != nil; gotErr != wantErr {
+			t.Errorf("Expected error %v for invoice %s, got %v", wantErr, invoiceNos[i], result.Err)
+		}
+	}
+	if got := atomic.LoadInt32(&maxInFlight); got > concurrency {
+		t.Errorf("Expected at most %d concurrent requests, got %d", concurrency, got)
+	}
+
+	// a cancelled context stops the batch
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	results, err = client.PaymentInquiryBatch(cancelled, invoiceNos, concurrency)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	for _, result := range results {
+		if result.Err == nil {
+			t.Errorf("Expected error for invoice %s with cancelled context", result.InvoiceNo)
+		}
+	}
+}
+
+func TestInquire(t *testing.T) {
+	var client *Client
+	var gotPayload map[string]any
+	client = NewTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var reqBody struct {
+			Payload string `json:"payload"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			t.Errorf("Error decoding request body: %v", err)
+			return
+		}
+		gotPayload = nil
+		if err := client.decodeJWTTokenForJSON(reqBody.Payload, &gotPayload); err != nil {
+			t.Errorf("Error decoding request payload: %v", err)
+			return
+		}
+		token, err := client.generateJWTTokenForJSON([]byte(`{"respCode":"0000","respDesc":"Success"}`))
+		if err != nil {
+			t.Errorf("Error generating JWT token: %v", err)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"payload": token})
+	})
+
+	testCases := []struct {
+		name        string
+		query       InquiryQuery
+		wantPayload map[string]any
+		wantErr     string
+	}{
+		{
+			name:  "by invoice",
+			query: InquiryQuery{InvoiceNo: "INV123", Locale: "en"},
+			wantPayload: map[string]any{
+				"merchantID": "JT01",
+				"invoiceNo":  "INV123",
+				"locale":     "en",
+			},
+		},
+		{
+			name:  "by payment token",
+			query: InquiryQuery{PaymentToken: "token123", MerchantID: "JT02"},
+			wantPayload: map[string]any{
+				"merchantID":   "JT02",
+				"paymentToken": "token123",
+			},
+		},
+		{
+			name:    "both set",
+			query:   InquiryQuery{InvoiceNo: "INV123", PaymentToken: "token123"},
+			wantErr: "only one of invoice number or payment token may be set",
+		},
+		{
+			name:    "neither set",
+			query:   InquiryQuery{Locale: "en"},
+			wantErr: "invoice number or payment token is required",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gotPayload = nil
+			resp, err := client.Inquire(ctx, tc.query)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Errorf("Expected error %q, got %v", tc.wantErr, err)
+				}
+				if gotPayload != nil {
+					t.Errorf("Expected no request, got %v", gotPayload)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Inquire failed: %v", err)
+			}
+			if PaymentResponseCode(resp.RespCode) != Code0000Successful {
+				t.Errorf("Expected respCode 0000, got %s", resp.RespCode)
+			}
+			if !reflect.DeepEqual(gotPayload, tc.wantPayload) {
+				t.Errorf("Expected payload %v, got %v", tc.wantPayload, gotPayload)
+			}
+		})
+	}
+}
+
+func TestPing(t *testing.T) {
+	var client *Client
+	var respBody string
+	client = NewTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		token, err := client.generateJWTTokenForJSON([]byte(respBody))
+		if err != nil {
+			t.Errorf("Error generating JWT token: %v", err)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"payload": token})
+	})
+
+	testCases := []struct {
+		name     string
+		respBody string
+		wantCode PaymentResponseCode // empty for a nil error
+	}{
+		{
+			name:     "transaction not found",
+			respBody: `{"respCode":"2002","respDesc":"Transaction not found"}`,
+		},
+		{
+			name:     "inquiry record not exist",
+			respBody: `{"respCode":"4071","respDesc":"Inquiry Record Not Exist"}`,
+		},
+		{
+			name:     "invalid merchant",
+			respBody: `{"respCode":"4003","respDesc":"Invalid merchant ID"}`,
+			wantCode: Code4003InvalidMerchantId,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			respBody = tc.respBody
+			err := client.Ping(ctx)
+			if tc.wantCode == "" {
+				if err != nil {
+					t.Errorf("Expected credentials OK, got %v", err)
+				}
+				return
+			}
+			if !IsResponseCode(err, tc.wantCode) {
+				t.Errorf("Expected error with respCode %s, got %v", tc.wantCode, err)
+			}
+		})
+	}
+
+	// a response signed with another secret fails verification
+	other, err := NewClientWithKeys(Config{
+		SecretKey:         "wrong_secret",
+		MerchantID:        "JT01",
+		PaymentGatewayURL: client.PaymentGatewayURL,
+	}, client.PrivateKey, client.PublicCert, client.ServerJWTPublicCert, client.ServerPKCS7PublicCert)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	respBody = `{"respCode":"2002","respDesc":"Transaction not found"}`
+	if err := other.Ping(ctx); err == nil {
+		t.Error("Expected error for a response signed with another secret")
+	}
+}
+
+func TestInquireDirectErrorResponse(t *testing.T) {
+	client := NewTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		// unsigned, without payload
+		w.Write([]byte(`{"respCode":"4003","respDesc":"Invalid merchant ID"}`))
+	})
+
+	resp, err := client.Inquire(ctx, InquiryQuery{InvoiceNo: "INV123"})
+	if !IsResponseCode(err, Code4003InvalidMerchantId) {
+		t.Fatalf("Expected APIError with respCode 4003, got %v", err)
+	}
+	if resp == nil || resp.RespDesc != "Invalid merchant ID" {
+		t.Errorf("Expected response with respDesc, got %+v", resp)
+	}
+
+	// Ping classifies it too
+	if err := client.Ping(ctx); !IsResponseCode(err, Code4003InvalidMerchantId) {
+		t.Errorf("Expected Ping error with respCode 4003, got %v", err)
+	}
+}
+
+func TestInquireDeclinedPayment(t *testing.T) {
+	var client *Client
+	var respBody string
+	client = NewTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		token, err := client.generateJWTTokenForJSON([]byte(respBody))
+		if err != nil {
+			t.Errorf("Error generating JWT token: %v", err)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"payload": token})
+	})
+
+	testCases := []struct {
+		respBody string
+		wantCode PaymentResponseCode // empty for a nil error
+	}{
+		{respBody: `{"respCode":"0000","respDesc":"Success"}`},
+		{respBody: `{"respCode":"4005","respDesc":"Do not honor"}`, wantCode: Code4005DoNotHonor},
+		{respBody: `{"respCode":"4051","respDesc":"Insufficient funds"}`, wantCode: Code4051InsufficientFunds},
+	}
+
+	for _, tc := range testCases {
+		respBody = tc.respBody
+		resp, err := client.Inquire(ctx, InquiryQuery{InvoiceNo: "INV123"})
+		if tc.wantCode == "" {
+			if err != nil {
+				t.Errorf("Expected no error for %s, got %v", tc.respBody, err)
+			}
+			continue
+		}
+		// a signed decline is returned as an *APIError, like PaymentToken and Refund
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.RespCode != tc.wantCode || !apiErr.RespCode.IsDecline() {
+			t.Errorf("Expected APIError with decline respCode %s, got %v", tc.wantCode, err)
+		}
+		if resp == nil || PaymentResponseCode(resp.RespCode) != tc.wantCode {
+			t.Errorf("Expected response with respCode %s, got %+v", tc.wantCode, resp)
+		}
+	}
+}
+
+func TestPaymentInquiryResponseAmountCents(t *testing.T) {
+	testCases := []struct {
+		json       string
+		wantAmount Cents
+	}{
+		{json: `{"amount":100.10}`, wantAmount: 10010},
+		{json: `{"amount":0.29}`, wantAmount: 29},
+		{json: `{}`, wantAmount: 0},
+	}
+
+	for _, tc := range testCases {
+		var resp PaymentInquiryResponse
+		if err := json.Unmarshal([]byte(tc.json), &resp); err != nil {
+			t.Fatalf("Failed to unmarshal: %v", err)
+		}
+		if got := resp.AmountCents(); got != tc.wantAmount {
+			t.Errorf("Expected AmountCents of %s to be %d, got %d", tc.json, tc.wantAmount, got)
+		}
+	}
+}
+
+func TestPaymentInquiryResponseFX(t *testing.T) {
+	var dcc PaymentInquiryResponse
+	if err := json.Unmarshal([]byte(`{"amount":100.00,"currencyCode":"SGD","fxAmount":2537.45,"fxRate":25.3745,"fxCurrencyCode":"THB"}`), &dcc); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if !dcc.HasFX() {
+		t.Error("Expected HasFX to be true")
+	}
+	if got := dcc.FXConvertedCents(); got != 253745 {
+		t.Errorf("Expected FXConvertedCents 253745, got %d", got)
+	}
+
+	var plain PaymentInquiryResponse
+	if err := json.Unmarshal([]byte(`{"amount":100.00,"currencyCode":"SGD","fxAmount":0,"fxRate":0,"fxCurrencyCode":""}`), &plain); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if plain.HasFX() {
+		t.Error("Expected HasFX to be false")
+	}
+	if got := plain.FXConvertedCents(); got != 0 {
+		t.Errorf("Expected FXConvertedCents 0, got %d", got)
+	}
+}
+
+func TestDeprecatedPaymentInquiryMatchesInquire(t *testing.T) {
+	var client *Client
+	var gotPayload map[string]any
+	client = NewTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var reqBody struct {
+			Payload string `json:"payload"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			t.Errorf("Error decoding request body: %v", err)
+			return
+		}
+		if err := client.decodeJWTTokenForJSON(reqBody.Payload, &gotPayload); err != nil {
+			t.Errorf("Error decoding request payload: %v", err)
+			return
+		}
+		token, err := client.generateJWTTokenForJSON([]byte(`{"respCode":"0000","respDesc":"Success","paymentScheme":"VI","issuerBank":"OCBC"}`))
+		if err != nil {
+			t.Errorf("Error generating JWT token: %v", err)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"payload": token})
+	})
+
+	testCases := []struct {
+		name       string
+		inquire    InquiryQuery
+		deprecated func() (*PaymentInquiryResponse, error)
+	}{
+		{
+			name:    "by invoice",
+			inquire: InquiryQuery{InvoiceNo: "INV123", Locale: "en"},
+			deprecated: func() (*PaymentInquiryResponse, error) {
+				return client.PaymentInquiryByInvoice(ctx, &PaymentInquiryByInvoiceRequest{InvoiceNo: "INV123", Locale: "en"})
+			},
+		},
+		{
+			name:    "by payment token",
+			inquire: InquiryQuery{PaymentToken: "token123", MerchantID: "JT02"},
+			deprecated: func() (*PaymentInquiryResponse, error) {
+				return client.PaymentInquiryByToken(ctx, &PaymentInquiryByTokenRequest{PaymentToken: "token123", MerchantID: "JT02"})
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gotPayload = nil
+			want, err := client.Inquire(ctx, tc.inquire)
+			if err != nil {
+				t.Fatalf("Inquire failed: %v", err)
+			}
+			wantPayload := gotPayload
+
+			gotPayload = nil
+			got, err := tc.deprecated()
+			if err != nil {
+				t.Fatalf("Deprecated inquiry failed: %v", err)
+			}
+			if !reflect.DeepEqual(gotPayload, wantPayload) {
+				t.Errorf("Expected payload %v, got %v", wantPayload, gotPayload)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Expected response %+v, got %+v", want, got)
+			}
+			if got.PaymentScheme != "VI" || got.IssuerBank != "OCBC" {
+				t.Errorf("Expected paymentScheme VI and issuerBank OCBC, got %s and %s", got.PaymentScheme, got.IssuerBank)
+			}
+		})
+	}
+}
+
+func TestInquireFlowCodeError(t *testing.T) {
+	var client *Client
+	client = NewTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		token, err := client.generateJWTTokenForJSON([]byte(`{"respCode":"Other","respDesc":"Transaction failed"}`))
+		if err != nil {
+			t.Errorf("Error generating JWT token: %v", err)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"payload": token})
+	})
+
+	_, err := client.Inquire(ctx, InquiryQuery{InvoiceNo: "INV123"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected APIError, got %v", err)
+	}
+	if apiErr.FlowCode != FlowOtherTransactionFailedOrRejectedPerformPaymentInquiryToGetPayment {
+		t.Errorf("Expected flow code Other, got %q", apiErr.FlowCode)
+	}
+	if !IsResponseCode(err, PaymentResponseCode(FlowOtherTransactionFailedOrRejectedPerformPaymentInquiryToGetPayment)) {
+		t.Errorf("Expected respCode Other, got %q", apiErr.RespCode)
+	}
+
+	// Ping returns the same error
+	if err := client.Ping(ctx); !errors.As(err, &apiErr) || apiErr.FlowCode != FlowOtherTransactionFailedOrRejectedPerformPaymentInquiryToGetPayment {
+		t.Errorf("Expected Ping error with flow code Other, got %v", err)
+	}
+}
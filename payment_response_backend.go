@@ -0,0 +1,101 @@
+package api2c2p
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PaymentResponseBackEnd is the decrypted `paymentResponse` 2C2P posts to the backend return URL
+// Documentation: docs/2c2p/api-payment-response-back-end-parameter.csv
+type PaymentResponseBackEnd struct {
+	XMLName               xml.Name            `xml:"PaymentResponse"`
+	Version               string              `xml:"version"`
+	TimeStamp             string              `xml:"timeStamp"`
+	MerchantID            string              `xml:"merchantID"`
+	RespCode              PaymentResponseCode `xml:"respCode"`
+	PAN                   string              `xml:"pan"`
+	Amount                string              `xml:"amt"`
+	UniqueTransactionCode string              `xml:"uniqueTransactionCode"`
+	TranRef               string              `xml:"tranRef"`
+	ApprovalCode          string              `xml:"approvalCode"`
+	RefNumber             string              `xml:"refNumber"`
+	ECI                   string              `xml:"eci"`
+	DateTime              string              `xml:"dateTime"`
+	Status                string              `xml:"status"`
+	FailReason            string              `xml:"failReason"` // can contain successful reason too
+	UserDefined1          string              `xml:"userDefined1"`
+	UserDefined2          string              `xml:"userDefined2"`
+	UserDefined3          string              `xml:"userDefined3"`
+	UserDefined4          string              `xml:"userDefined4"`
+	UserDefined5          string              `xml:"userDefined5"`
+	IPPPeriod             string              `xml:"ippPeriod"`
+	IPPInterestType       string              `xml:"ippInterestType"`
+	IPPInterestRate       string              `xml:"ippInterestRate"`
+	IPPMerchantAbsorbRate string              `xml:"ippMerchantAbsorbRate"`
+	PaidChannel           string              `xml:"paidChannel"`
+	PaidAgent             string              `xml:"paidAgent"`
+	PaymentChannel        string              `xml:"paymentChannel"`
+	BackendInvoice        string              `xml:"backendInvoice"`
+	IssuerCountry         string              `xml:"issuerCountry"`
+	IssuerCountryA3       string              `xml:"issuerCountryA3"`
+	BankName              string              `xml:"bankName"`
+	CardType              string              `xml:"cardType"`
+	ProcessBy             string              `xml:"processBy"`
+	PaymentScheme         string              `xml:"paymentScheme"`
+	PaymentID             string              `xml:"paymentID"`
+	AcquirerResponseCode  string              `xml:"acquirerResponseCode"`
+	SchemePaymentID       string              `xml:"schemePaymentID"`
+	HashValue             string              `xml:"hashValue"`
+}
+
+// IsSuccess reports whether the payment went through: a success respCode, i.e. "00", with status
+// "A" (approved, what 2C2P sends right after authorization, see
+// docs/2c2p/payment-return-be.success.xml) or "S" (settled, the funds were already captured).
+// Any other status, e.g. "F", "PF" (payment failed) or "AR" (authentication rejected), is a failure
+func (r PaymentResponseBackEnd) IsSuccess() bool {
+	if !r.RespCode.IsSuccess() {
+		return false
+	}
+	switch r.Status {
+	case "A", "S":
+		return true
+	}
+	return false
+}
+
+// CardScheme returns the card brand from paymentScheme, or processBy if that is empty.
+// cardType is not a brand but "CREDIT" or "DEBIT"
+func (r PaymentResponseBackEnd) CardScheme() CardScheme {
+	if r.PaymentScheme != "" {
+		return ParseCardScheme(r.PaymentScheme)
+	}
+	return ParseCardScheme(r.ProcessBy)
+}
+
+// AmountCents parses Amount, which is 12 digit zero prefixed cents like the `amt` of the
+// request (e.g. "000000010010" from SecureFields, see docs/2c2p/payment-return-be.success.xml),
+// or a decimal amount (e.g. "100.10") from flows that report the amount in dollars
+func (r PaymentResponseBackEnd) AmountCents() (Cents, error) {
+	whole, fraction, isDecimal := strings.Cut(strings.TrimSpace(r.Amount), ".")
+	if whole == "" || strings.Trim(whole, "0123456789") != "" || strings.Trim(fraction, "0123456789") != "" {
+		return 0, fmt.Errorf("invalid amount %q", r.Amount)
+	}
+	if !isDecimal {
+		if len(whole) != 12 {
+			return 0, fmt.Errorf("invalid amount %q, expected 12 digits or a decimal", r.Amount)
+		}
+		cents, err := strconv.ParseInt(whole, 10, 64)
+		return Cents(cents), err
+	}
+	if trimmed := strings.TrimRight(fraction, "0"); len(trimmed) > 2 {
+		return 0, fmt.Errorf("invalid amount %q, expected at most 2 decimal places", r.Amount)
+	}
+	fraction = (fraction + "00")[:2]
+	cents, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q: %w", r.Amount, err)
+	}
+	return Cents(cents), nil
+}
@@ -0,0 +1,97 @@
+package api2c2p
+
+import (
+	"encoding/xml"
+	"os"
+	"testing"
+)
+
+func TestPaymentResponseBackEndUnmarshal(t *testing.T) {
+	data, err := os.ReadFile("docs/2c2p/payment-return-be.success.xml")
+	if err != nil {
+		t.Fatalf("Failed to read sample XML: %v", err)
+	}
+
+	var response PaymentResponseBackEnd
+	if err := xml.Unmarshal(data, &response); err != nil {
+		t.Fatalf("Failed to unmarshal XML: %v", err)
+	}
+	if response.RespCode != "00" {
+		t.Errorf("Expected response code 00, got %s", response.RespCode)
+	}
+	if response.UniqueTransactionCode != "INV1738780109" {
+		t.Errorf("Expected unique transaction code INV1738780109, got %s", response.UniqueTransactionCode)
+	}
+	if response.PAN != "411111XXXXXX1111" {
+		t.Errorf("Expected PAN 411111XXXXXX1111, got %s", response.PAN)
+	}
+	if response.HashValue != "D49768D125DB55F138060606A904EAC3A6672C7F" {
+		t.Errorf("Expected hash value D49768D125DB55F138060606A904EAC3A6672C7F, got %s", response.HashValue)
+	}
+
+	// RespCode is typed, so known codes describe themselves
+	data = []byte(`<PaymentResponse><respCode>4005</respCode><status>F</status></PaymentResponse>`)
+	if err := xml.Unmarshal(data, &response); err != nil {
+		t.Fatalf("Failed to unmarshal XML: %v", err)
+	}
+	if got := response.RespCode.Description(); got != "Do not honor" {
+		t.Errorf("Expected description %q, got %q", "Do not honor", got)
+	}
+}
+
+func TestPaymentResponseBackEndIsSuccess(t *testing.T) {
+	testCases := []struct {
+		name     string
+		status   string
+		respCode PaymentResponseCode
+		want     bool
+	}{
+		{"approved", "A", "00", true},
+		{"settled", "S", "00", true},
+		{"approved with failing code", "A", "99", false},
+		{"settled with failing code", "S", "99", false},
+		{"failed", "F", "99", false},
+		{"payment failed with success code", "PF", "00", false},
+		{"authentication rejected", "AR", "00", false},
+		{"four digit success code", "A", Code0000Successful, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := PaymentResponseBackEnd{Status: tc.status, RespCode: tc.respCode}
+			if got := r.IsSuccess(); got != tc.want {
+				t.Errorf("Expected IsSuccess %v for status %q respCode %q, got %v", tc.want, tc.status, tc.respCode, got)
+			}
+		})
+	}
+}
+
+func TestPaymentResponseBackEndAmountCents(t *testing.T) {
+	testCases := []struct {
+		amount  string
+		want    Cents
+		wantErr bool
+	}{
+		{amount: "000000010010", want: 10010},
+		{amount: "100.10", want: 10010},
+		{amount: "100.1", want: 10010},
+		{amount: "100", wantErr: true},
+		{amount: "100.10000", want: 10010},
+		{amount: "100.105", wantErr: true},
+		{amount: "1,000.00", wantErr: true},
+		{amount: "", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		got, err := PaymentResponseBackEnd{Amount: tc.amount}.AmountCents()
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("Expected error for amount %q, got %d", tc.amount, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("Expected %d for amount %q, got %d (%v)", tc.want, tc.amount, got, err)
+		}
+	}
+}
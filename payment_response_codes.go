@@ -457,6 +457,456 @@ func (c PaymentResponseCode) Description() string {
 	}
 }
 
+// Category classifies the response code, e.g. to decide whether to retry
+func (c PaymentResponseCode) Category() ResponseCategory {
+	switch c {
+	case "0000":
+		return ResponseCategorySuccess
+	case "0001":
+		return ResponseCategoryPending
+	case "0003":
+		return ResponseCategoryDeclined
+	case "0004":
+		return ResponseCategoryDeclined
+	case "0999":
+		return ResponseCategorySystemError
+	case "2001":
+		return ResponseCategoryPending
+	case "2002":
+		return ResponseCategoryDeclined
+	case "2003":
+		return ResponseCategoryDeclined
+	case "4000":
+		return ResponseCategorySuccess
+	case "4001":
+		return ResponseCategoryDeclined
+	case "4002":
+		return ResponseCategoryDeclined
+	case "4003":
+		return ResponseCategoryInvalidRequest
+	case "4004":
+		return ResponseCategoryFraud
+	case "4005":
+		return ResponseCategoryDeclined
+	case "4006":
+		return ResponseCategoryDeclined
+	case "4007":
+		return ResponseCategoryFraud
+	case "4008":
+		return ResponseCategorySuccess
+	case "4009":
+		return ResponseCategoryPending
+	case "4010":
+		return ResponseCategorySuccess
+	case "4011":
+		return ResponseCategorySuccess
+	case "4012":
+		return ResponseCategoryInvalidRequest
+	case "4013":
+		return ResponseCategoryInvalidRequest
+	case "4014":
+		return ResponseCategoryInvalidRequest
+	case "4015":
+		return ResponseCategoryDeclined
+	case "4016":
+		return ResponseCategorySuccess
+	case "4017":
+		return ResponseCategoryDeclined
+	case "4018":
+		return ResponseCategoryDeclined
+	case "4019":
+		return ResponseCategoryDeclined
+	case "4020":
+		return ResponseCategoryInvalidRequest
+	case "4021":
+		return ResponseCategoryDeclined
+	case "4022":
+		return ResponseCategorySystemError
+	case "4023":
+		return ResponseCategoryDeclined
+	case "4024":
+		return ResponseCategoryDeclined
+	case "4025":
+		return ResponseCategoryDeclined
+	case "4026":
+		return ResponseCategoryInvalidRequest
+	case "4027":
+		return ResponseCategoryDeclined
+	case "4028":
+		return ResponseCategoryDeclined
+	case "4029":
+		return ResponseCategoryDeclined
+	case "4030":
+		return ResponseCategoryInvalidRequest
+	case "4031":
+		return ResponseCategoryDeclined
+	case "4032":
+		return ResponseCategoryDeclined
+	case "4033":
+		return ResponseCategoryFraud
+	case "4034":
+		return ResponseCategoryFraud
+	case "4035":
+		return ResponseCategoryFraud
+	case "4036":
+		return ResponseCategoryDeclined
+	case "4037":
+		return ResponseCategoryDeclined
+	case "4038":
+		return ResponseCategoryDeclined
+	case "4039":
+		return ResponseCategoryDeclined
+	case "4040":
+		return ResponseCategoryDeclined
+	case "4041":
+		return ResponseCategoryFraud
+	case "4042":
+		return ResponseCategoryDeclined
+	case "4043":
+		return ResponseCategoryFraud
+	case "4044":
+		return ResponseCategoryDeclined
+	case "4045":
+		return ResponseCategorySuccess
+	case "4046":
+		return ResponseCategoryDeclined
+	case "4047":
+		return ResponseCategorySuccess
+	case "4048":
+		return ResponseCategoryDeclined
+	case "4049":
+		return ResponseCategoryDeclined
+	case "4050":
+		return ResponseCategorySystemError
+	case "4051":
+		return ResponseCategoryDeclined
+	case "4052":
+		return ResponseCategoryDeclined
+	case "4053":
+		return ResponseCategoryDeclined
+	case "4054":
+		return ResponseCategoryDeclined
+	case "4055":
+		return ResponseCategoryDeclined
+	case "4056":
+		return ResponseCategoryDeclined
+	case "4057":
+		return ResponseCategoryDeclined
+	case "4058":
+		return ResponseCategoryDeclined
+	case "4059":
+		return ResponseCategoryFraud
+	case "4060":
+		return ResponseCategoryDeclined
+	case "4061":
+		return ResponseCategoryDeclined
+	case "4062":
+		return ResponseCategoryDeclined
+	case "4063":
+		return ResponseCategoryFraud
+	case "4064":
+		return ResponseCategoryDeclined
+	case "4065":
+		return ResponseCategoryDeclined
+	case "4066":
+		return ResponseCategoryDeclined
+	case "4067":
+		return ResponseCategoryFraud
+	case "4068":
+		return ResponseCategorySystemError
+	case "4069":
+		return ResponseCategoryDeclined
+	case "4070":
+		return ResponseCategoryDeclined
+	case "4071":
+		return ResponseCategoryDeclined
+	case "4072":
+		return ResponseCategoryInvalidRequest
+	case "4073":
+		return ResponseCategoryDeclined
+	case "4074":
+		return ResponseCategoryDeclined
+	case "4075":
+		return ResponseCategoryDeclined
+	case "4076":
+		return ResponseCategoryInvalidRequest
+	case "4077":
+		return ResponseCategoryInvalidRequest
+	case "4078":
+		return ResponseCategoryInvalidRequest
+	case "4079":
+		return ResponseCategoryDeclined
+	case "4080":
+		return ResponseCategoryDeclined
+	case "4081":
+		return ResponseCategoryDeclined
+	case "4082":
+		return ResponseCategoryDeclined
+	case "4083":
+		return ResponseCategoryDeclined
+	case "4084":
+		return ResponseCategoryDeclined
+	case "4085":
+		return ResponseCategoryDeclined
+	case "4086":
+		return ResponseCategorySystemError
+	case "4087":
+		return ResponseCategoryDeclined
+	case "4088":
+		return ResponseCategoryDeclined
+	case "4089":
+		return ResponseCategoryDeclined
+	case "4090":
+		return ResponseCategorySystemError
+	case "4091":
+		return ResponseCategorySystemError
+	case "4092":
+		return ResponseCategoryDeclined
+	case "4093":
+		return ResponseCategoryDeclined
+	case "4094":
+		return ResponseCategoryInvalidRequest
+	case "4095":
+		return ResponseCategorySystemError
+	case "4096":
+		return ResponseCategorySystemError
+	case "4097":
+		return ResponseCategorySystemError
+	case "4098":
+		return ResponseCategorySystemError
+	case "4099":
+		return ResponseCategoryDeclined
+	case "4110":
+		return ResponseCategorySuccess
+	case "4120":
+		return ResponseCategorySuccess
+	case "4121":
+		return ResponseCategoryDeclined
+	case "4122":
+		return ResponseCategoryDeclined
+	case "4130":
+		return ResponseCategoryDeclined
+	case "4131":
+		return ResponseCategoryDeclined
+	case "4132":
+		return ResponseCategoryDeclined
+	case "4140":
+		return ResponseCategoryInvalidRequest
+	case "4200":
+		return ResponseCategorySuccess
+	case "4201":
+		return ResponseCategoryDeclined
+	case "4202":
+		return ResponseCategoryInvalidRequest
+	case "4203":
+		return ResponseCategorySystemError
+	case "4204":
+		return ResponseCategoryDeclined
+	case "4205":
+		return ResponseCategoryDeclined
+	case "4208":
+		return ResponseCategoryDeclined
+	case "4209":
+		return ResponseCategoryDeclined
+	case "5002":
+		return ResponseCategorySystemError
+	case "5003":
+		return ResponseCategoryInvalidRequest
+	case "5004":
+		return ResponseCategoryInvalidRequest
+	case "5005":
+		return ResponseCategoryInvalidRequest
+	case "5006":
+		return ResponseCategoryInvalidRequest
+	case "5007":
+		return ResponseCategoryDeclined
+	case "5008":
+		return ResponseCategoryInvalidRequest
+	case "5009":
+		return ResponseCategoryDeclined
+	case "5010":
+		return ResponseCategoryDeclined
+	case "5011":
+		return ResponseCategoryInvalidRequest
+	case "5012":
+		return ResponseCategoryInvalidRequest
+	case "5013":
+		return ResponseCategoryInvalidRequest
+	case "5014":
+		return ResponseCategoryDeclined
+	case "5015":
+		return ResponseCategoryDeclined
+	case "5016":
+		return ResponseCategoryDeclined
+	case "5017":
+		return ResponseCategoryDeclined
+	case "5018":
+		return ResponseCategoryDeclined
+	case "5019":
+		return ResponseCategoryDeclined
+	case "5998":
+		return ResponseCategorySystemError
+	case "6012":
+		return ResponseCategoryInvalidRequest
+	case "6101":
+		return ResponseCategoryInvalidRequest
+	case "6102":
+		return ResponseCategoryInvalidRequest
+	case "6103":
+		return ResponseCategoryInvalidRequest
+	case "6104":
+		return ResponseCategoryInvalidRequest
+	case "6105":
+		return ResponseCategoryInvalidRequest
+	case "6106":
+		return ResponseCategoryInvalidRequest
+	case "6107":
+		return ResponseCategoryInvalidRequest
+	case "6108":
+		return ResponseCategoryInvalidRequest
+	case "6109":
+		return ResponseCategoryInvalidRequest
+	case "6110":
+		return ResponseCategoryInvalidRequest
+	case "7012":
+		return ResponseCategoryInvalidRequest
+	case "9004":
+		return ResponseCategoryInvalidRequest
+	case "9005":
+		return ResponseCategoryInvalidRequest
+	case "9006":
+		return ResponseCategoryInvalidRequest
+	case "9007":
+		return ResponseCategoryInvalidRequest
+	case "9008":
+		return ResponseCategoryInvalidRequest
+	case "9009":
+		return ResponseCategoryInvalidRequest
+	case "9010":
+		return ResponseCategoryInvalidRequest
+	case "9012":
+		return ResponseCategoryInvalidRequest
+	case "9013":
+		return ResponseCategoryInvalidRequest
+	case "9014":
+		return ResponseCategoryInvalidRequest
+	case "9015":
+		return ResponseCategoryInvalidRequest
+	case "9016":
+		return ResponseCategoryInvalidRequest
+	case "9017":
+		return ResponseCategoryInvalidRequest
+	case "9035":
+		return ResponseCategoryInvalidRequest
+	case "9037":
+		return ResponseCategoryInvalidRequest
+	case "9038":
+		return ResponseCategoryInvalidRequest
+	case "9039":
+		return ResponseCategoryInvalidRequest
+	case "9040":
+		return ResponseCategoryInvalidRequest
+	case "9041":
+		return ResponseCategoryInvalidRequest
+	case "9042":
+		return ResponseCategoryInvalidRequest
+	case "9057":
+		return ResponseCategoryInvalidRequest
+	case "9058":
+		return ResponseCategoryInvalidRequest
+	case "9059":
+		return ResponseCategoryInvalidRequest
+	case "9060":
+		return ResponseCategoryInvalidRequest
+	case "9078":
+		return ResponseCategoryInvalidRequest
+	case "9080":
+		return ResponseCategoryInvalidRequest
+	case "9088":
+		return ResponseCategoryInvalidRequest
+	case "9089":
+		return ResponseCategoryInvalidRequest
+	case "9090":
+		return ResponseCategoryInvalidRequest
+	case "9091":
+		return ResponseCategoryInvalidRequest
+	case "9092":
+		return ResponseCategoryInvalidRequest
+	case "9093":
+		return ResponseCategoryInvalidRequest
+	case "9094":
+		return ResponseCategoryInvalidRequest
+	case "9095":
+		return ResponseCategoryInvalidRequest
+	case "9100":
+		return ResponseCategoryInvalidRequest
+	case "9101":
+		return ResponseCategoryInvalidRequest
+	case "9102":
+		return ResponseCategoryInvalidRequest
+	case "9103":
+		return ResponseCategoryInvalidRequest
+	case "9104":
+		return ResponseCategoryInvalidRequest
+	case "9105":
+		return ResponseCategoryInvalidRequest
+	case "9106":
+		return ResponseCategoryInvalidRequest
+	case "9107":
+		return ResponseCategoryInvalidRequest
+	case "9108":
+		return ResponseCategoryInvalidRequest
+	case "9109":
+		return ResponseCategoryInvalidRequest
+	case "9110":
+		return ResponseCategoryInvalidRequest
+	case "9202":
+		return ResponseCategoryInvalidRequest
+	case "9900":
+		return ResponseCategoryInvalidRequest
+	case "9901":
+		return ResponseCategoryInvalidRequest
+	case "9902":
+		return ResponseCategoryInvalidRequest
+	case "9903":
+		return ResponseCategoryInvalidRequest
+	case "9904":
+		return ResponseCategoryInvalidRequest
+	case "9905":
+		return ResponseCategoryInvalidRequest
+	case "9906":
+		return ResponseCategoryInvalidRequest
+	case "9907":
+		return ResponseCategoryInvalidRequest
+	case "9908":
+		return ResponseCategoryInvalidRequest
+	case "9909":
+		return ResponseCategoryInvalidRequest
+	case "9990":
+		return ResponseCategorySystemError
+	case "9991":
+		return ResponseCategorySystemError
+	case "9992":
+		return ResponseCategorySystemError
+	case "9993":
+		return ResponseCategorySystemError
+	case "9994":
+		return ResponseCategorySystemError
+	case "9995":
+		return ResponseCategorySystemError
+	case "9996":
+		return ResponseCategorySystemError
+	case "9997":
+		return ResponseCategorySystemError
+	case "9998":
+		return ResponseCategorySystemError
+	case "9999":
+		return ResponseCategorySystemError
+	default:
+		return ResponseCategoryUnknown
+	}
+}
+
 // Known response codes
 const (
 	Code0000Successful                                                            PaymentResponseCode = "0000" // Successful
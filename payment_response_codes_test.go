@@ -0,0 +1,104 @@
+package api2c2p
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/choonkeat/2c2p/internal/responsecodes"
+)
+
+// generatedCodes returns the codes declared as constants of typeName in the generated file
+func generatedCodes(t *testing.T, path, typeName string) []string {
+	t.Helper()
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	var codes []string
+	pattern := regexp.MustCompile(`(?m)^\s*\w+\s+` + typeName + ` = "([^"]*)"`)
+	for _, match := range pattern.FindAllStringSubmatch(string(src), -1) {
+		codes = append(codes, match[1])
+	}
+	return codes
+}
+
+// diffCodes lists the differences between the CSV codes and the generated ones, one per line
+func diffCodes(fromCSV []responsecodes.Code, generated []string, description func(string) string) string {
+	want := map[string]string{}
+	for _, c := range fromCSV {
+		want[c.Code] = c.Description
+	}
+	got := map[string]bool{}
+	var diff []string
+	for _, code := range generated {
+		got[code] = true
+		desc, ok := want[code]
+		switch {
+		case !ok:
+			diff = append(diff, fmt.Sprintf("+ %s: %s (not in CSV)", code, description(code)))
+		case description(code) != desc:
+			diff = append(diff, fmt.Sprintf("~ %s: CSV %q, generated %q", code, desc, description(code)))
+		}
+	}
+	for code, desc := range want {
+		if !got[code] {
+			diff = append(diff, fmt.Sprintf("- %s: %s (not generated)", code, desc))
+		}
+	}
+	sort.Strings(diff)
+	return strings.Join(diff, "\n")
+}
+
+func TestPaymentResponseCodesMatchCSV(t *testing.T) {
+	testCases := []struct {
+		name        string
+		csvPath     string
+		read        func(io.Reader) ([]responsecodes.Code, error)
+		generator   string
+		goPath      string
+		typeName    string
+		description func(string) string
+	}{
+		{
+			name:        "payment",
+			csvPath:     "docs/2c2p/response-code-payment.csv",
+			read:        responsecodes.ReadPaymentCSV,
+			generator:   "./cmd/generate-response-codes",
+			goPath:      "payment_response_codes.go",
+			typeName:    "PaymentResponseCode",
+			description: func(code string) string { return PaymentResponseCode(code).Description() },
+		},
+		{
+			name:        "payment flow",
+			csvPath:     "docs/2c2p/response-code-payment-flow.csv",
+			read:        responsecodes.ReadPaymentFlowCSV,
+			generator:   "./cmd/generate-payment-flow-response-codes",
+			goPath:      "payment_flow_response_codes.go",
+			typeName:    "PaymentFlowResponseCode",
+			description: func(code string) string { return PaymentFlowResponseCode(code).Description() },
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := os.Open(tc.csvPath)
+			if err != nil {
+				t.Fatalf("Failed to open %s: %v", tc.csvPath, err)
+			}
+			defer f.Close()
+			fromCSV, err := tc.read(f)
+			if err != nil {
+				t.Fatalf("Failed to read %s: %v", tc.csvPath, err)
+			}
+
+			if diff := diffCodes(fromCSV, generatedCodes(t, tc.goPath, tc.typeName), tc.description); diff != "" {
+				t.Errorf("%s is out of date with %s, run `go run %s`:\n%s", tc.goPath, tc.csvPath, tc.generator, diff)
+			}
+		})
+	}
+}
@@ -3,9 +3,17 @@ package api2c2p
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html/template"
+	"math"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 )
 
 // PaymentTokenRequest3DSType represents the 3DS request type
@@ -30,8 +38,27 @@ const (
 	PaymentChannelIPP PaymentTokenPaymentChannel = "IPP"
 	// PaymentChannelAPM represents alternative payment methods
 	PaymentChannelAPM PaymentTokenPaymentChannel = "APM"
+	// PaymentChannelQR represents QR payment, e.g. PayNow or PromptPay
+	PaymentChannelQR PaymentTokenPaymentChannel = "QR"
 )
 
+// KnownPaymentChannels are the payment channels Validate accepts
+var KnownPaymentChannels = []PaymentTokenPaymentChannel{PaymentChannelCC, PaymentChannelIPP, PaymentChannelAPM, PaymentChannelQR}
+
+// Agent channels for PaymentTokenRequest.AgentChannel, i.e. where customers pay counter and bank transfer payments
+const (
+	AgentChannelATM            = "ATM"
+	AgentChannelBankCounter    = "BANKCOUNTER"
+	AgentChannelKiosk          = "KIOSK"
+	AgentChannelIBanking       = "IBANKING"
+	AgentChannelMobileBanking  = "MOBILEBANKING"
+	AgentChannelOverTheCounter = "OVERTHECOUNTER"
+	AgentChannelWebPay         = "WEBPAY"
+)
+
+// KnownAgentChannels are the agent channels Validate accepts
+var KnownAgentChannels = []string{AgentChannelATM, AgentChannelBankCounter, AgentChannelKiosk, AgentChannelIBanking, AgentChannelMobileBanking, AgentChannelOverTheCounter, AgentChannelWebPay}
+
 // PaymentTokenInterestType represents the installment interest type
 type PaymentTokenInterestType string
 
@@ -44,7 +71,25 @@ const (
 	InterestTypeMerchant PaymentTokenInterestType = "M"
 )
 
+// StoreCredentials is whether the card is stored for later merchant initiated payments
+type StoreCredentials string
+
+const (
+	// StoreCredentialsFirst stores the card with this first payment
+	StoreCredentialsFirst StoreCredentials = "F"
+	// StoreCredentialsSubsequent is a payment with previously stored credentials
+	StoreCredentialsSubsequent StoreCredentials = "S"
+	// StoreCredentialsNo does not store the card
+	StoreCredentialsNo StoreCredentials = "N"
+)
+
+// KnownStoreCredentials are the StoreCredentials values Validate accepts
+var KnownStoreCredentials = []StoreCredentials{StoreCredentialsFirst, StoreCredentialsSubsequent, StoreCredentialsNo}
+
+// LoyaltyPoints redeems the customer's loyalty points against the payment, see WithLoyaltyPoints
 type LoyaltyPoints struct {
+	// RedeemAmount is the amount to redeem in the payment currency, e.g. 10.50
+	// Must be positive and not more than PaymentTokenRequest.AmountCents
 	RedeemAmount float64 `json:"redeemAmount"`
 }
 
@@ -59,6 +104,10 @@ type PaymentTokenRequest struct {
 	// Max length: 100 characters
 	IdempotencyID string `json:"idempotencyID,omitempty"`
 
+	// NonceStr is a random value making each request payload, and so its signature, unique (optional)
+	// PaymentToken fills it with GenerateNonce when empty and Config.AutoNonce is set
+	NonceStr string `json:"nonceStr,omitempty"`
+
 	// InvoiceNo is the merchant's invoice number (required)
 	// Max length: 50 characters
 	InvoiceNo string `json:"invoiceNo"`
@@ -86,6 +135,10 @@ type PaymentTokenRequest struct {
 	// Format: YYYY-MM-DD HH:mm:ss
 	PaymentExpiryYYYYMMDDHHMMSS string `json:"paymentExpiry,omitempty"`
 
+	// PaymentExpiryAt overrides PaymentExpiryYYYYMMDDHHMMSS when not zero (optional)
+	// It is formatted in its own location, see FormatPaymentExpiry
+	PaymentExpiryAt time.Time `json:"-"`
+
 	// UserDefined1 is a custom field (optional)
 	// Max length: 255 characters
 	UserDefined1 string `json:"userDefined1,omitempty"`
@@ -106,11 +159,17 @@ type PaymentTokenRequest struct {
 	// Max length: 255 characters
 	UserDefined5 string `json:"userDefined5,omitempty"`
 
+	// Locale is the language of the payment page (optional)
+	// Based on ISO 639, see DefaultLocales
+	Locale string `json:"locale,omitempty"`
+
 	// StatementDescriptor is the dynamic statement description (optional)
 	// Max length: 25 characters
 	StatementDescriptor string `json:"statementDescriptor,omitempty"`
 
 	// CardTokens is a comma-separated list of card tokens (optional)
+	// With ImmediatePayment and StoreCredentialsSubsequent the token is charged without the customer,
+	// see ChargeStoredCard
 	CardTokens []string `json:"cardTokens,omitempty"`
 
 	// Request3DS specifies the 3DS request type (optional)
@@ -131,18 +190,26 @@ type PaymentTokenRequest struct {
 	DSTransactionID string `json:"dsTransactionID,omitempty"`
 
 	// StoreCredentials specifies whether to store credentials (optional)
-	// Values: "F" (First time), "S" (Subsequent), "N" (No)
-	StoreCredentials string `json:"storeCredentials,omitempty"`
+	// Values: StoreCredentialsFirst, StoreCredentialsSubsequent, StoreCredentialsNo
+	StoreCredentials StoreCredentials `json:"storeCredentials,omitempty"`
 
 	// Tokenize enables tokenization (optional)
 	Tokenize bool `json:"tokenize,omitempty"`
 
-	// TokenizeOnly only tokenizes without processing payment (optional)
+	// TokenizeOnly only tokenizes without processing payment (optional), see TokenizeCard
 	TokenizeOnly bool `json:"tokenizeOnly,omitempty"`
 
 	// IframeMode enables iframe mode (optional)
 	IframeMode bool `json:"iframeMode,omitempty"`
 
+	// FrontendReturnURL is where the customer's browser returns after payment (optional)
+	// Default: the frontend return URL configured in the 2C2P merchant portal
+	FrontendReturnURL string `json:"frontendReturnUrl,omitempty"`
+
+	// BackendReturnURL receives the backend payment notification (optional)
+	// Default: the backend return URL configured in the 2C2P merchant portal
+	BackendReturnURL string `json:"backendReturnUrl,omitempty"`
+
 	// PaymentRouteID specifies the payment route ID (optional)
 	PaymentRouteID string `json:"paymentRouteID,omitempty"`
 
@@ -162,16 +229,23 @@ type PaymentTokenRequest struct {
 	// Values: "A" (Advance), "C" (Customer), "M" (Merchant)
 	InterestType PaymentTokenInterestType `json:"interestType,omitempty"`
 
-	// AgentChannel is a comma-separated list of agent channels (optional)
+	// AgentChannel is a comma-separated list of agent channels (optional), e.g. AgentChannelATM
 	AgentChannel []string `json:"agentChannel,omitempty"`
 
+	// AllowUnknownChannels skips checking PaymentChannel and AgentChannel against
+	// KnownPaymentChannels and KnownAgentChannels, for channels newer than this package
+	AllowUnknownChannels bool `json:"-"`
+
 	// FXRateID is the forex rate ID (optional)
+	// FXRateID, FxProviderCode and OriginalAmount are set together or not at all
 	FXRateID string `json:"fxRateID,omitempty"`
 
 	// FxProviderCode is the forex provider code (optional)
 	FxProviderCode string `json:"fxProviderCode,omitempty"`
 
-	// OriginalAmount is the original currency amount (optional)
+	// OriginalAmount is the amount in the merchant's own currency before conversion (optional)
+	// AmountCents and CurrencyCodeISO4217 are then the converted amount the customer pays,
+	// i.e. OriginalAmount multiplied by the rate quoted for FXRateID
 	OriginalAmount float64 `json:"originalAmount,omitempty"`
 
 	// SubMerchantID is the sub-merchant ID (optional)
@@ -187,7 +261,8 @@ type PaymentTokenRequest struct {
 	SubMerchantDescription string `json:"subMerchantDescription,omitempty"`
 
 	// SubMerchantAmount is the sub-merchant amount (optional)
-	SubMerchantAmount float64 `json:"subMerchantAmount,omitempty"`
+	// Format: 12 digits with 5 decimal places, like AmountCents
+	SubMerchantAmount Cents `json:"subMerchantAmount,omitempty"`
 
 	// Recurring enables recurring payment (optional)
 	Recurring bool `json:"recurring,omitempty"`
@@ -202,11 +277,11 @@ type PaymentTokenRequest struct {
 	RecurringIntervalDays int `json:"recurringInterval,omitempty"`
 
 	// ChargeNextDateYYYYMMDD is the next charge date (optional)
-	// Format: YYYYMMDD
+	// Format: YYYYMMDD, see FormatChargeDate
 	ChargeNextDateYYYYMMDD string `json:"chargeNextDate,omitempty"`
 
 	// ChargeOnDateYYYYMMDD is the specific charge date (optional)
-	// Format: YYYYMMDD
+	// Format: YYYYMMDD, see FormatChargeDate
 	ChargeOnDateYYYYMMDD string `json:"chargeOnDate,omitempty"`
 
 	// AllowAccumulate allows accumulation of recurring payments (optional)
@@ -225,7 +300,180 @@ type PaymentTokenRequest struct {
 	SubMerchants []PaymentTokenSubMerchant `json:"subMerchants,omitempty"`
 
 	// UIParams is the UI parameters for payment token requests (optional)
-	UIParams *paymentTokenUiParams `json:"uiParams,omitempty"`
+	UIParams *PaymentTokenUIParams `json:"uiParams,omitempty"`
+}
+
+// Validate reports every problem with the request that 2C2P would reject, joined into one error
+func (r *PaymentTokenRequest) Validate() error {
+	errs := append(r.validateRecurring(), r.validateChannels()...)
+	errs = append(errs, r.validateLoyaltyPoints()...)
+	errs = append(errs, r.validateFX()...)
+	errs = append(errs, r.validateSubMerchants()...)
+	if r.StoreCredentials != "" && !slices.Contains(KnownStoreCredentials, r.StoreCredentials) {
+		errs = append(errs, fmt.Errorf("unknown storeCredentials %q, expected one of %v", r.StoreCredentials, KnownStoreCredentials))
+	}
+	return errors.Join(errs...)
+}
+
+// WithLoyaltyPoints redeems redeemAmount, in the payment currency, of the customer's loyalty points
+func (r *PaymentTokenRequest) WithLoyaltyPoints(redeemAmount float64) *PaymentTokenRequest {
+	r.LoyaltyPoints = &LoyaltyPoints{RedeemAmount: redeemAmount}
+	return r
+}
+
+// WithUserInfo pre-fills the payment page with the customer's details
+func (r *PaymentTokenRequest) WithUserInfo(info PaymentTokenUserInfo) *PaymentTokenRequest {
+	r.UIParams = &PaymentTokenUIParams{UserInfo: &info}
+	return r
+}
+
+func (r *PaymentTokenRequest) validateLoyaltyPoints() []error {
+	if r.LoyaltyPoints == nil {
+		return nil
+	}
+	redeem := r.LoyaltyPoints.RedeemAmount
+	if redeem <= 0 {
+		return []error{fmt.Errorf("loyaltyPoints.redeemAmount must be positive, got %v", redeem)}
+	}
+	if Cents(math.Round(redeem*100)) > r.AmountCents {
+		return []error{fmt.Errorf("loyaltyPoints.redeemAmount %v exceeds amount %s", redeem, r.AmountCents.ToDollars())}
+	}
+	return nil
+}
+
+func (r *PaymentTokenRequest) validateChannels() []error {
+	if r.AllowUnknownChannels {
+		return nil
+	}
+	var errs []error
+	for _, channel := range r.PaymentChannel {
+		if !slices.Contains(KnownPaymentChannels, channel) {
+			errs = append(errs, fmt.Errorf("unknown paymentChannel %q, expected one of %v", channel, KnownPaymentChannels))
+		}
+	}
+	for _, channel := range r.AgentChannel {
+		if !slices.Contains(KnownAgentChannels, channel) {
+			errs = append(errs, fmt.Errorf("unknown agentChannel %q, expected one of %v", channel, KnownAgentChannels))
+		}
+	}
+	return errs
+}
+
+func (r *PaymentTokenRequest) validateRecurring() []error {
+	var errs []error
+	if r.Recurring {
+		if r.RecurringAmount <= 0 {
+			errs = append(errs, fmt.Errorf("recurringAmount is required for recurring payments"))
+		}
+		if r.RecurringCount <= 0 {
+			errs = append(errs, fmt.Errorf("recurringCount is required for recurring payments"))
+		}
+		if r.RecurringIntervalDays <= 0 {
+			errs = append(errs, fmt.Errorf("recurringInterval is required for recurring payments"))
+		}
+	}
+	if r.AllowAccumulate && r.MaxAccumulateAmount <= 0 {
+		errs = append(errs, fmt.Errorf("maxAccumulateAmount is required when allowAccumulate is set"))
+	}
+	for _, date := range []struct{ name, value string }{
+		{"chargeNextDate", r.ChargeNextDateYYYYMMDD},
+		{"chargeOnDate", r.ChargeOnDateYYYYMMDD},
+	} {
+		if date.value == "" {
+			continue
+		}
+		if _, err := time.Parse("20060102", date.value); err != nil {
+			errs = append(errs, fmt.Errorf("%s %q is not in YYYYMMDD format", date.name, date.value))
+		}
+	}
+	return errs
+}
+
+func (r *PaymentTokenRequest) validateFX() []error {
+	var present, missing []string
+	for _, field := range []struct {
+		name string
+		set  bool
+	}{
+		{"fxProviderCode", r.FxProviderCode != ""},
+		{"fxRateID", r.FXRateID != ""},
+		{"originalAmount", r.OriginalAmount != 0},
+	} {
+		if field.set {
+			present = append(present, field.name)
+		} else {
+			missing = append(missing, field.name)
+		}
+	}
+	if len(present) == 0 || len(missing) == 0 {
+		return nil
+	}
+	return []error{fmt.Errorf("%s required when %s set", strings.Join(missing, " and "), strings.Join(present, " and "))}
+}
+
+func (r *PaymentTokenRequest) validateSubMerchants() []error {
+	var errs []error
+	if r.SubMerchantAmount != 0 {
+		if err := r.SubMerchantAmount.checkCurrencyExponent(r.CurrencyCodeISO4217); err != nil {
+			errs = append(errs, fmt.Errorf("subMerchantAmount: %w", err))
+		}
+	}
+	for i, subMerchant := range r.SubMerchants {
+		if subMerchant.Amount <= 0 {
+			errs = append(errs, fmt.Errorf("subMerchants[%d].amount must be positive, got %s", i, subMerchant.Amount.ToDollars()))
+			continue
+		}
+		if err := subMerchant.Amount.checkCurrencyExponent(r.CurrencyCodeISO4217); err != nil {
+			errs = append(errs, fmt.Errorf("subMerchants[%d].amount: %w", i, err))
+		}
+	}
+	return errs
+}
+
+// MarshalJSON implements json.Marshaler, applying PaymentExpiryAt
+func (r PaymentTokenRequest) MarshalJSON() ([]byte, error) {
+	type plain PaymentTokenRequest
+	if !r.PaymentExpiryAt.IsZero() {
+		r.PaymentExpiryYYYYMMDDHHMMSS = FormatPaymentExpiry(r.PaymentExpiryAt)
+	}
+	return json.Marshal(plain(r))
+}
+
+// FieldMap returns the fields as they are sent in the signed JWT payload, keyed by json name.
+// Omitted fields are absent and numbers are json.Number, useful for debugging signature mismatches
+func (r PaymentTokenRequest) FieldMap() map[string]any {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil // unreachable, every field type marshals
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var fields map[string]any
+	if err := decoder.Decode(&fields); err != nil {
+		return nil
+	}
+	return fields
+}
+
+// GenerateNonce returns 32 random hex characters for PaymentTokenRequest.NonceStr.
+// It panics if crypto/rand fails, which it can before Go 1.24
+func GenerateNonce() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("api2c2p: GenerateNonce: crypto/rand: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// FormatPaymentExpiry formats t for PaymentExpiryYYYYMMDDHHMMSS, e.g. "2025-02-04 23:59:59".
+// The wall clock of t is used as is; convert with t.In for the merchant's timezone
+func FormatPaymentExpiry(t time.Time) string {
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// FormatChargeDate formats t for ChargeNextDateYYYYMMDD and ChargeOnDateYYYYMMDD, e.g. "20250201"
+func FormatChargeDate(t time.Time) string {
+	return t.Format("20060102")
 }
 
 func (c *Client) newPaymentTokenRequest(ctx context.Context, req *PaymentTokenRequest) (*http.Request, error) {
@@ -264,11 +512,32 @@ func (c *Client) newPaymentTokenRequest(ctx context.Context, req *PaymentTokenRe
 	return httpReq, nil
 }
 
-// PaymentToken creates a payment token for processing a payment
-func (c *Client) PaymentToken(ctx context.Context, req *PaymentTokenRequest) (*PaymentTokenResponse, error) {
+// preparePaymentToken fills in the client defaults and validates req. The idempotency ID
+// is set on the returned copy, not req, so reusing req generates a new one
+func (c *Client) preparePaymentToken(req *PaymentTokenRequest) (*PaymentTokenRequest, error) {
 	if req.MerchantID == "" {
 		req.MerchantID = c.MerchantID
 	}
+	if req.NonceStr == "" && c.AutoNonce {
+		req.NonceStr = GenerateNonce()
+	}
+	if err := c.validateLocale(req.Locale); err != nil {
+		return nil, err
+	}
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid payment token request: %w", err)
+	}
+	prepared := *req
+	prepared.IdempotencyID = c.idempotencyID(req.IdempotencyID)
+	return &prepared, nil
+}
+
+// PaymentToken creates a payment token for processing a payment
+func (c *Client) PaymentToken(ctx context.Context, req *PaymentTokenRequest) (*PaymentTokenResponse, error) {
+	req, err := c.preparePaymentToken(req)
+	if err != nil {
+		return nil, err
+	}
 
 	// Make request
 	httpReq, err := c.newPaymentTokenRequest(ctx, req)
@@ -283,36 +552,54 @@ func (c *Client) PaymentToken(ctx context.Context, req *PaymentTokenRequest) (*P
 	}
 	defer resp.Body.Close()
 
-	// Try to decode response
-	var jwtResponse struct {
-		Payload string `json:"payload"`
+	// Decode response; errors may come back unsigned
+	var tokenResp PaymentTokenResponse
+	direct, err := c.decodePayloadResponse(resp.Body, &tokenResp)
+	if err != nil {
+		return nil, err
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&jwtResponse); err != nil || jwtResponse.Payload == "" {
-		// Try decoding as direct response
-		var response struct {
-			RespCode PaymentResponseCode `json:"respCode"`
-			RespDesc string              `json:"respDesc"`
+	if direct != nil {
+		tokenResp = PaymentTokenResponse{
+			RespCode: PaymentResponseCode(direct.RespCode),
+			RespDesc: direct.RespDesc,
 		}
-		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-			return nil, fmt.Errorf("decode response: %w", err)
-		}
-		return &PaymentTokenResponse{
-			RespCode: response.RespCode,
-			RespDesc: response.RespDesc,
-		}, nil
-	}
-
-	// If we got a JWT response, decode it
-	var tokenResp PaymentTokenResponse
-	if err := c.decodeJWTTokenForJSON(jwtResponse.Payload, &tokenResp); err != nil {
-		return nil, fmt.Errorf("decode jwt token: %w", err)
 	}
+	tokenResp.IdempotencyID = req.IdempotencyID
 
 	// Check response code
 	if tokenResp.IsSuccess() {
+		if tokenResp.PaymentToken == "" && tokenResp.WebPaymentURL == "" {
+			return &tokenResp, fmt.Errorf("%w: respCode %s (%s)", ErrEmptyPaymentToken, tokenResp.RespCode, tokenResp.RespDesc)
+		}
 		return &tokenResp, nil
 	}
-	return &tokenResp, fmt.Errorf("payment token failed: %s (%s)", tokenResp.RespCode, tokenResp.RespDesc)
+	return &tokenResp, &APIError{
+		Endpoint: "paymentToken",
+		RespCode: tokenResp.RespCode,
+		RespDesc: tokenResp.RespDesc,
+	}
+}
+
+// ChargeStoredCard charges a card token saved by an earlier payment, without the customer being
+// present (a merchant initiated transaction). It sets CardTokens, ImmediatePayment and
+// StoreCredentialsSubsequent on req; the outcome arrives like any payment, via the backend
+// notification or Inquire
+func (c *Client) ChargeStoredCard(ctx context.Context, token string, req *PaymentTokenRequest) (*PaymentTokenResponse, error) {
+	if strings.TrimSpace(token) == "" {
+		return nil, fmt.Errorf("charge stored card: card token is required")
+	}
+	req.CardTokens = []string{token}
+	req.ImmediatePayment = true
+	req.StoreCredentials = StoreCredentialsSubsequent
+	return c.PaymentToken(ctx, req)
+}
+
+// TokenizeCard saves the customer's card without charging it, by setting TokenizeOnly on req.
+// After the customer completes the payment page, Inquire the invoice for the saved
+// PaymentInquiryResponse.CustomerToken and CustomerTokenExpiry, to use with ChargeStoredCard
+func (c *Client) TokenizeCard(ctx context.Context, req *PaymentTokenRequest) (*PaymentTokenResponse, error) {
+	req.TokenizeOnly = true
+	return c.PaymentToken(ctx, req)
 }
 
 // PaymentTokenSubMerchant represents a sub-merchant for split payments
@@ -325,22 +612,24 @@ type PaymentTokenSubMerchant struct {
 	// Max length: 30 characters
 	InvoiceNo string `json:"invoiceNo"`
 
-	// Amount is the payment amount for this sub-merchant (required)
-	Amount float64 `json:"amount"`
+	// Amount is the payment amount for this sub-merchant, in the currency of the request (required)
+	// Format: 12 digits with 5 decimal places, like PaymentTokenRequest.AmountCents.
+	// Use CentsFromFloat64 to migrate from the former float64 amount
+	Amount Cents `json:"amount"`
 
 	// Description is the payment description for this sub-merchant (required)
 	// Max length: 250 characters
 	Description string `json:"description"`
 }
 
-// paymentTokenUiParams represents UI parameters for payment token requests
-type paymentTokenUiParams struct {
+// PaymentTokenUIParams represents UI parameters for payment token requests
+type PaymentTokenUIParams struct {
 	// UserInfo contains customer information for pre-filling payment forms
-	UserInfo *paymentTokenUserInfo `json:"userInfo,omitempty"`
+	UserInfo *PaymentTokenUserInfo `json:"userInfo,omitempty"`
 }
 
-// paymentTokenUserInfo represents user information for payment token requests
-type paymentTokenUserInfo struct {
+// PaymentTokenUserInfo represents user information for payment token requests
+type PaymentTokenUserInfo struct {
 	// Name is the customer's full name
 	Name string `json:"name"`
 
@@ -373,14 +662,45 @@ type PaymentTokenResponse struct {
 	// RespDesc is the response description
 	RespDesc string `json:"respDesc"`
 
-	// PaymentToken is the token to be used for payment
+	// PaymentToken is the token to be used for payment. It is set on every successful
+	// response, and is all that the QR, SecureFields and stored card flows need
 	PaymentToken string `json:"paymentToken"`
 
-	// WebPaymentURL is the URL to redirect customers for payment
+	// WebPaymentURL is the URL to redirect customers for payment in the redirect flow.
+	// No documented API returns it again for an existing token; if it is lost, create a new payment token
 	WebPaymentURL string `json:"webPaymentUrl"`
+
+	// IdempotencyID is the idempotency ID sent with the request, e.g. generated by AutoIdempotency
+	IdempotencyID string `json:"-"`
 }
 
 // IsSuccess returns true if the response code indicates success
 func (r *PaymentTokenResponse) IsSuccess() bool {
-	return r.RespCode == Code0000Successful
+	return r.RespCode.IsSuccess()
+}
+
+var (
+	iframeTemplate = template.Must(template.New("iframe").Parse(
+		`<iframe src="{{.URL}}" width="{{.Width}}" height="{{.Height}}" frameborder="0" ` +
+			`sandbox="allow-forms allow-scripts allow-same-origin allow-top-navigation allow-popups"></iframe>`))
+	redirectTemplate = template.Must(template.New("redirect").Parse(
+		`<meta http-equiv="refresh" content="0;url={{.}}"><a href="{{.}}">Continue to payment</a>`))
+)
+
+// IframeHTML returns a sandboxed iframe embedding WebPaymentURL, for requests with IframeMode
+func (r *PaymentTokenResponse) IframeHTML(width, height string) (template.HTML, error) {
+	var sb strings.Builder
+	if err := iframeTemplate.Execute(&sb, struct{ URL, Width, Height string }{r.WebPaymentURL, width, height}); err != nil {
+		return "", fmt.Errorf("render iframe: %w", err)
+	}
+	return template.HTML(sb.String()), nil
+}
+
+// RedirectHTML returns markup that sends the browser to WebPaymentURL, with a link as fallback
+func (r *PaymentTokenResponse) RedirectHTML() (template.HTML, error) {
+	var sb strings.Builder
+	if err := redirectTemplate.Execute(&sb, r.WebPaymentURL); err != nil {
+		return "", fmt.Errorf("render redirect: %w", err)
+	}
+	return template.HTML(sb.String()), nil
 }
@@ -0,0 +1,110 @@
+package api2c2p_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	api2c2p "github.com/choonkeat/2c2p"
+	"github.com/choonkeat/2c2p/api2c2ptest"
+	"github.com/choonkeat/2c2p/testutil"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestNewPaymentTokenRequest(t *testing.T) {
+	var gotRequest bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotRequest = true
+
+		// Verify request using testutil
+		testutil.AssertRequest(t, r, struct {
+			Method      string
+			URL         string
+			ContentType string
+			Headers     map[string]string
+			Body        any
+		}{
+			Method:      "POST",
+			URL:         "/payment/4.3/paymentToken",
+			ContentType: "application/json",
+			Body: map[string]any{
+				"merchantID":     "JT01",
+				"invoiceNo":      "INV123",
+				"description":    "Test payment",
+				"amount":         "000000000100.50000",
+				"currencyCode":   "SGD",
+				"request3DS":     "Y",
+				"paymentChannel": []string{"CC"},
+			},
+		})
+
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"respCode":     "0000",
+			"respDesc":     "Success",
+			"paymentToken": "token123",
+		}).SignedString([]byte("your_secret_key"))
+		if err != nil {
+			t.Errorf("Failed to sign response: %v", err)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"payload": token})
+	}))
+	defer ts.Close()
+
+	// no PEM files are read
+	client, err := api2c2ptest.NewMockClient("your_secret_key", "JT01", ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	req := &api2c2p.PaymentTokenRequest{
+		MerchantID:          "JT01",
+		InvoiceNo:           "INV123",
+		Description:         "Test payment",
+		AmountCents:         10050,
+		Request3DS:          "Y",
+		CurrencyCodeISO4217: "SGD",
+		PaymentChannel:      []api2c2p.PaymentTokenPaymentChannel{"CC"},
+	}
+
+	resp, err := client.PaymentToken(context.Background(), req)
+	if err != nil {
+		t.Fatalf("PaymentToken failed: %v", err)
+	}
+	if !gotRequest {
+		t.Fatal("Expected request to reach the mock server")
+	}
+	if resp.PaymentToken != "token123" {
+		t.Errorf("Expected payment token token123, got %s", resp.PaymentToken)
+	}
+}
+
+func TestPaymentTokenRequestWithUserInfo(t *testing.T) {
+	client, err := api2c2ptest.NewMockClient("your_secret_key", "JT01", "http://localhost")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	req := (&api2c2p.PaymentTokenRequest{
+		InvoiceNo:           "INV123",
+		Description:         "Test payment",
+		AmountCents:         10050,
+		CurrencyCodeISO4217: "SGD",
+	}).WithUserInfo(api2c2p.PaymentTokenUserInfo{
+		Name:     "John Doe",
+		Email:    "john@example.com",
+		MobileNo: "91234567",
+	})
+
+	prepared, err := client.InspectPaymentToken(context.Background(), req)
+	if err != nil {
+		t.Fatalf("InspectPaymentToken failed: %v", err)
+	}
+	uiParams, _ := prepared.Claims["uiParams"].(map[string]any)
+	userInfo, _ := uiParams["userInfo"].(map[string]any)
+	for key, want := range map[string]string{"name": "John Doe", "email": "john@example.com", "mobileNo": "91234567"} {
+		if userInfo[key] != want {
+			t.Errorf("Expected uiParams.userInfo.%s %s, got %v", key, want, userInfo[key])
+		}
+	}
+}
@@ -2,11 +2,17 @@ package api2c2p
 
 import (
 	"encoding/json"
+	"errors"
+	"html/template"
+	"math"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
-	"github.com/choonkeat/2c2p/testutil"
+	"github.com/google/uuid"
 )
 
 func TestPaymentTokenRequest_SignatureString(t *testing.T) {
@@ -50,8 +56,8 @@ func TestPaymentTokenRequest_SignatureString(t *testing.T) {
 		UserDefined5:                  "user5",
 		StatementDescriptor:           "Test Payment",
 	}
-	req.UIParams = &paymentTokenUiParams{
-		UserInfo: &paymentTokenUserInfo{
+	req.UIParams = &PaymentTokenUIParams{
+		UserInfo: &PaymentTokenUserInfo{
 			Name:                "John Doe",
 			Email:               "john@example.com",
 			MobileNo:            "0123456789",
@@ -115,8 +121,8 @@ func TestPaymentTokenRequest_SignatureString_Simple(t *testing.T) {
 		Description:         "Test payment",
 		AmountCents:         9910,
 		CurrencyCodeISO4217: "702",
-		UIParams: &paymentTokenUiParams{
-			UserInfo: &paymentTokenUserInfo{
+		UIParams: &PaymentTokenUIParams{
+			UserInfo: &PaymentTokenUserInfo{
 				Name:                "John Doe",
 				Email:               "john@example.com",
 				MobileNo:            "0123456789",
@@ -155,58 +161,6 @@ func TestPaymentTokenRequest_SignatureString_Simple(t *testing.T) {
 
 }
 
-func TestNewPaymentTokenRequest(t *testing.T) {
-	client, err := NewClient(Config{
-		SecretKey:                "your_secret_key",
-		MerchantID:               "JT01",
-		PaymentGatewayURL:        "https://pgw.example.com",
-		FrontendURL:              "https://frontend.example.com",
-		CombinedPEM:              "testdata/combined_private_public.pem",
-		ServerJWTPublicKeyFile:   "testdata/server.jwt.public_cert.pem",
-		ServerPKCS7PublicKeyFile: "testdata/server.pkcs7.public_cert.pem",
-	})
-	if err != nil {
-		t.Fatalf("Failed to create client: %v", err)
-	}
-	req := &PaymentTokenRequest{
-		MerchantID:          "JT01",
-		InvoiceNo:           "INV123",
-		Description:         "Test payment",
-		AmountCents:         10050,
-		Request3DS:          "Y",
-		CurrencyCodeISO4217: "SGD",
-		PaymentChannel:      []PaymentTokenPaymentChannel{"CC"},
-	}
-
-	// Create request
-	httpReq, err := client.newPaymentTokenRequest(ctx, req)
-	if err != nil {
-		t.Fatalf("Failed to create request: %v", err)
-	}
-
-	// Verify request using testutil
-	testutil.AssertRequest(t, httpReq, struct {
-		Method      string
-		URL         string
-		ContentType string
-		Headers     map[string]string
-		Body        any
-	}{
-		Method:      "POST",
-		URL:         "https://pgw.example.com/payment/4.3/paymentToken",
-		ContentType: "application/json",
-		Body: map[string]any{
-			"merchantID":     "JT01",
-			"invoiceNo":      "INV123",
-			"description":    "Test payment",
-			"amount":         "000000000100.50000",
-			"currencyCode":   "SGD",
-			"request3DS":     "Y",
-			"paymentChannel": []string{"CC"},
-		},
-	})
-}
-
 func TestCentsJSON(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -285,6 +239,13 @@ func TestCentsUnmarshalJSONErrors(t *testing.T) {
 			json:    `not_json`,
 			wantErr: "invalid character",
 		},
+		{name: "one decimal place", json: `"12.3"`, wantErr: "expected 5 decimal places"},
+		{name: "no decimal places", json: `"12."`, wantErr: "expected 5 decimal places"},
+		{name: "no whole part", json: `".50000"`, wantErr: "strconv.ParseInt"},
+		{name: "not a number", json: `"abc"`, wantErr: "invalid format"},
+		{name: "signed decimal places", json: `"12.+1234"`, wantErr: "not digits"},
+		{name: "huge number", json: `"99999999999999999999.00000"`, wantErr: "strconv.ParseInt"},
+		{name: "overflowing cents", json: `"92233720368547759.00000"`, wantErr: "out of range"},
 	}
 
 	for _, tc := range testCases {
@@ -301,3 +262,935 @@ func TestCentsUnmarshalJSONErrors(t *testing.T) {
 		})
 	}
 }
+
+func FuzzCentsUnmarshalJSON(f *testing.F) {
+	for _, seed := range []string{"000000000012.34000", "12.3", "12.", ".5", "abc", "-0.50000", "99999999999999999999.00000"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		data, err := json.Marshal(s)
+		if err != nil {
+			return
+		}
+		var c Cents
+		if err := json.Unmarshal(data, &c); err != nil || c < 0 {
+			return
+		}
+		// what decodes must survive a round trip
+		encoded, err := json.Marshal(c)
+		if err != nil {
+			t.Fatalf("MarshalJSON(%d) failed: %v", c, err)
+		}
+		var again Cents
+		if err := json.Unmarshal(encoded, &again); err != nil || again != c {
+			t.Errorf("Expected %s to round trip %d, got %d (%v)", encoded, c, again, err)
+		}
+	})
+}
+
+func TestCentsCheckedZeroPrefixed12DCents(t *testing.T) {
+	testCases := []struct {
+		cents   Cents
+		want    string
+		wantErr bool
+	}{
+		{cents: 0, want: "000000000000"},
+		{cents: 1234, want: "000000001234"},
+		{cents: MaxZeroPrefixed12DCents, want: "999999999999"},
+		{cents: MaxZeroPrefixed12DCents + 1, wantErr: true},
+		{cents: -1, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		got, err := tc.cents.CheckedZeroPrefixed12DCents()
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("Expected error for %d cents, got %s", tc.cents, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Expected no error for %d cents, got %v", tc.cents, err)
+		}
+		if got != tc.want {
+			t.Errorf("Expected %s, got %s", tc.want, got)
+		}
+	}
+}
+
+func TestPaymentTokenAutoIdempotency(t *testing.T) {
+	var client *Client
+	var sentIdempotencyID string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Payload string `json:"payload"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+			return
+		}
+		var req PaymentTokenRequest
+		if err := client.decodeJWTTokenForJSON(body.Payload, &req); err != nil {
+			t.Errorf("Failed to decode request payload: %v", err)
+			return
+		}
+		sentIdempotencyID = req.IdempotencyID
+
+		token, err := client.generateJWTTokenForJSON([]byte(`{"respCode":"0000","respDesc":"Success","paymentToken":"token123"}`))
+		if err != nil {
+			t.Errorf("Failed to sign response: %v", err)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"payload": token})
+	}))
+	defer ts.Close()
+
+	var err error
+	cfg := testConfig()
+	cfg.PaymentGatewayURL = ts.URL
+	cfg.AutoIdempotency = true
+	client, err = NewClient(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	resp, err := client.PaymentToken(ctx, &PaymentTokenRequest{InvoiceNo: "INV123", AmountCents: 100})
+	if err != nil {
+		t.Fatalf("PaymentToken failed: %v", err)
+	}
+	if _, err := uuid.Parse(sentIdempotencyID); err != nil {
+		t.Errorf("Expected a UUID idempotencyID to be sent, got %q", sentIdempotencyID)
+	}
+	if resp.IdempotencyID != sentIdempotencyID {
+		t.Errorf("Expected response idempotencyID %q, got %q", sentIdempotencyID, resp.IdempotencyID)
+	}
+
+	// the generated ID is not written back, so reusing the request generates a new one
+	req := &PaymentTokenRequest{InvoiceNo: "INV123", AmountCents: 100}
+	if _, err := client.PaymentToken(ctx, req); err != nil {
+		t.Fatalf("PaymentToken failed: %v", err)
+	}
+	firstIdempotencyID := sentIdempotencyID
+	if req.IdempotencyID != "" {
+		t.Errorf("Expected request idempotencyID to be unchanged, got %q", req.IdempotencyID)
+	}
+	if _, err := client.PaymentToken(ctx, req); err != nil {
+		t.Fatalf("PaymentToken failed: %v", err)
+	}
+	if sentIdempotencyID == firstIdempotencyID {
+		t.Errorf("Expected a new idempotencyID for a reused request, got %q twice", sentIdempotencyID)
+	}
+
+	// a caller supplied ID is kept
+	resp, err = client.PaymentToken(ctx, &PaymentTokenRequest{InvoiceNo: "INV124", AmountCents: 100, IdempotencyID: "retry-1"})
+	if err != nil {
+		t.Fatalf("PaymentToken failed: %v", err)
+	}
+	if sentIdempotencyID != "retry-1" || resp.IdempotencyID != "retry-1" {
+		t.Errorf("Expected idempotencyID retry-1 to be kept, sent %q, returned %q", sentIdempotencyID, resp.IdempotencyID)
+	}
+
+	// without AutoIdempotency nothing is generated
+	client.AutoIdempotency = false
+	resp, err = client.PaymentToken(ctx, &PaymentTokenRequest{InvoiceNo: "INV125", AmountCents: 100})
+	if err != nil {
+		t.Fatalf("PaymentToken failed: %v", err)
+	}
+	if sentIdempotencyID != "" || resp.IdempotencyID != "" {
+		t.Errorf("Expected no idempotencyID, sent %q, returned %q", sentIdempotencyID, resp.IdempotencyID)
+	}
+}
+
+func TestPaymentTokenAutoNonce(t *testing.T) {
+	var client *Client
+	var sentNonce string
+	client = NewTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Payload string `json:"payload"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+			return
+		}
+		var req PaymentTokenRequest
+		if err := client.decodeJWTTokenForJSON(body.Payload, &req); err != nil {
+			t.Errorf("Failed to decode request payload: %v", err)
+			return
+		}
+		sentNonce = req.NonceStr
+
+		token, err := client.generateJWTTokenForJSON([]byte(`{"respCode":"0000","respDesc":"Success","paymentToken":"token123"}`))
+		if err != nil {
+			t.Errorf("Failed to sign response: %v", err)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"payload": token})
+	})
+	client.AutoNonce = true
+
+	req := &PaymentTokenRequest{InvoiceNo: "INV123", AmountCents: 100}
+	if _, err := client.PaymentToken(ctx, req); err != nil {
+		t.Fatalf("PaymentToken failed: %v", err)
+	}
+	if len(sentNonce) != 32 {
+		t.Errorf("Expected a 32 character nonceStr to be sent, got %q", sentNonce)
+	}
+	if req.NonceStr != sentNonce {
+		t.Errorf("Expected request nonceStr %q to be the one sent, got %q", sentNonce, req.NonceStr)
+	}
+
+	// the nonce of a request is kept, e.g. when inspected before sending
+	firstNonce := sentNonce
+	prepared, err := client.InspectPaymentToken(ctx, req)
+	if err != nil {
+		t.Fatalf("InspectPaymentToken failed: %v", err)
+	}
+	if prepared.Claims["nonceStr"] != firstNonce {
+		t.Errorf("Expected nonceStr %q to be kept, got %v", firstNonce, prepared.Claims["nonceStr"])
+	}
+
+	// every new request gets its own
+	if _, err := client.PaymentToken(ctx, &PaymentTokenRequest{InvoiceNo: "INV124", AmountCents: 100}); err != nil {
+		t.Fatalf("PaymentToken failed: %v", err)
+	}
+	if sentNonce == firstNonce || sentNonce == "" {
+		t.Errorf("Expected a new nonceStr, got %q after %q", sentNonce, firstNonce)
+	}
+
+	// without AutoNonce nothing is generated
+	client.AutoNonce = false
+	if _, err := client.PaymentToken(ctx, &PaymentTokenRequest{InvoiceNo: "INV125", AmountCents: 100}); err != nil {
+		t.Fatalf("PaymentToken failed: %v", err)
+	}
+	if sentNonce != "" {
+		t.Errorf("Expected no nonceStr, got %q", sentNonce)
+	}
+}
+
+func TestPaymentTokenResponseHTML(t *testing.T) {
+	resp := &PaymentTokenResponse{
+		WebPaymentURL: `https://sandbox-pgw-ui.2c2p.com/payment/4.1/#/token/abc?x=1&y="2"`,
+	}
+	escapedURL := `https://sandbox-pgw-ui.2c2p.com/payment/4.1/#/token/abc?x=1&amp;y=%222%22`
+
+	iframe, err := resp.IframeHTML("100%", "600")
+	if err != nil {
+		t.Fatalf("Failed to render iframe: %v", err)
+	}
+	for _, want := range []string{
+		`<iframe src="` + escapedURL + `"`,
+		`width="100%"`,
+		`height="600"`,
+		`sandbox="allow-forms allow-scripts allow-same-origin allow-top-navigation allow-popups"`,
+		`</iframe>`,
+	} {
+		if !strings.Contains(string(iframe), want) {
+			t.Errorf("Expected iframe HTML to contain %q, got %s", want, iframe)
+		}
+	}
+
+	redirect, err := resp.RedirectHTML()
+	if err != nil {
+		t.Fatalf("Failed to render redirect: %v", err)
+	}
+	for _, want := range []string{
+		`<meta http-equiv="refresh" content="0;url=`,
+		`<a href="` + escapedURL + `">`,
+	} {
+		if !strings.Contains(string(redirect), want) {
+			t.Errorf("Expected redirect HTML to contain %q, got %s", want, redirect)
+		}
+	}
+
+	// unsafe URLs are neutralized
+	resp.WebPaymentURL = "javascript:alert(1)"
+	if iframe, _ := resp.IframeHTML("100%", "600"); strings.Contains(string(iframe), "javascript:") {
+		t.Errorf("Expected javascript URL to be escaped, got %s", iframe)
+	}
+	if redirect, _ := resp.RedirectHTML(); strings.Contains(string(redirect), "javascript:") {
+		t.Errorf("Expected javascript URL to be escaped, got %s", redirect)
+	}
+}
+
+func TestPaymentTokenResponseHTMLError(t *testing.T) {
+	defer func(iframe, redirect *template.Template) {
+		iframeTemplate, redirectTemplate = iframe, redirect
+	}(iframeTemplate, redirectTemplate)
+	// writes markup before failing on a field that does not exist
+	iframeTemplate = template.Must(template.New("iframe").Parse(`<iframe src="{{.URL}}" title="{{.Missing}}"></iframe>`))
+	redirectTemplate = template.Must(template.New("redirect").Parse(`<a href="{{.}}">{{.Missing}}</a>`))
+
+	resp := &PaymentTokenResponse{WebPaymentURL: "https://example.com"}
+	if iframe, err := resp.IframeHTML("100%", "600"); err == nil || iframe != "" {
+		t.Errorf("Expected error and no HTML, got %q, %v", iframe, err)
+	}
+	if redirect, err := resp.RedirectHTML(); err == nil || redirect != "" {
+		t.Errorf("Expected error and no HTML, got %q, %v", redirect, err)
+	}
+}
+
+func TestPaymentTokenRequestPaymentExpiryAt(t *testing.T) {
+	bangkok := time.FixedZone("ICT", 7*60*60)
+	expiry := time.Date(2025, 2, 4, 23, 59, 59, 0, bangkok)
+
+	testCases := []struct {
+		name string
+		req  PaymentTokenRequest
+		want string // "" means omitted
+	}{
+		{
+			name: "string field",
+			req:  PaymentTokenRequest{PaymentExpiryYYYYMMDDHHMMSS: "2025-02-04 23:59:59"},
+			want: "2025-02-04 23:59:59",
+		},
+		{
+			name: "time field keeps its timezone",
+			req:  PaymentTokenRequest{PaymentExpiryAt: expiry},
+			want: "2025-02-04 23:59:59",
+		},
+		{
+			name: "time field converted to UTC",
+			req:  PaymentTokenRequest{PaymentExpiryAt: expiry.UTC()},
+			want: "2025-02-04 16:59:59",
+		},
+		{
+			name: "time field overrides string field",
+			req:  PaymentTokenRequest{PaymentExpiryYYYYMMDDHHMMSS: "2000-01-01 00:00:00", PaymentExpiryAt: expiry},
+			want: "2025-02-04 23:59:59",
+		},
+		{
+			name: "zero time is omitted",
+			req:  PaymentTokenRequest{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, v := range []any{tc.req, &tc.req} {
+				jsonBytes, err := json.Marshal(v)
+				if err != nil {
+					t.Fatalf("Failed to marshal request: %v", err)
+				}
+				var got map[string]any
+				if err := json.Unmarshal(jsonBytes, &got); err != nil {
+					t.Fatalf("Failed to unmarshal request: %v", err)
+				}
+				paymentExpiry, ok := got["paymentExpiry"]
+				if tc.want == "" {
+					if ok {
+						t.Errorf("Expected paymentExpiry to be omitted, got %v", paymentExpiry)
+					}
+					continue
+				}
+				if paymentExpiry != tc.want {
+					t.Errorf("Expected paymentExpiry %q, got %v", tc.want, paymentExpiry)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatChargeDate(t *testing.T) {
+	chargeDate := time.Date(2025, 2, 1, 23, 30, 0, 0, time.FixedZone("SGT", 8*60*60))
+	if got := FormatChargeDate(chargeDate); got != "20250201" {
+		t.Errorf("Expected 20250201, got %s", got)
+	}
+	if got := FormatChargeDate(chargeDate.UTC()); got != "20250201" {
+		t.Errorf("Expected 20250201 in UTC, got %s", got)
+	}
+	if got := FormatChargeDate(chargeDate.Add(time.Hour)); got != "20250202" {
+		t.Errorf("Expected 20250202, got %s", got)
+	}
+}
+
+func TestPaymentTokenRequestValidateRecurring(t *testing.T) {
+	valid := func() PaymentTokenRequest {
+		return PaymentTokenRequest{
+			Recurring:              true,
+			RecurringAmount:        100.00,
+			RecurringCount:         12,
+			RecurringIntervalDays:  30,
+			ChargeNextDateYYYYMMDD: "20250201",
+			AllowAccumulate:        true,
+			MaxAccumulateAmount:    1000.00,
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		modify   func(r *PaymentTokenRequest)
+		wantErrs []string
+	}{
+		{name: "valid", modify: func(r *PaymentTokenRequest) {}},
+		{name: "not recurring", modify: func(r *PaymentTokenRequest) { *r = PaymentTokenRequest{} }},
+		{
+			name:     "missing recurring amount",
+			modify:   func(r *PaymentTokenRequest) { r.RecurringAmount = 0 },
+			wantErrs: []string{"recurringAmount is required for recurring payments"},
+		},
+		{
+			name:     "missing recurring count",
+			modify:   func(r *PaymentTokenRequest) { r.RecurringCount = 0 },
+			wantErrs: []string{"recurringCount is required for recurring payments"},
+		},
+		{
+			name:     "missing recurring interval",
+			modify:   func(r *PaymentTokenRequest) { r.RecurringIntervalDays = 0 },
+			wantErrs: []string{"recurringInterval is required for recurring payments"},
+		},
+		{
+			name:     "accumulate without max",
+			modify:   func(r *PaymentTokenRequest) { r.MaxAccumulateAmount = 0 },
+			wantErrs: []string{"maxAccumulateAmount is required when allowAccumulate is set"},
+		},
+		{
+			name:     "charge next date format",
+			modify:   func(r *PaymentTokenRequest) { r.ChargeNextDateYYYYMMDD = "2025-02-01" },
+			wantErrs: []string{`chargeNextDate "2025-02-01" is not in YYYYMMDD format`},
+		},
+		{
+			name:     "charge on date format",
+			modify:   func(r *PaymentTokenRequest) { r.ChargeOnDateYYYYMMDD = "20251301" },
+			wantErrs: []string{`chargeOnDate "20251301" is not in YYYYMMDD format`},
+		},
+		{
+			name: "every problem is listed",
+			modify: func(r *PaymentTokenRequest) {
+				*r = PaymentTokenRequest{Recurring: true, AllowAccumulate: true, ChargeNextDateYYYYMMDD: "tomorrow"}
+			},
+			wantErrs: []string{
+				"recurringAmount is required for recurring payments",
+				"recurringCount is required for recurring payments",
+				"recurringInterval is required for recurring payments",
+				"maxAccumulateAmount is required when allowAccumulate is set",
+				`chargeNextDate "tomorrow" is not in YYYYMMDD format`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := valid()
+			tc.modify(&req)
+			err := req.Validate()
+			if len(tc.wantErrs) == 0 {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Expected errors %q, got nil", tc.wantErrs)
+			}
+			if got := strings.Split(err.Error(), "\n"); !reflect.DeepEqual(got, tc.wantErrs) {
+				t.Errorf("Expected errors %q, got %q", tc.wantErrs, got)
+			}
+		})
+	}
+}
+
+func TestPaymentTokenRequestValidateChannels(t *testing.T) {
+	testCases := []struct {
+		name     string
+		req      PaymentTokenRequest
+		wantErrs []string
+	}{
+		{
+			name: "known channels",
+			req: PaymentTokenRequest{
+				PaymentChannel: []PaymentTokenPaymentChannel{PaymentChannelCC, PaymentChannelQR},
+				AgentChannel:   []string{AgentChannelATM, AgentChannelKiosk, AgentChannelWebPay},
+			},
+		},
+		{
+			name: "unknown channels",
+			req: PaymentTokenRequest{
+				PaymentChannel: []PaymentTokenPaymentChannel{"CreditCard"},
+				AgentChannel:   []string{"atm"},
+			},
+			wantErrs: []string{
+				`unknown paymentChannel "CreditCard", expected one of [CC IPP APM QR]`,
+				`unknown agentChannel "atm", expected one of [ATM BANKCOUNTER KIOSK IBANKING MOBILEBANKING OVERTHECOUNTER WEBPAY]`,
+			},
+		},
+		{
+			name: "unknown channels allowed",
+			req: PaymentTokenRequest{
+				PaymentChannel:       []PaymentTokenPaymentChannel{"NEWPAY"},
+				AgentChannel:         []string{"NEWAGENT"},
+				AllowUnknownChannels: true,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.req.Validate()
+			if len(tc.wantErrs) == 0 {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Expected errors %q, got nil", tc.wantErrs)
+			}
+			if got := strings.Split(err.Error(), "\n"); !reflect.DeepEqual(got, tc.wantErrs) {
+				t.Errorf("Expected errors %q, got %q", tc.wantErrs, got)
+			}
+		})
+	}
+}
+
+func TestPaymentTokenDirectErrorResponse(t *testing.T) {
+	client := NewTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		// unsigned, without payload
+		w.Write([]byte(`{"respCode":"9042","respDesc":"Hash value mismatch"}`))
+	})
+
+	resp, err := client.PaymentToken(ctx, &PaymentTokenRequest{InvoiceNo: "INV123", AmountCents: 100})
+	if !IsResponseCode(err, "9042") {
+		t.Fatalf("Expected APIError with respCode 9042, got %v", err)
+	}
+	if resp == nil || resp.RespDesc != "Hash value mismatch" {
+		t.Errorf("Expected response with respDesc, got %+v", resp)
+	}
+}
+
+func TestPaymentTokenReturnURLs(t *testing.T) {
+	client := NewTestClient(t, nil)
+	prepared, err := client.InspectPaymentToken(ctx, &PaymentTokenRequest{
+		InvoiceNo:         "INV123",
+		AmountCents:       100,
+		FrontendReturnURL: "https://merchant.example.com/payment-return",
+		BackendReturnURL:  "https://merchant.example.com/payment-notify",
+	})
+	if err != nil {
+		t.Fatalf("InspectPaymentToken failed: %v", err)
+	}
+	if got := prepared.Claims["frontendReturnUrl"]; got != "https://merchant.example.com/payment-return" {
+		t.Errorf("Expected frontendReturnUrl https://merchant.example.com/payment-return, got %v", got)
+	}
+	if got := prepared.Claims["backendReturnUrl"]; got != "https://merchant.example.com/payment-notify" {
+		t.Errorf("Expected backendReturnUrl https://merchant.example.com/payment-notify, got %v", got)
+	}
+}
+
+func TestPaymentTokenSuccessWithoutToken(t *testing.T) {
+	var client *Client
+	client = NewTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		token, err := client.generateJWTTokenForJSON([]byte(`{"respCode":"0000","respDesc":"Success","paymentToken":"","webPaymentUrl":""}`))
+		if err != nil {
+			t.Errorf("Failed to sign response: %v", err)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"payload": token})
+	})
+
+	resp, err := client.PaymentToken(ctx, &PaymentTokenRequest{InvoiceNo: "INV123", AmountCents: 100})
+	if !errors.Is(err, ErrEmptyPaymentToken) {
+		t.Fatalf("Expected ErrEmptyPaymentToken, got %v", err)
+	}
+	if resp == nil || resp.RespCode != "0000" {
+		t.Errorf("Expected response with respCode 0000, got %+v", resp)
+	}
+}
+
+func TestPaymentTokenRequestFieldMap(t *testing.T) {
+	typ := reflect.TypeOf(PaymentTokenRequest{})
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag, ok := field.Tag.Lookup("json")
+		if !field.IsExported() || !ok || tag == "-" {
+			continue
+		}
+		key, opts, _ := strings.Cut(tag, ",")
+		omitempty := strings.Contains(opts, "omitempty")
+
+		t.Run(field.Name, func(t *testing.T) {
+			var req PaymentTokenRequest
+			if _, found := req.FieldMap()[key]; found == omitempty {
+				t.Errorf("Expected zero value present in map to be %v, got %v", !omitempty, found)
+			}
+
+			value := reflect.ValueOf(&req).Elem().Field(i)
+			switch value.Kind() {
+			case reflect.Pointer:
+				value.Set(reflect.New(field.Type.Elem()))
+			case reflect.Slice:
+				value.Set(reflect.MakeSlice(field.Type, 1, 1))
+			case reflect.String:
+				value.SetString("x")
+			case reflect.Bool:
+				value.SetBool(true)
+			case reflect.Int, reflect.Int64:
+				value.SetInt(1)
+			case reflect.Float64:
+				value.SetFloat(1)
+			default:
+				t.Fatalf("Unhandled kind %s, extend this test", value.Kind())
+			}
+			if _, found := req.FieldMap()[key]; !found {
+				t.Errorf("Expected key %q in map, got %v", key, req.FieldMap())
+			}
+		})
+	}
+}
+
+func TestPaymentTokenRequestFieldMapValues(t *testing.T) {
+	req := PaymentTokenRequest{
+		MerchantID:      "JT01",
+		AmountCents:     1234,
+		PaymentExpiryAt: time.Date(2025, 2, 4, 23, 59, 59, 0, time.UTC),
+		RecurringCount:  12,
+	}
+	want := map[string]any{
+		"merchantID":     "JT01",
+		"invoiceNo":      "",
+		"description":    "",
+		"amount":         "000000000012.34000",
+		"currencyCode":   "",
+		"paymentExpiry":  "2025-02-04 23:59:59",
+		"recurringCount": json.Number("12"),
+	}
+	if got := req.FieldMap(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestPaymentTokenRequestJSONOptionalFields(t *testing.T) {
+	req := PaymentTokenRequest{
+		NonceStr: "0123456789abcdef0123456789abcdef",
+		Locale:   "th",
+		UIParams: &PaymentTokenUIParams{
+			UserInfo: &PaymentTokenUserInfo{Name: "John Doe", Email: "john@example.com"},
+		},
+	}
+	fields := req.FieldMap()
+	if fields["nonceStr"] != "0123456789abcdef0123456789abcdef" {
+		t.Errorf("Expected nonceStr 0123456789abcdef0123456789abcdef, got %v", fields["nonceStr"])
+	}
+	if fields["locale"] != "th" {
+		t.Errorf("Expected locale th, got %v", fields["locale"])
+	}
+	uiParams, _ := fields["uiParams"].(map[string]any)
+	userInfo, _ := uiParams["userInfo"].(map[string]any)
+	if userInfo["name"] != "John Doe" || userInfo["email"] != "john@example.com" {
+		t.Errorf("Expected uiParams.userInfo with name and email, got %v", fields["uiParams"])
+	}
+
+	fields = PaymentTokenRequest{}.FieldMap()
+	for _, key := range []string{"nonceStr", "locale", "uiParams"} {
+		if _, found := fields[key]; found {
+			t.Errorf("Expected %s to be omitted when empty, got %v", key, fields[key])
+		}
+	}
+}
+
+func TestPaymentTokenRequestWithLoyaltyPoints(t *testing.T) {
+	req := (&PaymentTokenRequest{AmountCents: 10000}).WithLoyaltyPoints(10.5)
+
+	jsonBytes, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("Failed to marshal request: %v", err)
+	}
+	var got struct {
+		LoyaltyPoints struct {
+			RedeemAmount float64 `json:"redeemAmount"`
+		} `json:"loyaltyPoints"`
+	}
+	if err := json.Unmarshal(jsonBytes, &got); err != nil {
+		t.Fatalf("Failed to unmarshal request: %v", err)
+	}
+	if got.LoyaltyPoints.RedeemAmount != 10.5 {
+		t.Errorf("Expected loyaltyPoints.redeemAmount 10.5, got %s", jsonBytes)
+	}
+
+	testCases := []struct {
+		name         string
+		redeemAmount float64
+		wantErr      string
+	}{
+		{name: "partial", redeemAmount: 10.5},
+		{name: "whole amount", redeemAmount: 100},
+		{name: "zero", redeemAmount: 0, wantErr: "loyaltyPoints.redeemAmount must be positive, got 0"},
+		{name: "negative", redeemAmount: -1, wantErr: "loyaltyPoints.redeemAmount must be positive, got -1"},
+		{name: "more than amount", redeemAmount: 100.01, wantErr: "loyaltyPoints.redeemAmount 100.01 exceeds amount 100.00"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := (&PaymentTokenRequest{AmountCents: 10000}).WithLoyaltyPoints(tc.redeemAmount).Validate()
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.wantErr {
+				t.Errorf("Expected error %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestPaymentTokenRequestValidateFX(t *testing.T) {
+	testCases := []struct {
+		name    string
+		req     PaymentTokenRequest
+		wantErr string
+	}{
+		{name: "no fx"},
+		{
+			name: "all fx fields",
+			req:  PaymentTokenRequest{FxProviderCode: "fx1", FXRateID: "rate1", OriginalAmount: 10.5},
+		},
+		{
+			name:    "provider only",
+			req:     PaymentTokenRequest{FxProviderCode: "fx1"},
+			wantErr: "fxRateID and originalAmount required when fxProviderCode set",
+		},
+		{
+			name:    "missing original amount",
+			req:     PaymentTokenRequest{FxProviderCode: "fx1", FXRateID: "rate1"},
+			wantErr: "originalAmount required when fxProviderCode and fxRateID set",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.req.Validate()
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.wantErr {
+				t.Errorf("Expected error %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestPaymentTokenRequestValidateStoreCredentials(t *testing.T) {
+	for _, value := range []StoreCredentials{"", StoreCredentialsFirst, StoreCredentialsSubsequent, StoreCredentialsNo} {
+		req := PaymentTokenRequest{StoreCredentials: value}
+		if err := req.Validate(); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", value, err)
+		}
+	}
+
+	req := PaymentTokenRequest{StoreCredentials: "Y"}
+	want := `unknown storeCredentials "Y", expected one of [F S N]`
+	if err := req.Validate(); err == nil || err.Error() != want {
+		t.Errorf("Expected error %q, got %v", want, err)
+	}
+}
+
+func TestChargeStoredCard(t *testing.T) {
+	var client *Client
+	var sent map[string]any
+	client = NewTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Payload string `json:"payload"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+			return
+		}
+		if err := client.decodeJWTTokenForJSON(body.Payload, &sent); err != nil {
+			t.Errorf("Failed to decode request payload: %v", err)
+			return
+		}
+
+		token, err := client.generateJWTTokenForJSON([]byte(`{"respCode":"0000","respDesc":"Success","paymentToken":"token123"}`))
+		if err != nil {
+			t.Errorf("Failed to sign response: %v", err)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"payload": token})
+	})
+
+	resp, err := client.ChargeStoredCard(ctx, "card-token-1", &PaymentTokenRequest{InvoiceNo: "INV123", AmountCents: 100})
+	if err != nil {
+		t.Fatalf("ChargeStoredCard failed: %v", err)
+	}
+	if resp.PaymentToken != "token123" {
+		t.Errorf("Expected paymentToken token123, got %s", resp.PaymentToken)
+	}
+	if sent["immediatePayment"] != true {
+		t.Errorf("Expected immediatePayment true, got %v", sent["immediatePayment"])
+	}
+	if !reflect.DeepEqual(sent["cardTokens"], []any{"card-token-1"}) {
+		t.Errorf("Expected cardTokens [card-token-1], got %v", sent["cardTokens"])
+	}
+	if sent["storeCredentials"] != "S" {
+		t.Errorf("Expected storeCredentials S, got %v", sent["storeCredentials"])
+	}
+
+	sent = nil
+	if _, err := client.ChargeStoredCard(ctx, " ", &PaymentTokenRequest{InvoiceNo: "INV124", AmountCents: 100}); err == nil {
+		t.Error("Expected an error without a card token")
+	}
+	if sent != nil {
+		t.Errorf("Expected no request without a card token, got %v", sent)
+	}
+}
+
+func TestTokenizeCard(t *testing.T) {
+	var client *Client
+	var sent map[string]any
+	client = NewTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Payload string `json:"payload"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+			return
+		}
+		var respJSON string
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/paymentToken"):
+			if err := client.decodeJWTTokenForJSON(body.Payload, &sent); err != nil {
+				t.Errorf("Failed to decode request payload: %v", err)
+				return
+			}
+			respJSON = `{"respCode":"0000","respDesc":"Success","paymentToken":"token123"}`
+		case strings.HasSuffix(r.URL.Path, "/paymentInquiry"):
+			respJSON = `{"respCode":"0000","respDesc":"Success","invoiceNo":"INV123","customerToken":"20012345678901234567","customerTokenExpiry":"20301231"}`
+		default:
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+			return
+		}
+		token, err := client.generateJWTTokenForJSON([]byte(respJSON))
+		if err != nil {
+			t.Errorf("Failed to sign response: %v", err)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"payload": token})
+	})
+
+	if _, err := client.TokenizeCard(ctx, &PaymentTokenRequest{InvoiceNo: "INV123", AmountCents: 100}); err != nil {
+		t.Fatalf("TokenizeCard failed: %v", err)
+	}
+	if sent["tokenizeOnly"] != true {
+		t.Errorf("Expected tokenizeOnly true, got %v", sent["tokenizeOnly"])
+	}
+	if _, found := sent["immediatePayment"]; found {
+		t.Errorf("Expected no immediatePayment, got %v", sent["immediatePayment"])
+	}
+
+	inquiry, err := client.Inquire(ctx, InquiryQuery{InvoiceNo: "INV123"})
+	if err != nil {
+		t.Fatalf("Inquire failed: %v", err)
+	}
+	if inquiry.CustomerToken != "20012345678901234567" {
+		t.Errorf("Expected customerToken 20012345678901234567, got %s", inquiry.CustomerToken)
+	}
+	if inquiry.CustomerTokenExpiry != "20301231" {
+		t.Errorf("Expected customerTokenExpiry 20301231, got %s", inquiry.CustomerTokenExpiry)
+	}
+}
+
+func TestPaymentTokenSubMerchantAmountJSON(t *testing.T) {
+	req := PaymentTokenRequest{
+		AmountCents:       250090,
+		SubMerchantAmount: 250090,
+		SubMerchants:      []PaymentTokenSubMerchant{{MerchantID: "JT02", Amount: 250090}},
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("Failed to marshal request: %v", err)
+	}
+	var fields struct {
+		Amount            json.RawMessage `json:"amount"`
+		SubMerchantAmount json.RawMessage `json:"subMerchantAmount"`
+		SubMerchants      []struct {
+			Amount json.RawMessage `json:"amount"`
+		} `json:"subMerchants"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("Failed to unmarshal request: %v", err)
+	}
+	if string(fields.Amount) != `"000000002500.90000"` {
+		t.Errorf("Expected amount \"000000002500.90000\", got %s", fields.Amount)
+	}
+	if string(fields.SubMerchantAmount) != string(fields.Amount) {
+		t.Errorf("Expected subMerchantAmount %s, got %s", fields.Amount, fields.SubMerchantAmount)
+	}
+	if len(fields.SubMerchants) != 1 || string(fields.SubMerchants[0].Amount) != string(fields.Amount) {
+		t.Errorf("Expected subMerchants amount %s, got %+v", fields.Amount, fields.SubMerchants)
+	}
+}
+
+func TestPaymentTokenRequestValidateSubMerchants(t *testing.T) {
+	testCases := []struct {
+		name    string
+		req     PaymentTokenRequest
+		wantErr string
+	}{
+		{
+			name: "SGD cents",
+			req:  PaymentTokenRequest{CurrencyCodeISO4217: "SGD", SubMerchants: []PaymentTokenSubMerchant{{Amount: 10050}}},
+		},
+		{
+			name: "JPY whole yen",
+			req:  PaymentTokenRequest{CurrencyCodeISO4217: "JPY", SubMerchants: []PaymentTokenSubMerchant{{Amount: 10000}}},
+		},
+		{
+			name:    "JPY fraction",
+			req:     PaymentTokenRequest{CurrencyCodeISO4217: "JPY", SubMerchants: []PaymentTokenSubMerchant{{Amount: 10000}, {Amount: 10050}}},
+			wantErr: "subMerchants[1].amount: amount 100.50 has more than 0 decimal places for JPY",
+		},
+		{
+			name:    "JPY subMerchantAmount fraction",
+			req:     PaymentTokenRequest{CurrencyCodeISO4217: "JPY", SubMerchantAmount: 1},
+			wantErr: "subMerchantAmount: amount 0.01 has more than 0 decimal places for JPY",
+		},
+		{
+			name:    "zero amount",
+			req:     PaymentTokenRequest{CurrencyCodeISO4217: "SGD", SubMerchants: []PaymentTokenSubMerchant{{}}},
+			wantErr: "subMerchants[0].amount must be positive, got 0.00",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.req.Validate()
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.wantErr {
+				t.Errorf("Expected error %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCentsFromFloat64(t *testing.T) {
+	testCases := []struct {
+		amount float64
+		want   Cents
+	}{
+		{25.5, 2550},
+		{0.29, 29},
+		{1234567.89, 123456789},
+		{-1.005, -100},
+	}
+	for _, tc := range testCases {
+		got, err := CentsFromFloat64(tc.amount)
+		if err != nil {
+			t.Errorf("Expected no error for %v, got %v", tc.amount, err)
+		}
+		if got != tc.want {
+			t.Errorf("Expected %v to be %d cents, got %d", tc.amount, tc.want, got)
+		}
+	}
+
+	if _, err := CentsFromFloat64(math.Inf(1)); err == nil {
+		t.Errorf("Expected error for +Inf, got nil")
+	}
+}
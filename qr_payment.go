@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 )
 
 // DoPaymentResponse represents a response from the QR payment API
@@ -63,6 +64,101 @@ type PaymentOptionResponse struct {
 	RespDesc string `json:"respDesc"`
 }
 
+// PaymentOptionPayload is the decoded payload of a payment option response, listing the
+// payment categories and groups available to the payment token, e.g. to render a payment method picker.
+// Only the fields needed for that are decoded
+// Documentation: https://developer.2c2p.com/v4.3.1/docs/api-payment-option-response-parameter
+type PaymentOptionPayload struct {
+	// PaymentToken is the payment token ID (C 255, M)
+	PaymentToken string `json:"paymentToken"`
+
+	// ChannelCategories are the payment categories, e.g. card or QR, each with its groups
+	ChannelCategories []PaymentOptionCategory `json:"channelCategories"`
+
+	// RespCode is the response code (N 4, M)
+	RespCode PaymentResponseCode `json:"respCode"`
+
+	// RespDesc is the response description (C 255, M)
+	RespDesc string `json:"respDesc"`
+}
+
+// PaymentOptionCategory is a payment category; its Code is PaymentOptionDetailsRequest.CategoryCode
+type PaymentOptionCategory struct {
+	SequenceNo  int                  `json:"sequenceNo"`
+	Code        string               `json:"code"`
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	IconURL     string               `json:"iconUrl"`
+	LogoURL     string               `json:"logoUrl"`
+	Default     bool                 `json:"default"`
+	Groups      []PaymentOptionGroup `json:"groups"`
+}
+
+// PaymentOptionGroup is a payment group within a category; its Code is PaymentOptionDetailsRequest.GroupCode
+type PaymentOptionGroup struct {
+	SequenceNo int    `json:"sequenceNo"`
+	Code       string `json:"code"`
+	Name       string `json:"name"`
+	IconURL    string `json:"iconUrl"`
+	LogoURL    string `json:"logoUrl"`
+	Default    bool   `json:"default"`
+}
+
+// PaymentOptionDetailsPayload is the decoded payload of a payment option details response,
+// listing the channels of one category and group. Only the fields needed to pick a channel are decoded
+// Documentation: https://developer.2c2p.com/v4.3.1/docs/api-payment-option-details-response-parameter
+type PaymentOptionDetailsPayload struct {
+	// CategoryCode and GroupCode are the category and group requested
+	CategoryCode string `json:"categoryCode"`
+	GroupCode    string `json:"groupCode"`
+
+	// Channels are the payment channels; use Payment.Code.ChannelCode as DoPaymentParams.PaymentChannelCode
+	Channels []PaymentOptionChannel `json:"channels"`
+
+	// RespCode is the response code (N 4, M)
+	RespCode PaymentResponseCode `json:"respCode"`
+
+	// RespDesc is the response description (C 255, M)
+	RespDesc string `json:"respDesc"`
+}
+
+// PaymentOptionChannel is a payment channel of a payment option group
+type PaymentOptionChannel struct {
+	SequenceNo int    `json:"sequenceNo"`
+	Name       string `json:"name"`
+	IconURL    string `json:"iconUrl"`
+	LogoURL    string `json:"logoUrl"`
+
+	// IsDown is set while the channel is unavailable
+	IsDown bool `json:"isDown"`
+
+	Payment struct {
+		Code struct {
+			ChannelCode      string `json:"channelCode"`
+			AgentCode        string `json:"agentCode"`
+			AgentChannelCode string `json:"agentChannelCode"`
+		} `json:"code"`
+	} `json:"payment"`
+}
+
+// DecodePaymentOptionPayload verifies and decodes the JWT payload of a payment option response
+func (c *Client) DecodePaymentOptionPayload(payload string) (*PaymentOptionPayload, error) {
+	var decoded PaymentOptionPayload
+	if err := c.decodeJWTTokenForJSON(payload, &decoded); err != nil {
+		return nil, fmt.Errorf("decode payment option payload: %w", err)
+	}
+	return &decoded, nil
+}
+
+// DecodePaymentOptionDetailsPayload verifies and decodes the JWT payload of a payment option details response
+func (c *Client) DecodePaymentOptionDetailsPayload(payload string) (*PaymentOptionDetailsPayload, error) {
+	var decoded PaymentOptionDetailsPayload
+	if err := c.decodeJWTTokenForJSON(payload, &decoded); err != nil {
+		return nil, fmt.Errorf("decode payment option details payload: %w", err)
+	}
+	return &decoded, nil
+}
+
 // APIResponse represents a response from the payment option details API
 type APIResponse struct {
 	Payload  string              `json:"payload"`
@@ -300,3 +396,54 @@ func (c *Client) CreateQRPayment(ctx context.Context, params *CreateQRPaymentPar
 
 	return &doPaymentRespData, nil
 }
+
+// Defaults for QRPollOptions
+const (
+	DefaultQRPollInterval    = 2 * time.Second
+	DefaultQRPollMaxInterval = 30 * time.Second
+)
+
+// QRPollOptions are the optional settings of WaitForQRPayment
+type QRPollOptions struct {
+	// Interval is the wait before the second inquiry; it doubles after every pending response.
+	// Default: DefaultQRPollInterval
+	Interval time.Duration
+
+	// MaxInterval caps the wait between inquiries
+	// Default: DefaultQRPollMaxInterval
+	MaxInterval time.Duration
+}
+
+// WaitForQRPayment inquires invoiceNo until the payment is no longer pending, e.g. after the
+// customer scans the QR code from CreateQRPayment, backing off between inquiries.
+// Bound the wait with a ctx deadline; when ctx is done the last response is returned with ctx.Err()
+func (c *Client) WaitForQRPayment(ctx context.Context, invoiceNo string, opts QRPollOptions) (*PaymentInquiryResponse, error) {
+	interval, maxInterval := opts.Interval, opts.MaxInterval
+	if interval <= 0 {
+		interval = DefaultQRPollInterval
+	}
+	if maxInterval <= 0 {
+		maxInterval = DefaultQRPollMaxInterval
+	}
+
+	var last *PaymentInquiryResponse
+	for {
+		resp, err := c.Inquire(ctx, InquiryQuery{InvoiceNo: invoiceNo})
+		if ctx.Err() != nil {
+			return last, ctx.Err()
+		}
+		if resp == nil || PaymentResponseCode(resp.RespCode).Category() != ResponseCategoryPending {
+			return resp, err
+		}
+		last = resp
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return last, ctx.Err()
+		case <-timer.C:
+		}
+		interval = min(interval*2, maxInterval)
+	}
+}
@@ -1,21 +1,20 @@
 package api2c2p
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/choonkeat/2c2p/testutil"
 )
 
 func TestNewPaymentOptionsRequest(t *testing.T) {
-	client, err := NewClient(Config{
-		SecretKey:                "test_secret",
-		MerchantID:               "JT01",
-		PaymentGatewayURL:        "https://pgw.example.com",
-		FrontendURL:              "https://frontend.example.com",
-		CombinedPEM:              "testdata/combined_private_public.pem",
-		ServerJWTPublicKeyFile:   "testdata/server.jwt.public_cert.pem",
-		ServerPKCS7PublicKeyFile: "testdata/server.pkcs7.public_cert.pem",
-	})
+	client, err := NewClient(testConfig())
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
 	}
@@ -45,15 +44,7 @@ func TestNewPaymentOptionsRequest(t *testing.T) {
 }
 
 func TestNewPaymentOptionDetailsRequest(t *testing.T) {
-	client, err := NewClient(Config{
-		SecretKey:                "test_secret",
-		MerchantID:               "JT01",
-		PaymentGatewayURL:        "https://pgw.example.com",
-		FrontendURL:              "https://frontend.example.com",
-		CombinedPEM:              "testdata/combined_private_public.pem",
-		ServerJWTPublicKeyFile:   "testdata/server.jwt.public_cert.pem",
-		ServerPKCS7PublicKeyFile: "testdata/server.pkcs7.public_cert.pem",
-	})
+	client, err := NewClient(testConfig())
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
 	}
@@ -85,15 +76,7 @@ func TestNewPaymentOptionDetailsRequest(t *testing.T) {
 }
 
 func TestNewDoPaymentRequest(t *testing.T) {
-	client, err := NewClient(Config{
-		SecretKey:                "test_secret",
-		MerchantID:               "JT01",
-		PaymentGatewayURL:        "https://pgw.example.com",
-		FrontendURL:              "https://frontend.example.com",
-		CombinedPEM:              "testdata/combined_private_public.pem",
-		ServerJWTPublicKeyFile:   "testdata/server.jwt.public_cert.pem",
-		ServerPKCS7PublicKeyFile: "testdata/server.pkcs7.public_cert.pem",
-	})
+	client, err := NewClient(testConfig())
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
 	}
@@ -181,3 +164,134 @@ func TestNewDoPaymentRequest(t *testing.T) {
 		},
 	})
 }
+
+func TestWaitForQRPayment(t *testing.T) {
+	var client *Client
+	var inquiries atomic.Int32
+	client = NewTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		respJSON := `{"respCode":"0001","respDesc":"Transaction is pending"}`
+		if inquiries.Add(1) >= 3 {
+			respJSON = `{"respCode":"0000","respDesc":"Success","invoiceNo":"INV123"}`
+		}
+		token, err := client.generateJWTTokenForJSON([]byte(respJSON))
+		if err != nil {
+			t.Errorf("Failed to sign response: %v", err)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"payload": token})
+	})
+
+	resp, err := client.WaitForQRPayment(ctx, "INV123", QRPollOptions{Interval: time.Millisecond, MaxInterval: 2 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("WaitForQRPayment failed: %v", err)
+	}
+	if resp.RespCode != "0000" {
+		t.Errorf("Expected respCode 0000, got %s", resp.RespCode)
+	}
+	if got := inquiries.Load(); got != 3 {
+		t.Errorf("Expected 3 inquiries, got %d", got)
+	}
+}
+
+func TestWaitForQRPaymentCancel(t *testing.T) {
+	cancelCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var client *Client
+	client = NewTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		token, err := client.generateJWTTokenForJSON([]byte(`{"respCode":"0001","respDesc":"Transaction is pending"}`))
+		if err != nil {
+			t.Errorf("Failed to sign response: %v", err)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"payload": token})
+		cancel() // mid-poll, while WaitForQRPayment sleeps
+	})
+
+	start := time.Now()
+	resp, err := client.WaitForQRPayment(cancelCtx, "INV123", QRPollOptions{Interval: time.Hour})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected prompt return on cancel, took %s", elapsed)
+	}
+	if resp != nil && resp.RespCode != "0001" {
+		t.Errorf("Expected last pending response, got %+v", resp)
+	}
+}
+
+func TestDecodePaymentOptionPayload(t *testing.T) {
+	client := NewTestClient(t, nil)
+
+	payload, err := client.generateJWTTokenForJSON([]byte(`{
+		"paymentToken": "token123",
+		"channelCategories": [
+			{"sequenceNo": 1, "code": "GCARD", "name": "Card", "default": true, "groups": [
+				{"sequenceNo": 1, "code": "CC", "name": "Credit / Debit Card", "default": true}
+			]},
+			{"sequenceNo": 2, "code": "QR", "name": "QR Payment", "groups": [
+				{"sequenceNo": 1, "code": "SGQR", "name": "SGQR"},
+				{"sequenceNo": 2, "code": "PAYNOW", "name": "PayNow"}
+			]}
+		],
+		"respCode": "0000",
+		"respDesc": "Success"
+	}`))
+	if err != nil {
+		t.Fatalf("Failed to sign payload: %v", err)
+	}
+
+	options, err := client.DecodePaymentOptionPayload(payload)
+	if err != nil {
+		t.Fatalf("Failed to decode payload: %v", err)
+	}
+	var got []string
+	for _, category := range options.ChannelCategories {
+		for _, group := range category.Groups {
+			got = append(got, category.Code+"/"+group.Code)
+		}
+	}
+	if want := []string{"GCARD/CC", "QR/SGQR", "QR/PAYNOW"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected groups %v, got %v", want, got)
+	}
+	if options.PaymentToken != "token123" || options.RespCode != Code0000Successful {
+		t.Errorf("Expected paymentToken token123 and respCode 0000, got %+v", options)
+	}
+
+	if _, err := client.DecodePaymentOptionPayload(payload + "x"); err == nil {
+		t.Error("Expected an error for a tampered payload")
+	}
+}
+
+func TestDecodePaymentOptionDetailsPayload(t *testing.T) {
+	client := NewTestClient(t, nil)
+
+	payload, err := client.generateJWTTokenForJSON([]byte(`{
+		"categoryCode": "QR",
+		"groupCode": "SGQR",
+		"channels": [
+			{"sequenceNo": 1, "name": "SGQR", "payment": {"code": {"channelCode": "SGQR"}}},
+			{"sequenceNo": 2, "name": "PayNow", "isDown": true, "payment": {"code": {"channelCode": "PNQR", "agentCode": "OCBC"}}}
+		],
+		"respCode": "0000",
+		"respDesc": "Success"
+	}`))
+	if err != nil {
+		t.Fatalf("Failed to sign payload: %v", err)
+	}
+
+	details, err := client.DecodePaymentOptionDetailsPayload(payload)
+	if err != nil {
+		t.Fatalf("Failed to decode payload: %v", err)
+	}
+	if len(details.Channels) != 2 {
+		t.Fatalf("Expected 2 channels, got %+v", details.Channels)
+	}
+	if got := details.Channels[0].Payment.Code.ChannelCode; got != "SGQR" {
+		t.Errorf("Expected channelCode SGQR, got %s", got)
+	}
+	if ch := details.Channels[1]; !ch.IsDown || ch.Payment.Code.AgentCode != "OCBC" {
+		t.Errorf("Expected PayNow to be down with agentCode OCBC, got %+v", ch)
+	}
+}
@@ -3,32 +3,53 @@ package api2c2p
 import (
 	"bytes"
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
+	"crypto/x509"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/go-jose/go-jose/v4"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// ProcessType is the action of a PaymentProcessRequest
+type ProcessType string
+
+const (
+	// ProcessRefund refunds a settled payment, see Refund
+	ProcessRefund ProcessType = "R"
+	// ProcessVoid cancels a payment before it is settled, see VoidCancel
+	ProcessVoid ProcessType = "V"
+	// ProcessSettle captures an authorized payment, see Capture
+	ProcessSettle ProcessType = "S"
+	// ProcessInquiry looks up the status of a payment
+	ProcessInquiry ProcessType = "I"
+)
+
+// KnownProcessTypes are the ProcessType values PerformPaymentProcess accepts
+var KnownProcessTypes = []ProcessType{ProcessRefund, ProcessVoid, ProcessSettle, ProcessInquiry}
+
 // PaymentProcessRequest represents a refund request
 type PaymentProcessRequest struct {
-	XMLName         xml.Name `xml:"PaymentProcessRequest"`
-	Version         string   `xml:"version"`
-	TimeStamp       *string  `xml:"timeStamp,omitempty"`
-	MerchantID      string   `xml:"merchantID"`
-	InvoiceNo       string   `xml:"invoiceNo"`
-	ChildMerchantID *string  `xml:"childMerchantID,omitempty"`
-	ActionAmount    Dollars  `xml:"actionAmount"`
-	ProcessType     string   `xml:"processType"`
-	BankCode        *string  `xml:"bankCode,omitempty"`
-	AccountName     *string  `xml:"accountName,omitempty"`
-	AccountNumber   *string  `xml:"accountNumber,omitempty"`
+	XMLName         xml.Name    `xml:"PaymentProcessRequest"`
+	Version         string      `xml:"version"`
+	TimeStamp       *string     `xml:"timeStamp,omitempty"`
+	MerchantID      string      `xml:"merchantID"`
+	InvoiceNo       string      `xml:"invoiceNo"`
+	ChildMerchantID *string     `xml:"childMerchantID,omitempty"`
+	ActionAmount    Dollars     `xml:"actionAmount"`
+	ProcessType     ProcessType `xml:"processType"`
+	BankCode        *string     `xml:"bankCode,omitempty"`
+	AccountName     *string     `xml:"accountName,omitempty"`
+	AccountNumber   *string     `xml:"accountNumber,omitempty"`
 	SubMerchantList *struct {
 		SubMerchant []struct {
 			SubMID          string                 `xml:"subMID,attr"`
@@ -77,18 +98,65 @@ type RefundResponse struct {
 	ReferenceNo    string   `xml:"referenceNo,omitempty"`
 	TransactionID  string   `xml:"transactionID,omitempty"`
 	TransactionRef string   `xml:"transactionRef,omitempty"`
+
+	// IdempotencyID is the idempotency ID sent with the request, e.g. generated by AutoIdempotency
+	IdempotencyID string `xml:"-"`
+}
+
+// RefundOptions are the optional fields of a refund request
+type RefundOptions struct {
+	// ChildMerchantID refunds a payment made to a marketplace child merchant
+	ChildMerchantID string
+
+	// IncludeTimestamp sends the current time as timeStamp, for configurations that require one
+	IncludeTimestamp bool
+}
+
+// paymentProcessTimeStampLayout is the ddMMyyHHmmss format of PaymentProcessRequest.TimeStamp
+const paymentProcessTimeStampLayout = "020106150405"
+
+// timeStamp returns the current time formatted for PaymentProcessRequest.TimeStamp
+func (c *Client) timeStamp() *string {
+	timeStamp := c.now().Format(paymentProcessTimeStampLayout)
+	return &timeStamp
 }
 
 // Refund processes a refund request for a previously successful payment
 func (c *Client) Refund(ctx context.Context, invoiceNo string, amount Cents) (*RefundResponse, error) {
-	// Create refund request
+	return c.RefundWithOptions(ctx, invoiceNo, amount, RefundOptions{})
+}
+
+// RefundWithOptions is Refund with the optional fields in opts
+func (c *Client) RefundWithOptions(ctx context.Context, invoiceNo string, amount Cents, opts RefundOptions) (*RefundResponse, error) {
+	req := c.newRefundRequest(invoiceNo, amount, opts)
+
+	// Create HTTP request
+	var refundResp RefundResponse
+	if err := c.PerformPaymentProcess(ctx, req, &refundResp); err != nil {
+		return &refundResp, err
+	}
+	if req.IdempotencyID != nil {
+		refundResp.IdempotencyID = *req.IdempotencyID
+	}
+	if !PaymentResponseCode(refundResp.RespCode).IsSuccess() {
+		return &refundResp, &APIError{
+			Endpoint: "refund",
+			RespCode: PaymentResponseCode(refundResp.RespCode),
+			RespDesc: refundResp.RespDesc,
+		}
+	}
+	return &refundResp, nil
+}
+
+// newRefundRequest builds the payment process request for RefundWithOptions
+func (c *Client) newRefundRequest(invoiceNo string, amount Cents, opts RefundOptions) *PaymentProcessRequest {
 	req := &PaymentProcessRequest{
 		Version:      "4.3",
-		TimeStamp:    nil, // No timestamp as requested
+		TimeStamp:    nil, // No timestamp unless opts.IncludeTimestamp
 		MerchantID:   c.MerchantID,
 		InvoiceNo:    invoiceNo,
 		ActionAmount: amount.ToDollars(),
-		ProcessType:  "R",
+		ProcessType:  ProcessRefund,
 		// LoyaltyPayments: &struct {
 		// 	LoyaltyRefund []LoyaltyRefund `xml:"loyaltyRefund"`
 		// }{
@@ -101,13 +169,24 @@ func (c *Client) Refund(ctx context.Context, invoiceNo string, amount Cents) (*R
 		// },
 	}
 
-	// Create HTTP request
-	var refundResp RefundResponse
-	return &refundResp, c.PerformPaymentProcess(ctx, req, &refundResp)
+	if opts.ChildMerchantID != "" {
+		req.ChildMerchantID = &opts.ChildMerchantID
+	}
+	if opts.IncludeTimestamp {
+		req.TimeStamp = c.timeStamp()
+	}
+	if id := c.idempotencyID(""); id != "" {
+		req.IdempotencyID = &id
+	}
+	return req
 }
 
 // Refund processes a refund request for a previously successful payment
 func (c *Client) PerformPaymentProcess(ctx context.Context, input *PaymentProcessRequest, output interface{}) error {
+	if !slices.Contains(KnownProcessTypes, input.ProcessType) {
+		return fmt.Errorf("unknown processType %q, expected one of %v", input.ProcessType, KnownProcessTypes)
+	}
+
 	// Create HTTP request
 	httpReq, err := c.NewPaymentProcessRequest(ctx, input)
 	if err != nil {
@@ -115,7 +194,7 @@ func (c *Client) PerformPaymentProcess(ctx context.Context, input *PaymentProces
 	}
 
 	// Send request
-	resp, err := c.httpClient.Do(httpReq)
+	resp, err := c.do(httpReq)
 	if err != nil {
 		return fmt.Errorf("send request: %w", err)
 	}
@@ -138,7 +217,7 @@ func (c *Client) PerformPaymentProcess(ctx context.Context, input *PaymentProces
 	return nil
 }
 
-func jwsWithRawPayload(privateKey *rsa.PrivateKey, token *jwt.Token, payload []byte) (string, error) {
+func jwsWithRawPayload(privateKey crypto.PrivateKey, token *jwt.Token, payload []byte) (string, error) {
 	h, err := json.Marshal(token.Header)
 	if err != nil {
 		return "", err
@@ -154,33 +233,103 @@ func jwsWithRawPayload(privateKey *rsa.PrivateKey, token *jwt.Token, payload []b
 	return sstr + "." + token.EncodeSegment(sig), nil
 }
 
+// jwsSigningMethod picks the JWS algorithm for privateKey; 2C2P documents PS256 (RSA)
+func jwsSigningMethod(privateKey crypto.PrivateKey) (jwt.SigningMethod, error) {
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return jwt.SigningMethodPS256, nil
+	case *ecdsa.PrivateKey:
+		switch key.Curve.Params().BitSize {
+		case 256:
+			return jwt.SigningMethodES256, nil
+		case 384:
+			return jwt.SigningMethodES384, nil
+		case 521:
+			return jwt.SigningMethodES512, nil
+		}
+		return nil, fmt.Errorf("unsupported ECDSA curve: %s", key.Curve.Params().Name)
+	case ed25519.PrivateKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, fmt.Errorf("unsupported private key type: %T", privateKey)
+}
+
+// validateJOSEAlgorithms rejects algorithms we cannot use with 2C2P's RSA keys
+func validateJOSEAlgorithms(keyAlg jose.KeyAlgorithm, enc jose.ContentEncryption, sigAlg jose.SignatureAlgorithm) error {
+	switch keyAlg {
+	case jose.RSA_OAEP, jose.RSA_OAEP_256:
+	default:
+		return fmt.Errorf("unsupported JWE key algorithm: %s", keyAlg)
+	}
+	switch enc {
+	case jose.A128GCM, jose.A192GCM, jose.A256GCM, jose.A128CBC_HS256, jose.A192CBC_HS384, jose.A256CBC_HS512:
+	default:
+		return fmt.Errorf("unsupported JWE content encryption: %s", enc)
+	}
+	switch sigAlg {
+	case jose.PS256, jose.PS384, jose.PS512, jose.RS256, jose.RS384, jose.RS512:
+	default:
+		return fmt.Errorf("unsupported JWS signature algorithm: %s", sigAlg)
+	}
+	return nil
+}
+
+// DecryptActionResponse verifies and decrypts a payment maintenance (refund/void) response body,
+// e.g. one received by a service that does not make the requests itself
+func (c *Client) DecryptActionResponse(body string) ([]byte, error) {
+	return c.verifyJWSAndDecryptJWE(body)
+}
+
+// VerifyAndDecryptJWSJWE verifies the JWS signature of input with serverPublicCert and
+// decrypts the enclosed JWE with privateKey, returning the payload, without a configured Client
+func VerifyAndDecryptJWSJWE(input string, serverPublicCert *x509.Certificate, privateKey *rsa.PrivateKey) ([]byte, error) {
+	client, err := newClientWithKeys(Config{}, privateKey, nil, serverPublicCert, nil)
+	if err != nil {
+		return nil, err
+	}
+	return client.verifyJWSAndDecryptJWE(input)
+}
+
 // verifyJWSAndDecryptJWE verifies a JWS token using the public key and decrypts the JWE payload using the private key.
 // The inputToken string should be a JWS token containing a JWE payload.
 func (c *Client) verifyJWSAndDecryptJWE(inputToken string) ([]byte, error) {
-	publicKey, ok := c.ServerJWTPublicCert.PublicKey.(*rsa.PublicKey)
-	if !ok {
-		return nil, fmt.Errorf("convert public key to RSA public key")
+	if c.closed.Load() {
+		return nil, ErrClientClosed
 	}
-
 	// Parse and verify JWS
-	jws, err := jose.ParseSigned(inputToken, []jose.SignatureAlgorithm{jose.PS256})
+	jws, err := jose.ParseSigned(inputToken, []jose.SignatureAlgorithm{c.JWSSignatureAlgorithm})
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse JWS: %w", err)
 	}
 
-	// Verify JWS signature and get payload
-	jweTokenBytes, err := jws.Verify(publicKey)
+	// Verify JWS signature and get payload, trying each accepted certificate in turn
+	var jweTokenBytes []byte
+	certs := append([]*x509.Certificate{c.ServerJWTPublicCert}, c.ServerJWTPublicCerts...)
+	for i, cert := range certs {
+		publicKey, ok := cert.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			err = fmt.Errorf("convert public key to RSA public key")
+			continue
+		}
+		if jweTokenBytes, err = jws.Verify(publicKey); err == nil {
+			c.logger.Debug("verified JWS signature", "certIndex", i, "certSerial", cert.SerialNumber, "certSubject", cert.Subject.CommonName)
+			break
+		}
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to verify JWS signature: %w", err)
 	}
 
 	// Parse JWE token
-	object, err := jose.ParseEncrypted(string(jweTokenBytes), []jose.KeyAlgorithm{jose.RSA_OAEP}, []jose.ContentEncryption{jose.A256GCM})
+	object, err := jose.ParseEncrypted(string(jweTokenBytes), []jose.KeyAlgorithm{c.JWEKeyAlgorithm}, []jose.ContentEncryption{c.JWEContentEncryption})
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse JWE token: %w", err)
 	}
 
-	// Decrypt JWE token
+	// Decrypt JWE token; RSA-OAEP and RSA-OAEP-256 need an RSA key
+	if _, ok := c.PrivateKey.(*rsa.PrivateKey); !ok {
+		return nil, fmt.Errorf("decrypt JWE token: %w", ErrRSAKeyRequired)
+	}
 	decrypted, err := object.Decrypt(c.PrivateKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decrypt JWE token: %w", err)
@@ -190,12 +339,15 @@ func (c *Client) verifyJWSAndDecryptJWE(inputToken string) ([]byte, error) {
 }
 
 func (c *Client) encryptJWEAndSignJWS(xmlData []byte) (string, error) {
+	if c.closed.Load() {
+		return "", ErrClientClosed
+	}
 	// Encrypt with JWE
 	// Create encrypter
 	encrypter, err := jose.NewEncrypter(
-		jose.A256GCM,
+		c.JWEContentEncryption,
 		jose.Recipient{
-			Algorithm: jose.RSA_OAEP,
+			Algorithm: c.JWEKeyAlgorithm,
 			Key:       c.ServerJWTPublicCert.PublicKey,
 		},
 		// this option means to include `"typ": "JWE"` in header
@@ -208,7 +360,6 @@ func (c *Client) encryptJWEAndSignJWS(xmlData []byte) (string, error) {
 	}
 
 	// Encrypt data
-	log.Printf("[DEBUG] Encrypting data: %s", string(xmlData))
 	jwe, err := encrypter.Encrypt(xmlData)
 	if err != nil {
 		return "", fmt.Errorf("encrypt data: %w", err)
@@ -219,12 +370,21 @@ func (c *Client) encryptJWEAndSignJWS(xmlData []byte) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("serialize JWE: %w", err)
 	}
-	log.Printf("jweToken: %s", jweToken)
 
 	// Then sign with JWS PS256
 	// https://developer.2c2p.com/v4.3.1/recipes/prepare-request-payload-with-jwt-jws-with-keys
 	// https://developer.2c2p.com/v4.3.1/docs/payment-maintenance-refund-guide
-	token := jwt.New(jwt.SigningMethodPS256)
+	method, err := jwsSigningMethod(c.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := c.PrivateKey.(*rsa.PrivateKey); ok {
+		method = jwt.GetSigningMethod(string(c.JWSSignatureAlgorithm))
+	}
+	token := jwt.New(method)
+	if c.KeyID != "" {
+		token.Header["kid"] = c.KeyID
+	}
 
 	// Sign the token
 	signedJWE, err := jwsWithRawPayload(c.PrivateKey, token, []byte(jweToken))
@@ -234,13 +394,21 @@ func (c *Client) encryptJWEAndSignJWS(xmlData []byte) (string, error) {
 	return signedJWE, nil
 }
 
-// NewPaymentProcessRequest creates a new HTTP request for refunding a payment
-func (c *Client) NewPaymentProcessRequest(ctx context.Context, req *PaymentProcessRequest) (*http.Request, error) {
-	// Marshal request to XML
+// marshalPaymentProcessRequest returns the XML that is encrypted into the JWE of a payment process request
+func marshalPaymentProcessRequest(req *PaymentProcessRequest) ([]byte, error) {
 	xmlData, err := xml.MarshalIndent(req, "", "  ")
 	if err != nil {
 		return nil, fmt.Errorf("marshal request: %w", err)
 	}
+	return xmlData, nil
+}
+
+// NewPaymentProcessRequest creates a new HTTP request for refunding a payment
+func (c *Client) NewPaymentProcessRequest(ctx context.Context, req *PaymentProcessRequest) (*http.Request, error) {
+	xmlData, err := marshalPaymentProcessRequest(req)
+	if err != nil {
+		return nil, err
+	}
 
 	// Sign the token
 	signedJWE, err := c.encryptJWEAndSignJWS(xmlData)
@@ -249,12 +417,12 @@ func (c *Client) NewPaymentProcessRequest(ctx context.Context, req *PaymentProce
 	}
 
 	// Create request
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.frontendEndpoint("2C2PFrontend/PaymentAction/2.0/action"), strings.NewReader(signedJWE))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.frontendEndpoint(c.ActionPath), strings.NewReader(signedJWE))
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
 
 	// Set headers
-	httpReq.Header.Set("Content-Type", "text/plain")
+	httpReq.Header.Set("Content-Type", c.ActionContentType)
 	return httpReq, nil
 }
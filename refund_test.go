@@ -3,24 +3,24 @@ package api2c2p
 import (
 	"bytes"
 	"context"
+	"crypto/rsa"
 	"io"
 	"log"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"encoding/xml"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/google/uuid"
 )
 
 func TestNewPaymentProcessRequest(t *testing.T) {
-	client, err := NewClient(Config{
-		SecretKey:                "test_secret",
-		MerchantID:               "JT01",
-		PaymentGatewayURL:        "https://pgw.example.com",
-		FrontendURL:              "https://frontend.example.com",
-		CombinedPEM:              "testdata/combined_private_public.pem",
-		ServerJWTPublicKeyFile:   "testdata/public_cert.pem", // we have to decrypt what we encrypted in this test
-		ServerPKCS7PublicKeyFile: "testdata/server.pkcs7.public_cert.pem",
-	})
+	cfg := testConfig()
+	cfg.ServerJWTPublicKeyFile = "testdata/public_cert.pem" // we have to decrypt what we encrypted in this test
+	client, err := NewClient(cfg)
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
 	}
@@ -81,6 +81,67 @@ func TestNewPaymentProcessRequest(t *testing.T) {
 	}
 }
 
+func TestNewPaymentProcessRequestContentType(t *testing.T) {
+	testCases := []struct {
+		name        string
+		contentType string
+		want        string
+	}{
+		{name: "default", contentType: "", want: "text/plain"},
+		{name: "configured", contentType: "application/jose", want: "application/jose"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.ActionContentType = tc.contentType
+			client, err := NewClient(cfg)
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+			httpReq, err := client.NewPaymentProcessRequest(context.Background(), &PaymentProcessRequest{
+				InvoiceNo:   "260121085327",
+				ProcessType: "R",
+			})
+			if err != nil {
+				t.Fatalf("Failed to create refund request: %v", err)
+			}
+			if ct := httpReq.Header.Get("Content-Type"); ct != tc.want {
+				t.Errorf("Expected Content-Type %s, got %s", tc.want, ct)
+			}
+		})
+	}
+}
+
+func TestPaymentProcessRequestProcessType(t *testing.T) {
+	testCases := []struct {
+		processType ProcessType
+		want        string
+	}{
+		{ProcessRefund, "<processType>R</processType>"},
+		{ProcessVoid, "<processType>V</processType>"},
+		{ProcessSettle, "<processType>S</processType>"},
+		{ProcessInquiry, "<processType>I</processType>"},
+	}
+	for _, tc := range testCases {
+		data, err := xml.Marshal(PaymentProcessRequest{ProcessType: tc.processType})
+		if err != nil {
+			t.Fatalf("Failed to marshal request: %v", err)
+		}
+		if !bytes.Contains(data, []byte(tc.want)) {
+			t.Errorf("Expected XML to contain %s, got %s", tc.want, data)
+		}
+	}
+
+	client := NewTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected no request for an unknown processType")
+	})
+	err := client.PerformPaymentProcess(ctx, &PaymentProcessRequest{InvoiceNo: "INV123", ProcessType: "X"}, &RefundResponse{})
+	if err == nil || err.Error() != `unknown processType "X", expected one of [R V S I]` {
+		t.Errorf("Expected unknown processType error, got %v", err)
+	}
+}
+
 type mockRoundTripper struct {
 	response []byte
 	err      error
@@ -97,15 +158,9 @@ func (m *mockRoundTripper) RoundTrip(req *http.Request) (*http.Response, error)
 }
 
 func TestRefund(t *testing.T) {
-	client, err := NewClient(Config{
-		SecretKey:                "test_secret",
-		MerchantID:               "JT01",
-		PaymentGatewayURL:        "https://pgw.example.com",
-		FrontendURL:              "https://frontend.example.com",
-		CombinedPEM:              "testdata/combined_private_public.pem",
-		ServerJWTPublicKeyFile:   "testdata/public_cert.pem", // we have to decrypt what we encrypted in this test
-		ServerPKCS7PublicKeyFile: "testdata/server.pkcs7.public_cert.pem",
-	})
+	cfg := testConfig()
+	cfg.ServerJWTPublicKeyFile = "testdata/public_cert.pem" // we have to decrypt what we encrypted in this test
+	client, err := NewClient(cfg)
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
 	}
@@ -136,7 +191,7 @@ func TestRefund(t *testing.T) {
 			response: []byte(signedJWE),
 		},
 	}
-	client.httpClient = NewLoggingClient(mockClient, nil, false)
+	client.httpClient = NewStructuredLoggingClient(mockClient, nil, false)
 
 	resp, err := client.Refund(context.Background(), "260121085327", 25.00)
 	if err != nil {
@@ -153,4 +208,289 @@ func TestRefund(t *testing.T) {
 	if resp.ProcessType != "R" {
 		t.Errorf("Expected process type R, got %s", resp.ProcessType)
 	}
+	if resp.IdempotencyID != "" {
+		t.Errorf("Expected no idempotencyID without AutoIdempotency, got %s", resp.IdempotencyID)
+	}
+
+	client.AutoIdempotency = true
+	resp, err = client.Refund(context.Background(), "260121085327", 25.00)
+	if err != nil {
+		t.Fatalf("Failed to process refund: %v", err)
+	}
+	if _, err := uuid.Parse(resp.IdempotencyID); err != nil {
+		t.Errorf("Expected a UUID idempotencyID, got %q", resp.IdempotencyID)
+	}
+}
+
+func TestVerifyJWSAndDecryptJWEKeyRotation(t *testing.T) {
+	// signs with our private key and encrypts for our own certificate,
+	// standing in for 2C2P's new key pair
+	cfg := testConfig()
+	cfg.ServerJWTPublicKeyFile = "testdata/public_cert.pem"
+	server, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create server client: %v", err)
+	}
+	token, err := server.encryptJWEAndSignJWS([]byte("<PaymentProcessResponse/>"))
+	if err != nil {
+		t.Fatalf("Failed to encrypt response: %v", err)
+	}
+
+	// only the second certificate verifies the signature
+	client, err := NewClient(Config{
+		SecretKey:   "test_secret",
+		MerchantID:  "JT01",
+		CombinedPEM: "testdata/combined_private_public.pem",
+		ServerJWTPublicKeyFiles: []string{
+			"testdata/server.jwt.public_cert.pem",
+			"testdata/public_cert.pem",
+		},
+		ServerPKCS7PublicKeyFile: "testdata/server.pkcs7.public_cert.pem",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if len(client.ServerJWTPublicCerts) != 1 {
+		t.Fatalf("Expected 1 additional server JWT certificate, got %d", len(client.ServerJWTPublicCerts))
+	}
+
+	decrypted, err := client.verifyJWSAndDecryptJWE(token)
+	if err != nil {
+		t.Fatalf("Expected second certificate to verify, got %v", err)
+	}
+	if string(decrypted) != "<PaymentProcessResponse/>" {
+		t.Errorf("Expected decrypted payload %q, got %q", "<PaymentProcessResponse/>", decrypted)
+	}
+
+	// without the rotated certificate, verification fails
+	client.ServerJWTPublicCerts = nil
+	if _, err := client.verifyJWSAndDecryptJWE(token); err == nil {
+		t.Error("Expected verification to fail without the rotated certificate")
+	}
+}
+
+func TestEncryptJWEAndSignJWSKeyID(t *testing.T) {
+	testCases := []struct {
+		name  string
+		keyID string
+		want  func(client *Client) string
+	}{
+		{
+			name:  "configured",
+			keyID: "merchant-key-2024",
+			want:  func(*Client) string { return "merchant-key-2024" },
+		},
+		{
+			name: "derived from certificate",
+			want: func(client *Client) string { return KeyIDFromCert(client.PublicCert) },
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.ServerJWTPublicKeyFile = "testdata/public_cert.pem"
+			cfg.KeyID = tc.keyID
+			client, err := NewClient(cfg)
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+			signed, err := client.encryptJWEAndSignJWS([]byte("<PaymentProcessRequest/>"))
+			if err != nil {
+				t.Fatalf("Failed to sign: %v", err)
+			}
+
+			jws, err := jose.ParseSigned(signed, []jose.SignatureAlgorithm{jose.PS256})
+			if err != nil {
+				t.Fatalf("Failed to parse JWS: %v", err)
+			}
+			want := tc.want(client)
+			if got := jws.Signatures[0].Header.KeyID; got != want {
+				t.Errorf("Expected kid %q, got %q", want, got)
+			}
+		})
+	}
+}
+
+func TestRefundWithOptions(t *testing.T) {
+	cfg := testConfig()
+	cfg.ServerJWTPublicKeyFile = "testdata/public_cert.pem" // we have to decrypt what we encrypted in this test
+	cfg.Clock = func() time.Time {
+		return time.Date(2021, 1, 26, 8, 53, 27, 0, time.UTC)
+	}
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	var gotRequest []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("Failed to read request body: %v", err)
+			return
+		}
+		gotRequest, err = client.verifyJWSAndDecryptJWE(string(body))
+		if err != nil {
+			t.Errorf("Failed to verify and decrypt request: %v", err)
+			return
+		}
+		signedJWE, err := client.encryptJWEAndSignJWS([]byte(`<PaymentProcessResponse><respCode>0000</respCode></PaymentProcessResponse>`))
+		if err != nil {
+			t.Errorf("Failed to encrypt response: %v", err)
+			return
+		}
+		w.Write([]byte(signedJWE))
+	}))
+	defer ts.Close()
+	client.FrontendURL = ts.URL
+
+	testCases := []struct {
+		name    string
+		opts    RefundOptions
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "child merchant",
+			opts:    RefundOptions{ChildMerchantID: "CHILD01"},
+			want:    []string{"<childMerchantID>CHILD01</childMerchantID>"},
+			notWant: []string{"timeStamp"},
+		},
+		{
+			name:    "timestamp",
+			opts:    RefundOptions{IncludeTimestamp: true},
+			want:    []string{"<timeStamp>260121085327</timeStamp>"},
+			notWant: []string{"childMerchantID"},
+		},
+		{
+			name:    "defaults",
+			notWant: []string{"childMerchantID", "timeStamp"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := client.RefundWithOptions(ctx, "260121085327", 2500, tc.opts); err != nil {
+				t.Fatalf("Failed to process refund: %v", err)
+			}
+			for _, want := range tc.want {
+				if !bytes.Contains(gotRequest, []byte(want)) {
+					t.Errorf("Expected request to contain %s, got %s", want, gotRequest)
+				}
+			}
+			for _, notWant := range tc.notWant {
+				if bytes.Contains(gotRequest, []byte(notWant)) {
+					t.Errorf("Expected request without %s, got %s", notWant, gotRequest)
+				}
+			}
+		})
+	}
+}
+
+func TestDecryptActionResponse(t *testing.T) {
+	cfg := testConfig()
+	cfg.ServerJWTPublicKeyFile = "testdata/public_cert.pem" // we have to decrypt what we encrypted in this test
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	want := `<PaymentProcessResponse><respCode>0000</respCode></PaymentProcessResponse>`
+	signedJWE, err := client.encryptJWEAndSignJWS([]byte(want))
+	if err != nil {
+		t.Fatalf("Failed to encrypt response: %v", err)
+	}
+
+	got, err := client.DecryptActionResponse(signedJWE)
+	if err != nil {
+		t.Fatalf("DecryptActionResponse failed: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+
+	privateKey, ok := client.PrivateKey.(*rsa.PrivateKey)
+	if !ok {
+		t.Fatalf("Expected RSA private key, got %T", client.PrivateKey)
+	}
+	got, err = VerifyAndDecryptJWSJWE(signedJWE, client.ServerJWTPublicCert, privateKey)
+	if err != nil {
+		t.Fatalf("VerifyAndDecryptJWSJWE failed: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+
+	// the signature must match the given certificate
+	otherCert, err := loadServerPublicCert(KeyRoleServerJWT, nil, "testdata/server.jwt.public_cert.pem")
+	if err != nil {
+		t.Fatalf("Failed to load certificate: %v", err)
+	}
+	if _, err := VerifyAndDecryptJWSJWE(signedJWE, otherCert, privateKey); err == nil {
+		t.Error("Expected verification to fail with another certificate")
+	}
+	if _, err := client.DecryptActionResponse("not a token"); err == nil {
+		t.Error("Expected error for malformed body")
+	}
+}
+
+func TestEncryptJWEAndSignJWSAlgorithms(t *testing.T) {
+	newClient := func(cfg Config) *Client {
+		t.Helper()
+		cfg.SecretKey = "test_secret"
+		cfg.MerchantID = "JT01"
+		cfg.CombinedPEM = "testdata/combined_private_public.pem"
+		cfg.ServerJWTPublicKeyFile = "testdata/public_cert.pem" // we have to decrypt what we encrypted in this test
+		cfg.ServerPKCS7PublicKeyFile = "testdata/server.pkcs7.public_cert.pem"
+		client, err := NewClient(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		return client
+	}
+	client := newClient(Config{
+		JWEKeyAlgorithm:       jose.RSA_OAEP_256,
+		JWEContentEncryption:  jose.A128GCM,
+		JWSSignatureAlgorithm: jose.PS512,
+	})
+
+	want := `<PaymentProcessResponse><respCode>0000</respCode></PaymentProcessResponse>`
+	signedJWE, err := client.encryptJWEAndSignJWS([]byte(want))
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	jws, err := jose.ParseSigned(signedJWE, []jose.SignatureAlgorithm{jose.PS512})
+	if err != nil {
+		t.Fatalf("Expected PS512 signature, got %v", err)
+	}
+	jwe, err := jose.ParseEncrypted(string(jws.UnsafePayloadWithoutVerification()), []jose.KeyAlgorithm{jose.RSA_OAEP_256}, []jose.ContentEncryption{jose.A128GCM})
+	if err != nil {
+		t.Fatalf("Expected RSA-OAEP-256 and A128GCM encryption, got %v", err)
+	}
+	if jwe.Header.Algorithm != string(jose.RSA_OAEP_256) {
+		t.Errorf("Expected alg %s, got %s", jose.RSA_OAEP_256, jwe.Header.Algorithm)
+	}
+
+	got, err := client.DecryptActionResponse(signedJWE)
+	if err != nil {
+		t.Fatalf("DecryptActionResponse failed: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+
+	// a client expecting the defaults rejects other algorithms
+	if _, err := newClient(Config{}).DecryptActionResponse(signedJWE); err == nil {
+		t.Error("Expected default client to reject PS512 signature")
+	}
+
+	for _, cfg := range []Config{
+		{JWEKeyAlgorithm: jose.RSA1_5},
+		{JWEContentEncryption: "A512GCM"},
+		{JWSSignatureAlgorithm: jose.HS256},
+	} {
+		if _, err := NewClientWithKeys(cfg, client.PrivateKey, client.PublicCert, client.ServerJWTPublicCert, nil); err == nil {
+			t.Errorf("Expected error for unsupported algorithms %+v", cfg)
+		}
+	}
 }
@@ -0,0 +1,56 @@
+package api2c2p
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ResponseCategory groups response codes by how a merchant should handle them
+type ResponseCategory string
+
+// Response categories, see PaymentResponseCode.Category
+const (
+	ResponseCategoryUnknown        ResponseCategory = ""
+	ResponseCategorySuccess        ResponseCategory = "success"
+	ResponseCategoryPending        ResponseCategory = "pending"
+	ResponseCategoryDeclined       ResponseCategory = "declined"
+	ResponseCategoryFraud          ResponseCategory = "fraud"
+	ResponseCategorySystemError    ResponseCategory = "system_error"
+	ResponseCategoryInvalidRequest ResponseCategory = "invalid_request"
+)
+
+// IsSuccess reports whether c is a success code. The payment gateway APIs (payment token,
+// inquiry) send 4 digit codes, e.g. "0000", while SecureFields backend responses and
+// some payment maintenance (refund/void) responses send the older 2 digit "00"
+func (c PaymentResponseCode) IsSuccess() bool {
+	return c == Code0000Successful || c == "00"
+}
+
+// IsDecline reports whether the issuer or 2C2P declined the payment, including suspected fraud
+func (c PaymentResponseCode) IsDecline() bool {
+	switch c.Category() {
+	case ResponseCategoryDeclined, ResponseCategoryFraud:
+		return true
+	}
+	return false
+}
+
+// IsRetryable reports whether the failure is transient, so the same request may succeed later
+func (c PaymentResponseCode) IsRetryable() bool {
+	return c.Category() == ResponseCategorySystemError
+}
+
+// UnmarshalJSON zero-pads numeric codes shorter than 4 digits, e.g. "00" becomes "0000",
+// so Description and Category find them. XML is left alone: the 2 digit codes of
+// PaymentResponseBackEnd are a separate set, e.g. "99" is not "0099"
+func (c *PaymentResponseCode) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if len(s) < 4 && s != "" && strings.Trim(s, "0123456789") == "" {
+		s = strings.Repeat("0", 4-len(s)) + s
+	}
+	*c = PaymentResponseCode(s)
+	return nil
+}
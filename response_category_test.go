@@ -0,0 +1,136 @@
+package api2c2p
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPaymentResponseCodeCategory(t *testing.T) {
+	testCases := []struct {
+		code      PaymentResponseCode
+		category  ResponseCategory
+		decline   bool
+		retryable bool
+	}{
+		{Code0000Successful, ResponseCategorySuccess, false, false},
+		{"0001", ResponseCategoryPending, false, false},
+		{"0003", ResponseCategoryDeclined, true, false},
+		{"4000", ResponseCategorySuccess, false, false},
+		{"4110", ResponseCategorySuccess, false, false},
+		{"4140", ResponseCategoryInvalidRequest, false, false},
+		{Code4005DoNotHonor, ResponseCategoryDeclined, true, false},
+		{"4051", ResponseCategoryDeclined, true, false},
+		{"4059", ResponseCategoryFraud, true, false},
+		{"4043", ResponseCategoryFraud, true, false},
+		{"5002", ResponseCategorySystemError, false, true},
+		{"5005", ResponseCategoryInvalidRequest, false, false},
+		{"5009", ResponseCategoryDeclined, true, false},
+		{"5998", ResponseCategorySystemError, false, true},
+		{"9994", ResponseCategorySystemError, false, true},
+		{"9042", ResponseCategoryInvalidRequest, false, false},
+		{"6101", ResponseCategoryInvalidRequest, false, false},
+		{"9035", ResponseCategoryInvalidRequest, false, false},
+		{"9999", ResponseCategorySystemError, false, true},
+		{"1234", ResponseCategoryUnknown, false, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.code), func(t *testing.T) {
+			if got := tc.code.Category(); got != tc.category {
+				t.Errorf("Expected category %q, got %q", tc.category, got)
+			}
+			if got := tc.code.IsDecline(); got != tc.decline {
+				t.Errorf("Expected IsDecline %v, got %v", tc.decline, got)
+			}
+			if got := tc.code.IsRetryable(); got != tc.retryable {
+				t.Errorf("Expected IsRetryable %v, got %v", tc.retryable, got)
+			}
+		})
+	}
+}
+
+func TestPaymentResponseCodeIsSuccess(t *testing.T) {
+	testCases := []struct {
+		code PaymentResponseCode
+		want bool
+	}{
+		{Code0000Successful, true},
+		{"00", true},
+		{Code4005DoNotHonor, false},
+		{"05", false},
+		{"", false},
+	}
+
+	for _, tc := range testCases {
+		if got := tc.code.IsSuccess(); got != tc.want {
+			t.Errorf("Expected IsSuccess of %q to be %v, got %v", tc.code, tc.want, got)
+		}
+	}
+}
+
+func TestPaymentFlowResponseCodeClassification(t *testing.T) {
+	testCases := []struct {
+		code           PaymentFlowResponseCode
+		requiresAction bool
+		success        bool
+		failure        bool
+		description    string
+	}{
+		{Flow1000LoadRedirectUrlWithIframeWebview, true, false, false, "Load redirect URL with IFrame / Webview."},
+		{Flow1001FullRedirectionToWebPage, true, false, false, "Full redirection to web page"},
+		{Flow10051DisplayGeneratedQrAndWaitForCustomerToScan, true, false, false, Flow10051DisplayGeneratedQrAndWaitForCustomerToScan.Description()},
+		{Flow2000TransactionCompletedAndMerchantRequireToDisplayPaymentResult, false, true, false, "Transaction completed and merchant require to display payment result."},
+		{FlowOtherTransactionFailedOrRejectedPerformPaymentInquiryToGetPayment, false, false, true, "Transaction failed or rejected, perform payment inquiry to get payment status and full response."},
+		{"4005", false, false, false, "Unknown response code: 4005"},
+		{"0000", false, false, false, "Unknown response code: 0000"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.code), func(t *testing.T) {
+			if got := tc.code.RequiresAction(); got != tc.requiresAction {
+				t.Errorf("Expected RequiresAction %v, got %v", tc.requiresAction, got)
+			}
+			if got := tc.code.IsTerminalSuccess(); got != tc.success {
+				t.Errorf("Expected IsTerminalSuccess %v, got %v", tc.success, got)
+			}
+			if got := tc.code.IsTerminalFailure(); got != tc.failure {
+				t.Errorf("Expected IsTerminalFailure %v, got %v", tc.failure, got)
+			}
+			if got := tc.code.Description(); got != tc.description {
+				t.Errorf("Expected Description %q, got %q", tc.description, got)
+			}
+		})
+	}
+}
+
+func TestPaymentResponseCodeUnmarshalJSON(t *testing.T) {
+	testCases := []struct {
+		input string
+		want  PaymentResponseCode
+	}{
+		{`"00"`, Code0000Successful},
+		{`"0000"`, Code0000Successful},
+		{`"1"`, "0001"},
+		{`"4005"`, Code4005DoNotHonor},
+		{`""`, ""},
+		{`"ABC"`, "ABC"},
+	}
+
+	for _, tc := range testCases {
+		var resp PaymentTokenResponse
+		if err := json.Unmarshal([]byte(`{"respCode":`+tc.input+`}`), &resp); err != nil {
+			t.Fatalf("Failed to unmarshal %s: %v", tc.input, err)
+		}
+		if resp.RespCode != tc.want {
+			t.Errorf("Expected respCode %q for %s, got %q", tc.want, tc.input, resp.RespCode)
+		}
+	}
+
+	var code PaymentResponseCode
+	if err := json.Unmarshal([]byte(`"00"`), &code); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if got := code.Description(); got != "Successful" {
+		t.Errorf("Expected description %q, got %q", "Successful", got)
+	}
+}
@@ -2,16 +2,22 @@ package api2c2p
 
 // `Server-to-server API - Frontend return URL` must be set in the 2c2p portal
 import (
+	"bytes"
+	"crypto"
 	"crypto/hmac"
 	"crypto/rsa"
 	"crypto/sha1"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/xml"
 	"fmt"
-	"log"
+	"hash"
+	"io"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/fullsailor/pkcs7"
 	"github.com/google/uuid"
@@ -36,6 +42,39 @@ type SecureFieldsResponse struct {
 	RespCode PaymentResponseCode `json:"respCode"` // Response code
 }
 
+// MaskedPan returns MaskedCardInfo, see MaskedPan.Brand
+func (r SecureFieldsResponse) MaskedPan() MaskedPan {
+	return MaskedPan(r.MaskedCardInfo)
+}
+
+// ExpiryDate parses ExpMonthCardInfo and ExpYearCardInfo; 2-digit years like "25" are taken as 2025
+func (r SecureFieldsResponse) ExpiryDate() (month, year int, err error) {
+	month, err = strconv.Atoi(strings.TrimSpace(r.ExpMonthCardInfo))
+	if err != nil || month < 1 || month > 12 {
+		return 0, 0, fmt.Errorf("invalid expiry month %q", r.ExpMonthCardInfo)
+	}
+	yearStr := strings.TrimSpace(r.ExpYearCardInfo)
+	year, err = strconv.Atoi(yearStr)
+	if err != nil || year < 0 || (len(yearStr) != 2 && len(yearStr) != 4) {
+		return 0, 0, fmt.Errorf("invalid expiry year %q", r.ExpYearCardInfo)
+	}
+	if len(yearStr) == 2 {
+		year += 2000
+	}
+	return month, year, nil
+}
+
+// IsExpired reports whether the card has expired as of asOf; a card is valid through the end of
+// its expiry month. An unparseable expiry date is treated as expired
+func (r SecureFieldsResponse) IsExpired(asOf time.Time) bool {
+	month, year, err := r.ExpiryDate()
+	if err != nil {
+		return true
+	}
+	firstInvalid := time.Date(year, time.Month(month)+1, 1, 0, 0, 0, 0, asOf.Location())
+	return !asOf.Before(firstInvalid)
+}
+
 // SecureFieldsErrorResponse represents error details from 2C2P Secure Fields
 type SecureFieldsErrorResponse struct {
 	ErrorCode        int    `json:"errCode"`
@@ -55,21 +94,53 @@ type FormValuer interface {
 	PostFormValue(string) string
 }
 
+// SecureFieldsConfig overrides the JavaScript URLs used to render the secure fields form.
+// Empty fields fall back to the built-in sandbox or production defaults.
+type SecureFieldsConfig struct {
+	// Sandbox selects the built-in sandbox defaults instead of production
+	Sandbox bool
+
+	// JSURL is the my2c2p-secureFields script URL
+	JSURL string
+
+	// SecurePayURL is the my2c2p SecurePayment script URL
+	SecurePayURL string
+}
+
+// ScriptURLs returns the URLs for required JavaScript files, preferring the configured overrides
+func (cfg SecureFieldsConfig) ScriptURLs() (secureFieldsJS, securePay string) {
+	secureFieldsJS, securePay = SecureFieldsScriptURLs(cfg.Sandbox)
+	if cfg.JSURL != "" {
+		secureFieldsJS = cfg.JSURL
+	}
+	if cfg.SecurePayURL != "" {
+		securePay = cfg.SecurePayURL
+	}
+	return secureFieldsJS, securePay
+}
+
 // SecureFieldsScriptURLs returns the URLs for required JavaScript files
 // Set sandbox to true for testing environment
+//
+// The production URLs are unconfirmed by 2C2P; use SecureFieldsConfig to supply
+// the URLs your merchant account requires
 func SecureFieldsScriptURLs(sandbox bool) (secureFieldsJS, securePay string) {
 	if sandbox {
 		return "https://2c2p-uat-cloudfront.s3-ap-southeast-1.amazonaws.com/2C2PPGW/secureField/my2c2p-secureFields.1.0.0.min.js",
 			"https://demo2.2c2p.com/2C2PFrontEnd/SecurePayment/api/my2c2p-sandbox.1.7.3.min.js"
 	}
-	// Production URLs - TODO: confirm with 2C2P for production URLs
 	return "https://2c2p-cloudfront.s3-ap-southeast-1.amazonaws.com/2C2PPGW/secureField/my2c2p-secureFields.1.0.0.min.js",
 		"https://2c2p.com/2C2PFrontEnd/SecurePayment/api/my2c2p.1.7.3.min.js"
 }
 
 // SecureFieldsFormHTML generates the HTML template for secure fields form
 func SecureFieldsFormHTML(merchantID, secretKey, formAction string, sandbox bool) string {
-	secureFieldsJS, securePayJS := SecureFieldsScriptURLs(sandbox)
+	return SecureFieldsFormHTMLWithConfig(merchantID, secretKey, formAction, SecureFieldsConfig{Sandbox: sandbox})
+}
+
+// SecureFieldsFormHTMLWithConfig generates the HTML template for secure fields form using the script URLs from cfg
+func SecureFieldsFormHTMLWithConfig(merchantID, secretKey, formAction string, cfg SecureFieldsConfig) string {
+	secureFieldsJS, securePayJS := cfg.ScriptURLs()
 	return `<!DOCTYPE html>
 <html>
 <head>
@@ -149,56 +220,123 @@ func SecureFieldsFormHTML(merchantID, secretKey, formAction string, sandbox bool
 </html>`
 }
 
-func createSignatureString(apiVersion, timestamp, merchantID, invoiceNo string, details SecureFieldsPaymentDetails, encryptedCardInfo string) string {
+// createSignatureString concatenates the signed fields; amount is the 12 digit amt sent in the
+// request, so that the signature and the XML cannot format AmountCents differently
+func createSignatureString(apiVersion, timestamp, merchantID, invoiceNo, amount string, details SecureFieldsPaymentDetails, encryptedCardInfo string) string {
+	ippTransaction, installmentPeriod, interestType := details.installmentFields()
 	// Construct signature string with all fields in the same order as PHP
 	return fmt.Sprintf("%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s",
-		apiVersion,          // version
-		timestamp,           // timestamp
-		merchantID,          // merchantID
-		invoiceNo,           // uniqueTransactionCode
-		details.Description, // desc
-		details.AmountCents.ZeroPrefixed12DCents(), // amt
-		details.CurrencyCode,                       // currencyCode
-		"",                                         // paymentChannel
-		"",                                         // storeCardUniqueID
-		"",                                         // panBank
-		details.CountryCode,                        // country
-		details.CustomerName,                       // cardholderName
-		"",                                         // cardholderEmail
-		"",                                         // payCategoryID
-		details.UserDefined1,                       // userDefined1
-		details.UserDefined2,                       // userDefined2
-		details.UserDefined3,                       // userDefined3
-		details.UserDefined4,                       // userDefined4
-		details.UserDefined5,                       // userDefined5
-		details.StoreCard,                          // storeCard
-		"",                                         // ippTransaction
-		"",                                         // installmentPeriod
-		"",                                         // interestType
-		"",                                         // recurring
-		"",                                         // invoicePrefix
-		"",                                         // recurringAmount
-		"",                                         // allowAccumulate
-		"",                                         // maxAccumulateAmt
-		"",                                         // recurringInterval
-		"",                                         // recurringCount
-		"",                                         // chargeNextDate
-		"",                                         // promotion
-		"Y",                                        // request3DS
-		"",                                         // statementDescriptor
-		"",                                         // agentCode
-		"",                                         // channelCode
-		"",                                         // paymentExpiry
-		"",                                         // mobileNo
-		"",                                         // tokenizeWithoutAuthorization
-		encryptedCardInfo,                          // encryptedCardInfo
+		apiVersion,           // version
+		timestamp,            // timestamp
+		merchantID,           // merchantID
+		invoiceNo,            // uniqueTransactionCode
+		details.Description,  // desc
+		amount,               // amt
+		details.CurrencyCode, // currencyCode
+		"",                   // paymentChannel
+		"",                   // storeCardUniqueID
+		"",                   // panBank
+		details.CountryCode,  // country
+		details.CustomerName, // cardholderName
+		"",                   // cardholderEmail
+		"",                   // payCategoryID
+		details.UserDefined1, // userDefined1
+		details.UserDefined2, // userDefined2
+		details.UserDefined3, // userDefined3
+		details.UserDefined4, // userDefined4
+		details.UserDefined5, // userDefined5
+		details.StoreCard,    // storeCard
+		ippTransaction,       // ippTransaction
+		installmentPeriod,    // installmentPeriod
+		interestType,         // interestType
+		"",                   // recurring
+		"",                   // invoicePrefix
+		"",                   // recurringAmount
+		"",                   // allowAccumulate
+		"",                   // maxAccumulateAmt
+		"",                   // recurringInterval
+		"",                   // recurringCount
+		"",                   // chargeNextDate
+		"",                   // promotion
+		"Y",                  // request3DS
+		"",                   // statementDescriptor
+		"",                   // agentCode
+		"",                   // channelCode
+		"",                   // paymentExpiry
+		"",                   // mobileNo
+		"",                   // tokenizeWithoutAuthorization
+		encryptedCardInfo,    // encryptedCardInfo
 	)
 }
 
-func createHMAC(data, key string) string {
-	h := hmac.New(sha1.New, []byte(key))
+// HashAlgorithm selects the HMAC digest used for the SecureFields secureHash
+type HashAlgorithm string
+
+const (
+	// HashAlgorithmSHA1 is accepted by all SecureFields API versions
+	HashAlgorithmSHA1 HashAlgorithm = "SHA1"
+	// HashAlgorithmSHA256 is required by newer SecureFields API versions
+	HashAlgorithmSHA256 HashAlgorithm = "SHA256"
+)
+
+func (a HashAlgorithm) newHash() (func() hash.Hash, error) {
+	switch a {
+	case "", HashAlgorithmSHA1:
+		return sha1.New, nil
+	case HashAlgorithmSHA256:
+		return sha256.New, nil
+	}
+	return nil, fmt.Errorf("unsupported hash algorithm: %q", string(a))
+}
+
+func createHMAC(alg HashAlgorithm, data, key string) (string, error) {
+	newHash, err := alg.newHash()
+	if err != nil {
+		return "", err
+	}
+	h := hmac.New(newHash, []byte(key))
 	h.Write([]byte(data))
-	return strings.ToUpper(hex.EncodeToString(h.Sum(nil)))
+	return strings.ToUpper(hex.EncodeToString(h.Sum(nil))), nil
+}
+
+// hmacEqual compares two hex encoded digests in constant time, ignoring case
+func hmacEqual(a, b string) bool {
+	aBytes, err := hex.DecodeString(a)
+	if err != nil {
+		return false
+	}
+	bBytes, err := hex.DecodeString(b)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(aBytes, bBytes)
+}
+
+// KeyValue is a named field of a signed message; only Value is part of the signature
+type KeyValue struct {
+	Key   string
+	Value string
+}
+
+// VerifySecureHash reports whether expected is the HMAC of the field values concatenated in
+// the given order, signed with secret. The comparison is constant time. SHA-1 or SHA-256 is
+// chosen by the length of expected. The docs this client follows do not say which fields any
+// 2C2P response signs, so the library does not validate inbound responses with it; fields and
+// their order are up to the caller
+func VerifySecureHash(fields []KeyValue, secret, expected string) bool {
+	var sb strings.Builder
+	for _, field := range fields {
+		sb.WriteString(field.Value)
+	}
+	alg := HashAlgorithmSHA1
+	if len(expected) == hex.EncodedLen(sha256.Size) {
+		alg = HashAlgorithmSHA256
+	}
+	actual, err := createHMAC(alg, sb.String(), secret)
+	if err != nil {
+		return false
+	}
+	return hmacEqual(actual, expected)
 }
 
 // SecureFieldsPaymentPayload contains all required fields to render a 2C2P payment form
@@ -207,19 +345,70 @@ type SecureFieldsPaymentPayload struct {
 	FormFields map[string]string
 }
 
+// DefaultSecureFieldsAPIVersion is the SecureFields API version used when SecureFieldsPaymentDetails.APIVersion is empty
+const DefaultSecureFieldsAPIVersion = "9.4"
+
+// Defaults used for loyalty payments when SecureFieldsPaymentDetails leaves them empty
+const (
+	DefaultLoyaltyProvider = "MCCY"
+	DefaultRedeemCurrency  = "SGD"
+)
+
 type SecureFieldsPaymentDetails struct {
+	// APIVersion is the SecureFields API version; it is part of the signed string
+	// Default: DefaultSecureFieldsAPIVersion
+	APIVersion string
+	// HashAlgorithm is the secureHash digest; match it to what APIVersion expects
+	// Default: HashAlgorithmSHA1
+	HashAlgorithm    HashAlgorithm
 	AmountCents      Cents
 	CurrencyCode     string
 	IsLoyaltyPayment bool
-	Description      string
-	CustomerName     string
-	CountryCode      string
-	StoreCard        string
-	UserDefined1     string
-	UserDefined2     string
-	UserDefined3     string
-	UserDefined4     string
-	UserDefined5     string
+	// LoyaltyProvider identifies the loyalty program, only used when IsLoyaltyPayment
+	// Default: DefaultLoyaltyProvider
+	LoyaltyProvider string
+	// RedeemCurrency is the currency of the redeemed points, only used when IsLoyaltyPayment
+	// Default: DefaultRedeemCurrency
+	RedeemCurrency string
+	// RewardQuantityCents is the reward quantity to redeem, only used when IsLoyaltyPayment
+	// Default: AmountCents
+	RewardQuantityCents Cents
+	Description         string
+	CustomerName        string
+	CountryCode         string
+	// StoreCard is "Y" to store the card or "N" (or empty) not to, like YesNo
+	StoreCard    string
+	UserDefined1 string
+	UserDefined2 string
+	UserDefined3 string
+	UserDefined4 string
+	UserDefined5 string
+	// InstallmentPeriodMonths makes this an installment payment when greater than 0
+	InstallmentPeriodMonths int
+	// InterestType is who pays the installment interest, only used with InstallmentPeriodMonths
+	InterestType PaymentTokenInterestType
+	// InstallmentBankFilter limits the installment banks, only used with InstallmentPeriodMonths.
+	// It is not part of the signed string
+	InstallmentBankFilter []string
+}
+
+// installmentFields returns the ippTransaction, installmentPeriod and interestType values, empty unless
+// details is an installment payment
+func (details SecureFieldsPaymentDetails) installmentFields() (ippTransaction, installmentPeriod, interestType string) {
+	if details.InstallmentPeriodMonths <= 0 {
+		return "", "", ""
+	}
+	return "Y", strconv.Itoa(details.InstallmentPeriodMonths), string(details.InterestType)
+}
+
+// validateStoreCard rejects StoreCard values that would not unmarshal as YesNo
+func (details SecureFieldsPaymentDetails) validateStoreCard() error {
+	switch details.StoreCard {
+	case "", "Y", "N":
+		return nil
+	default:
+		return fmt.Errorf("invalid storeCard %q, expected Y or N", details.StoreCard)
+	}
 }
 
 // PaymentRequest represents the XML structure for a payment request
@@ -244,6 +433,10 @@ type PaymentRequest struct {
 	UserDefined3          string           `xml:"userDefined3"`
 	UserDefined4          string           `xml:"userDefined4"`
 	UserDefined5          string           `xml:"userDefined5"`
+	IppTransaction        string           `xml:"ippTransaction,omitempty"`
+	InstallmentPeriod     string           `xml:"installmentPeriod,omitempty"`
+	InterestType          string           `xml:"interestType,omitempty"`
+	InstallmentBankFilter string           `xml:"installmentBankFilter,omitempty"`
 	IsLoyaltyPayment      YesNo            `xml:"isLoyaltyPayment,omitempty"` // Y or N
 	LoyaltyPayments       *LoyaltyPayments `xml:"loyaltyPayments,omitempty"`
 }
@@ -295,30 +488,46 @@ func (yn *YesNo) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
 	return nil
 }
 
-func CreateSecureFieldsPaymentPayload(c2pURL, merchantID, secretKey, timestamp, invoiceNo string, paymentDetails SecureFieldsPaymentDetails, form FormValuer) SecureFieldsPaymentPayload {
+func CreateSecureFieldsPaymentPayload(c2pURL, merchantID, secretKey, timestamp, invoiceNo string, paymentDetails SecureFieldsPaymentDetails, form FormValuer) (SecureFieldsPaymentPayload, error) {
+	if err := paymentDetails.validateStoreCard(); err != nil {
+		return SecureFieldsPaymentPayload{}, err
+	}
 	encryptedCardInfo := form.PostFormValue("encryptedCardInfo")
+	apiVersion := paymentDetails.APIVersion
+	if apiVersion == "" {
+		apiVersion = DefaultSecureFieldsAPIVersion
+	}
+
+	amount, err := paymentDetails.AmountCents.CheckedZeroPrefixed12DCents()
+	if err != nil {
+		return SecureFieldsPaymentPayload{}, err
+	}
 
 	// Create HMAC signature string
 	strToHash := createSignatureString(
-		"9.4", // API version
+		apiVersion,
 		timestamp,
 		merchantID,
 		invoiceNo,
+		amount,
 		paymentDetails,
 		encryptedCardInfo,
 	)
 
 	// Create HMAC hash
-	hmacHash := createHMAC(strToHash, secretKey)
+	hmacHash, err := createHMAC(paymentDetails.HashAlgorithm, strToHash, secretKey)
+	if err != nil {
+		return SecureFieldsPaymentPayload{}, fmt.Errorf("create secure hash: %w", err)
+	}
 
 	// Create payment request XML
 	paymentRequest := PaymentRequest{
-		Version:               "9.4",
+		Version:               apiVersion,
 		TimeStamp:             timestamp,
 		MerchantID:            merchantID,
 		UniqueTransactionCode: invoiceNo,
 		Description:           paymentDetails.Description,
-		Amount:                paymentDetails.AmountCents.ZeroPrefixed12DCents(),
+		Amount:                amount,
 		CurrencyCode:          paymentDetails.CurrencyCode,
 		PanCountry:            paymentDetails.CountryCode,
 		CardholderName:        paymentDetails.CustomerName,
@@ -332,34 +541,47 @@ func CreateSecureFieldsPaymentPayload(c2pURL, merchantID, secretKey, timestamp,
 		UserDefined4:          paymentDetails.UserDefined4,
 		UserDefined5:          paymentDetails.UserDefined5,
 	}
+	paymentRequest.IppTransaction, paymentRequest.InstallmentPeriod, paymentRequest.InterestType = paymentDetails.installmentFields()
+	if paymentRequest.IppTransaction != "" {
+		paymentRequest.InstallmentBankFilter = strings.Join(paymentDetails.InstallmentBankFilter, ",")
+	}
 
 	if paymentDetails.IsLoyaltyPayment {
+		loyaltyProvider := paymentDetails.LoyaltyProvider
+		if loyaltyProvider == "" {
+			loyaltyProvider = DefaultLoyaltyProvider
+		}
+		redeemCurrency := paymentDetails.RedeemCurrency
+		if redeemCurrency == "" {
+			redeemCurrency = DefaultRedeemCurrency
+		}
+		rewardQuantity := paymentDetails.RewardQuantityCents
+		if rewardQuantity == 0 {
+			rewardQuantity = paymentDetails.AmountCents
+		}
 		paymentRequest.IsLoyaltyPayment = Yes
 		paymentRequest.LoyaltyPayments = &LoyaltyPayments{
 			LoyaltyPayment: []LoyaltyPayment{
 				{
-					LoyaltyProvider: "MCCY",
+					LoyaltyProvider: loyaltyProvider,
 					RedeemAmt:       paymentDetails.AmountCents.ToDollars(),
-					RedeemCurrency:  "SGD", // paymentDetails.CurrencyCode,
+					RedeemCurrency:  redeemCurrency,
 					Redemption: Redemption{
 						Reward: Reward{
 							ID:       uuid.New().String(), // generate random UUID
-							Quantity: paymentDetails.AmountCents.ToDollars(),
+							Quantity: rewardQuantity.ToDollars(),
 						},
 					},
 				},
 			},
 		}
 	}
-	log.Printf("Payment request: %v", paymentRequest)
 
 	// Marshal the payment request to XML
 	xmlBytes, err := xml.Marshal(paymentRequest)
 	if err != nil {
-		log.Printf("Error marshaling payment request to XML: %v", err)
-		return SecureFieldsPaymentPayload{}
+		return SecureFieldsPaymentPayload{}, fmt.Errorf("marshal payment request: %w", err)
 	}
-	log.Printf("Payment request XML: %s", string(xmlBytes))
 
 	// Base64 encode the XML
 	return SecureFieldsPaymentPayload{
@@ -367,58 +589,63 @@ func CreateSecureFieldsPaymentPayload(c2pURL, merchantID, secretKey, timestamp,
 		FormFields: map[string]string{
 			"paymentRequest": base64.StdEncoding.EncodeToString(xmlBytes),
 		},
+	}, nil
+}
+
+// SecurePaymentFormOptions are the optional fields of BuildSecurePaymentFormWithOptions
+type SecurePaymentFormOptions struct {
+	// Timestamp is the timeStamp of the request. Default: the Unix time of the client's Clock
+	Timestamp string
+
+	// InvoiceNo is the uniqueTransactionCode of the request. Default: GenerateInvoiceNo
+	InvoiceNo string
+}
+
+// BuildSecurePaymentForm signs the SecureFields payment request with the client's
+// MerchantID and SecretKey, returning the form to auto-submit to the client's FrontendURL.
+// encryptedCardInfo is the `encryptedCardInfo` value posted by the SecureFields form
+func (c *Client) BuildSecurePaymentForm(details SecureFieldsPaymentDetails, encryptedCardInfo string) (SecureFieldsPaymentPayload, error) {
+	return c.BuildSecurePaymentFormWithOptions(details, encryptedCardInfo, SecurePaymentFormOptions{})
+}
+
+// BuildSecurePaymentFormWithOptions is BuildSecurePaymentForm with the optional fields in opts
+func (c *Client) BuildSecurePaymentFormWithOptions(details SecureFieldsPaymentDetails, encryptedCardInfo string, opts SecurePaymentFormOptions) (SecureFieldsPaymentPayload, error) {
+	if opts.Timestamp == "" {
+		opts.Timestamp = strconv.FormatInt(c.now().Unix(), 10)
+	}
+	if opts.InvoiceNo == "" {
+		opts.InvoiceNo = c.GenerateInvoiceNo()
 	}
+	form := formValues{"encryptedCardInfo": encryptedCardInfo}
+	return CreateSecureFieldsPaymentPayload(c.FrontendURL, c.MerchantID, c.SecretKey, opts.Timestamp, opts.InvoiceNo, details, form)
+}
+
+// formValues is a FormValuer backed by a map
+type formValues map[string]string
+
+func (f formValues) PostFormValue(key string) string {
+	return f[key]
 }
 
-type PaymentResponseBackEnd struct {
-	XMLName               xml.Name            `xml:"PaymentResponse"`
-	Version               string              `xml:"version"`
-	TimeStamp             string              `xml:"timeStamp"`
-	MerchantID            string              `xml:"merchantID"`
-	RespCode              PaymentResponseCode `xml:"respCode"`
-	PAN                   string              `xml:"pan"`
-	Amount                string              `xml:"amt"`
-	UniqueTransactionCode string              `xml:"uniqueTransactionCode"`
-	TranRef               string              `xml:"tranRef"`
-	ApprovalCode          string              `xml:"approvalCode"`
-	RefNumber             string              `xml:"refNumber"`
-	ECI                   string              `xml:"eci"`
-	DateTime              string              `xml:"dateTime"`
-	Status                string              `xml:"status"`
-	FailReason            string              `xml:"failReason"` // can contain successful reason too
-	UserDefined1          string              `xml:"userDefined1"`
-	UserDefined2          string              `xml:"userDefined2"`
-	UserDefined3          string              `xml:"userDefined3"`
-	UserDefined4          string              `xml:"userDefined4"`
-	UserDefined5          string              `xml:"userDefined5"`
-	IPPPeriod             string              `xml:"ippPeriod"`
-	IPPInterestType       string              `xml:"ippInterestType"`
-	IPPInterestRate       string              `xml:"ippInterestRate"`
-	IPPMerchantAbsorbRate string              `xml:"ippMerchantAbsorbRate"`
-	PaidChannel           string              `xml:"paidChannel"`
-	PaidAgent             string              `xml:"paidAgent"`
-	PaymentChannel        string              `xml:"paymentChannel"`
-	BackendInvoice        string              `xml:"backendInvoice"`
-	IssuerCountry         string              `xml:"issuerCountry"`
-	IssuerCountryA3       string              `xml:"issuerCountryA3"`
-	BankName              string              `xml:"bankName"`
-	CardType              string              `xml:"cardType"`
-	ProcessBy             string              `xml:"processBy"`
-	PaymentScheme         string              `xml:"paymentScheme"`
-	PaymentID             string              `xml:"paymentID"`
-	AcquirerResponseCode  string              `xml:"acquirerResponseCode"`
-	SchemePaymentID       string              `xml:"schemePaymentID"`
-	HashValue             string              `xml:"hashValue"`
-}
-
-// DecryptPaymentResponseBackend decrypts and parses the payment response from 2C2P
+// DecryptPaymentResponseBackend decrypts and parses the payment response from 2C2P.
+// The `paymentResponse` form value is usually base64 PKCS7, but a compact JWS wrapping a JWE,
+// as used by refunds, is also accepted and its signature verified. Decryption failures are
+// returned as a *PaymentResponseFormatError naming the detected format
 func (c *Client) DecryptPaymentResponseBackend(r FormValuer) (PaymentResponseBackEnd, []byte, error) {
 	encryptedResponse := r.PostFormValue("paymentResponse")
 
 	// Decrypt the response
-	decrypted, err := decryptPKCS7([]byte(encryptedResponse), c.PrivateKey, c.PublicCert)
+	var decrypted []byte
+	var err error
+	format := detectPaymentResponseFormat(encryptedResponse)
+	switch format {
+	case PaymentResponseFormatJWS:
+		decrypted, err = c.verifyJWSAndDecryptJWE(encryptedResponse)
+	default:
+		decrypted, err = c.DecryptPKCS7([]byte(encryptedResponse))
+	}
 	if err != nil {
-		return PaymentResponseBackEnd{}, nil, fmt.Errorf("error decrypting response: %w", err)
+		return PaymentResponseBackEnd{}, nil, &PaymentResponseFormatError{Format: format, Err: err}
 	}
 
 	// Parse XML response
@@ -431,15 +658,71 @@ func (c *Client) DecryptPaymentResponseBackend(r FormValuer) (PaymentResponseBac
 	return response, decrypted, nil
 }
 
-// decryptPKCS7 decrypts base64-encoded PKCS7 enveloped data using certificate and private key from PEM data.
-// The combinedPEM must contain both a private key (PKCS8) and certificate in PEM format.
-func decryptPKCS7(encryptedData []byte, privateKey *rsa.PrivateKey, publicCert *x509.Certificate) ([]byte, error) {
+// detectPaymentResponseFormat tells a compact JWS (three base64url segments joined by dots)
+// from base64 PKCS7, whose standard alphabet has no dots
+func detectPaymentResponseFormat(s string) PaymentResponseFormat {
+	if strings.Count(s, ".") == 2 {
+		return PaymentResponseFormatJWS
+	}
+	return PaymentResponseFormatPKCS7
+}
+
+// DecryptPKCS7 decrypts base64-encoded PKCS7 enveloped data, e.g. the `paymentResponse` form value,
+// using the client's private key and certificate
+func (c *Client) DecryptPKCS7(encryptedData []byte) ([]byte, error) {
+	if c.closed.Load() {
+		return nil, ErrClientClosed
+	}
+	return decryptPKCS7(encryptedData, c.PrivateKey, c.PublicCert)
+}
+
+// DecryptPKCS7Reader is DecryptPKCS7 for a base64 body read from r, e.g. a request body.
+// The base64 is decoded as it is read, so the encoded text is never held in memory; the
+// PKCS7 parser needs the whole decoded envelope though, so that is still buffered
+func (c *Client) DecryptPKCS7Reader(r io.Reader) (io.Reader, error) {
+	if c.closed.Load() {
+		return nil, ErrClientClosed
+	}
+	if _, ok := c.PrivateKey.(*rsa.PrivateKey); !ok {
+		return nil, fmt.Errorf("decrypt PKCS7: %w", ErrRSAKeyRequired)
+	}
+	decodedData, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, r))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode base64 data: %v", err)
+	}
+	decrypted, err := decryptPKCS7Envelope(decodedData, c.PrivateKey, c.PublicCert)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(decrypted), nil
+}
+
+// DecryptPKCS7 decrypts base64-encoded PKCS7 enveloped data without a Client.
+// The combinedPEM must contain both a private key and certificate in PEM format.
+func DecryptPKCS7(encryptedData, combinedPEM []byte) ([]byte, error) {
+	privateKey, publicCert, err := loadPrivateKeyAndCert(combinedPEM, "")
+	if err != nil {
+		return nil, err
+	}
+	return decryptPKCS7(encryptedData, privateKey, publicCert)
+}
+
+func decryptPKCS7(encryptedData []byte, privateKey crypto.PrivateKey, publicCert *x509.Certificate) ([]byte, error) {
+	if _, ok := privateKey.(*rsa.PrivateKey); !ok {
+		return nil, fmt.Errorf("decrypt PKCS7: %w", ErrRSAKeyRequired)
+	}
+
 	// Decode base64 data
 	decodedData, err := base64.StdEncoding.DecodeString(string(encryptedData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode base64 data: %v", err)
 	}
 
+	return decryptPKCS7Envelope(decodedData, privateKey, publicCert)
+}
+
+// decryptPKCS7Envelope decrypts DER PKCS7 enveloped data
+func decryptPKCS7Envelope(decodedData []byte, privateKey crypto.PrivateKey, publicCert *x509.Certificate) ([]byte, error) {
 	// Parse the PKCS7 data
 	p7, err := pkcs7.Parse(decodedData)
 	if err != nil {
@@ -1,18 +1,25 @@
 package api2c2p
 
 import (
+	"bytes"
 	"crypto/hmac"
 	"crypto/sha1"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"hash"
+	"io"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/fullsailor/pkcs7"
 )
@@ -25,19 +32,16 @@ import (
 // If a test fails because the expected XML needs to be updated, the test will
 // write the actual decrypted result to the .xml file, making it pass on the next run.
 func TestDecryptPaymentResponse(t *testing.T) {
-	client, err := NewClient(Config{
-		SecretKey:                "test_secret",
-		MerchantID:               "JT01",
-		PaymentGatewayURL:        "https://pgw.example.com",
-		FrontendURL:              "https://frontend.example.com",
-		CombinedPEM:              "testdata/combined_private_public.pem",
-		ServerJWTPublicKeyFile:   "testdata/server.jwt.public_cert.pem",
-		ServerPKCS7PublicKeyFile: "testdata/server.pkcs7.public_cert.pem",
-	})
+	client, err := NewClient(testConfig())
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
 	}
 
+	combinedPEM, err := os.ReadFile("testdata/combined_private_public.pem")
+	if err != nil {
+		t.Fatalf("Failed to read combined PEM: %v", err)
+	}
+
 	// Find all encrypted test data files
 	matches, err := filepath.Glob("testdata/payment-response-*.txt")
 	if err != nil {
@@ -59,7 +63,7 @@ func TestDecryptPaymentResponse(t *testing.T) {
 			}
 
 			// Decrypt the data
-			got, err := decryptPKCS7(encryptedData, client.PrivateKey, client.PublicCert)
+			got, err := client.DecryptPKCS7(encryptedData)
 			if err != nil {
 				t.Fatalf("Failed to decrypt data: %v", err)
 			}
@@ -74,20 +78,79 @@ func TestDecryptPaymentResponse(t *testing.T) {
 			if string(want) != string(got) {
 				t.Errorf("Decrypted result does not match %s.\nGot:\n%s\nWant:\n%s", expectedFile, got, string(want))
 			}
+
+			// The package-level function gives the same result from the PEM alone
+			got, err = DecryptPKCS7(encryptedData, combinedPEM)
+			if err != nil {
+				t.Fatalf("Failed to decrypt data with combined PEM: %v", err)
+			}
+			if string(want) != string(got) {
+				t.Errorf("Decrypted result with combined PEM does not match %s.\nGot:\n%s\nWant:\n%s", expectedFile, got, string(want))
+			}
+
+			// The reader variant too
+			reader, err := client.DecryptPKCS7Reader(bytes.NewReader(encryptedData))
+			if err != nil {
+				t.Fatalf("Failed to decrypt data from reader: %v", err)
+			}
+			if got, err = io.ReadAll(reader); err != nil || string(want) != string(got) {
+				t.Errorf("Decrypted result from reader does not match %s.\nGot:\n%s\nWant:\n%s", expectedFile, got, string(want))
+			}
 		})
 	}
 }
 
+// benchmarkPKCS7Payload returns a client and a base64 PKCS7 envelope of about 1MB of XML for it
+func benchmarkPKCS7Payload(b *testing.B) (*Client, []byte) {
+	b.Helper()
+	client, err := NewClient(testConfig())
+	if err != nil {
+		b.Fatalf("Failed to create client: %v", err)
+	}
+	xmlData := "<PaymentResponse>" + strings.Repeat("<userDefined1>x</userDefined1>", 1<<15) + "</PaymentResponse>"
+	encrypted, err := pkcs7.Encrypt([]byte(xmlData), []*x509.Certificate{client.PublicCert})
+	if err != nil {
+		b.Fatalf("Failed to encrypt data: %v", err)
+	}
+	return client, []byte(base64.StdEncoding.EncodeToString(encrypted))
+}
+
+func BenchmarkDecryptPKCS7(b *testing.B) {
+	client, encoded := benchmarkPKCS7Payload(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.DecryptPKCS7(encoded); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecryptPKCS7Reader(b *testing.B) {
+	client, encoded := benchmarkPKCS7Payload(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.DecryptPKCS7Reader(bytes.NewReader(encoded)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestDecryptPKCS7WithoutPrivateKey(t *testing.T) {
+	certOnly, err := os.ReadFile("testdata/public_cert.pem")
+	if err != nil {
+		t.Fatalf("Failed to read certificate: %v", err)
+	}
+	if _, err := DecryptPKCS7([]byte("AAAA"), certOnly); err == nil {
+		t.Error("Expected error when combined PEM has no private key")
+	}
+}
+
 func TestDecryptPaymentResponseWithXML(t *testing.T) {
-	client, err := NewClient(Config{
-		SecretKey:                "test_secret",
-		MerchantID:               "JT01",
-		PaymentGatewayURL:        "https://pgw.example.com",
-		FrontendURL:              "https://frontend.example.com",
-		CombinedPEM:              "testdata/combined_private_public.pem",
-		ServerJWTPublicKeyFile:   "testdata/public_cert.pem", // we have to decrypt what we encrypted in this test
-		ServerPKCS7PublicKeyFile: "testdata/server.pkcs7.public_cert.pem",
-	})
+	cfg := testConfig()
+	cfg.ServerJWTPublicKeyFile = "testdata/public_cert.pem" // we have to decrypt what we encrypted in this test
+	client, err := NewClient(cfg)
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
 	}
@@ -154,6 +217,55 @@ func TestDecryptPaymentResponseWithXML(t *testing.T) {
 	}
 }
 
+func TestDecryptPaymentResponseJWS(t *testing.T) {
+	cfg := testConfig()
+	cfg.ServerJWTPublicKeyFile = "testdata/public_cert.pem" // we have to verify what we signed in this test
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	xmlData, err := xml.Marshal(PaymentResponseBackEnd{RespCode: "00", Status: "A"})
+	if err != nil {
+		t.Fatalf("Failed to marshal XML: %v", err)
+	}
+	signed, err := client.encryptJWEAndSignJWS(xmlData)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+
+	response, decrypted, err := client.DecryptPaymentResponseBackend(mockFormValuer{values: map[string]string{"paymentResponse": signed}})
+	if err != nil {
+		t.Fatalf("DecryptPaymentResponseBackend failed: %v", err)
+	}
+	if string(decrypted) != string(xmlData) {
+		t.Errorf("Expected decrypted %s, got %s", xmlData, decrypted)
+	}
+	if !response.IsSuccess() {
+		t.Errorf("Expected successful response, got %+v", response)
+	}
+
+	testCases := []struct {
+		name       string
+		value      string
+		wantFormat PaymentResponseFormat
+	}{
+		{name: "tampered JWS", value: signed[:len(signed)-4] + "AAAA", wantFormat: PaymentResponseFormatJWS},
+		{name: "invalid PKCS7", value: "invalid base64", wantFormat: PaymentResponseFormatPKCS7},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := client.DecryptPaymentResponseBackend(mockFormValuer{values: map[string]string{"paymentResponse": tc.value}})
+			var formatErr *PaymentResponseFormatError
+			if !errors.As(err, &formatErr) {
+				t.Fatalf("Expected *PaymentResponseFormatError, got %v", err)
+			}
+			if formatErr.Format != tc.wantFormat {
+				t.Errorf("Expected format %s, got %s", tc.wantFormat, formatErr.Format)
+			}
+		})
+	}
+}
+
 // mockFormValuer implements FormValuer for testing
 type mockFormValuer struct {
 	values map[string]string
@@ -189,7 +301,10 @@ func TestCreatePaymentPayload(t *testing.T) {
 	}
 
 	// Call function
-	payload := CreateSecureFieldsPaymentPayload("http://localhost:8080", merchantID, secretKey, timestamp, invoiceNo, paymentDetails, form)
+	payload, err := CreateSecureFieldsPaymentPayload("http://localhost:8080", merchantID, secretKey, timestamp, invoiceNo, paymentDetails, form)
+	if err != nil {
+		t.Fatalf("Failed to create payment payload: %v", err)
+	}
 
 	// Decode base64
 	xmlBytes, err := base64.StdEncoding.DecodeString(payload.FormFields["paymentRequest"])
@@ -289,3 +404,509 @@ func TestCreatePaymentPayload(t *testing.T) {
 		t.Errorf("Expected secureHash %q, got %q", expectedHash, secureHash)
 	}
 }
+
+func TestCreatePaymentPayloadAPIVersion(t *testing.T) {
+	paymentDetails := SecureFieldsPaymentDetails{
+		APIVersion:   "9.9",
+		AmountCents:  9910,
+		CurrencyCode: "702",
+		Description:  "1 room for 2 nights",
+	}
+	form := mockFormValuer{
+		values: map[string]string{
+			"encryptedCardInfo": "ENCRYPTED_CARD_DATA",
+		},
+	}
+
+	payload, err := CreateSecureFieldsPaymentPayload("http://localhost:8080", "MERCHANT123", "SECRET456", "1707210770", "INV1707210770", paymentDetails, form)
+	if err != nil {
+		t.Fatalf("Failed to create payment payload: %v", err)
+	}
+	xmlBytes, err := base64.StdEncoding.DecodeString(payload.FormFields["paymentRequest"])
+	if err != nil {
+		t.Fatalf("Failed to decode base64: %v", err)
+	}
+	xmlStr := string(xmlBytes)
+
+	if !strings.Contains(xmlStr, "<version>9.9</version>") {
+		t.Errorf("Expected XML to contain version 9.9\nXML: %s", xmlStr)
+	}
+
+	strToHash := createSignatureString("9.9", "1707210770", "MERCHANT123", "INV1707210770", paymentDetails.AmountCents.ZeroPrefixed12DCents(), paymentDetails, "ENCRYPTED_CARD_DATA")
+	if !strings.HasPrefix(strToHash, "9.9") {
+		t.Errorf("Expected signature string to start with version 9.9, got %q", strToHash)
+	}
+	expectedHash, err := createHMAC(HashAlgorithmSHA1, strToHash, "SECRET456")
+	if err != nil {
+		t.Fatalf("Failed to create HMAC: %v", err)
+	}
+	if !strings.Contains(xmlStr, "<secureHash>"+expectedHash+"</secureHash>") {
+		t.Errorf("Expected secureHash %q signed with version 9.9\nXML: %s", expectedHash, xmlStr)
+	}
+}
+
+func TestCreatePaymentPayloadLargeAmount(t *testing.T) {
+	paymentDetails := SecureFieldsPaymentDetails{
+		AmountCents:  Cents(99999999999),
+		CurrencyCode: "702",
+		Description:  "1 room for 2 nights",
+	}
+	form := mockFormValuer{
+		values: map[string]string{
+			"encryptedCardInfo": "ENCRYPTED_CARD_DATA",
+		},
+	}
+
+	payload, err := CreateSecureFieldsPaymentPayload("http://localhost:8080", "MERCHANT123", "SECRET456", "1707210770", "INV1707210770", paymentDetails, form)
+	if err != nil {
+		t.Fatalf("Failed to create payment payload: %v", err)
+	}
+	xmlBytes, err := base64.StdEncoding.DecodeString(payload.FormFields["paymentRequest"])
+	if err != nil {
+		t.Fatalf("Failed to decode base64: %v", err)
+	}
+	var request PaymentRequest
+	if err := xml.Unmarshal(xmlBytes, &request); err != nil {
+		t.Fatalf("Failed to unmarshal XML: %v", err)
+	}
+	if request.Amount != "099999999999" {
+		t.Errorf("Expected amt 099999999999, got %s", request.Amount)
+	}
+
+	strToHash := createSignatureString(DefaultSecureFieldsAPIVersion, "1707210770", "MERCHANT123", "INV1707210770", request.Amount, paymentDetails, "ENCRYPTED_CARD_DATA")
+	expectedHash, err := createHMAC(HashAlgorithmSHA1, strToHash, "SECRET456")
+	if err != nil {
+		t.Fatalf("Failed to create HMAC: %v", err)
+	}
+	if request.SecureHash != expectedHash {
+		t.Errorf("Expected secureHash %s signed with amt %s, got %s", expectedHash, request.Amount, request.SecureHash)
+	}
+
+	paymentDetails.AmountCents = MaxZeroPrefixed12DCents + 1
+	if _, err := CreateSecureFieldsPaymentPayload("http://localhost:8080", "MERCHANT123", "SECRET456", "1707210770", "INV1707210770", paymentDetails, form); err == nil {
+		t.Error("Expected error for an amount over 12 digits, got nil")
+	}
+}
+
+func TestCreatePaymentPayloadHashAlgorithm(t *testing.T) {
+	testCases := []struct {
+		name      string
+		algorithm HashAlgorithm
+		wantLen   int
+		wantErr   bool
+	}{
+		{name: "default", wantLen: 40},
+		{name: "SHA1", algorithm: HashAlgorithmSHA1, wantLen: 40},
+		{name: "SHA256", algorithm: HashAlgorithmSHA256, wantLen: 64},
+		{name: "unsupported", algorithm: "MD5", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			paymentDetails := SecureFieldsPaymentDetails{
+				AmountCents:   9910,
+				CurrencyCode:  "702",
+				Description:   "1 room for 2 nights",
+				HashAlgorithm: tc.algorithm,
+			}
+			form := mockFormValuer{
+				values: map[string]string{
+					"encryptedCardInfo": "ENCRYPTED_CARD_DATA",
+				},
+			}
+
+			payload, err := CreateSecureFieldsPaymentPayload("http://localhost:8080", "MERCHANT123", "SECRET456", "1707210770", "INV1707210770", paymentDetails, form)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("Expected error for unsupported hash algorithm")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to create payment payload: %v", err)
+			}
+			xmlBytes, err := base64.StdEncoding.DecodeString(payload.FormFields["paymentRequest"])
+			if err != nil {
+				t.Fatalf("Failed to decode base64: %v", err)
+			}
+
+			strToHash := createSignatureString(DefaultSecureFieldsAPIVersion, "1707210770", "MERCHANT123", "INV1707210770", paymentDetails.AmountCents.ZeroPrefixed12DCents(), paymentDetails, "ENCRYPTED_CARD_DATA")
+			var mac hash.Hash
+			if tc.wantLen == 64 {
+				mac = hmac.New(sha256.New, []byte("SECRET456"))
+			} else {
+				mac = hmac.New(sha1.New, []byte("SECRET456"))
+			}
+			mac.Write([]byte(strToHash))
+			expectedHash := strings.ToUpper(hex.EncodeToString(mac.Sum(nil)))
+			if len(expectedHash) != tc.wantLen {
+				t.Fatalf("Expected hash length %d, got %d", tc.wantLen, len(expectedHash))
+			}
+			if !strings.Contains(string(xmlBytes), "<secureHash>"+expectedHash+"</secureHash>") {
+				t.Errorf("Expected secureHash %q\nXML: %s", expectedHash, xmlBytes)
+			}
+		})
+	}
+}
+
+func TestCreatePaymentPayloadInstallment(t *testing.T) {
+	paymentDetails := SecureFieldsPaymentDetails{
+		AmountCents:             60000,
+		CurrencyCode:            "764",
+		Description:             "Television",
+		CountryCode:             "TH",
+		CustomerName:            "John Doe",
+		InstallmentPeriodMonths: 6,
+		InterestType:            InterestTypeMerchant,
+		InstallmentBankFilter:   []string{"KTC"},
+	}
+	form := mockFormValuer{
+		values: map[string]string{
+			"encryptedCardInfo": "ENCRYPTED_CARD_DATA",
+		},
+	}
+
+	payload, err := CreateSecureFieldsPaymentPayload("http://localhost:8080", "MERCHANT123", "SECRET456", "1707210770", "INV1707210770", paymentDetails, form)
+	if err != nil {
+		t.Fatalf("Failed to create payment payload: %v", err)
+	}
+	xmlBytes, err := base64.StdEncoding.DecodeString(payload.FormFields["paymentRequest"])
+	if err != nil {
+		t.Fatalf("Failed to decode base64: %v", err)
+	}
+	xmlStr := string(xmlBytes)
+
+	for _, exp := range []string{
+		"<ippTransaction>Y</ippTransaction>",
+		"<installmentPeriod>6</installmentPeriod>",
+		"<interestType>M</interestType>",
+		"<installmentBankFilter>KTC</installmentBankFilter>",
+	} {
+		if !strings.Contains(xmlStr, exp) {
+			t.Errorf("Expected XML to contain %q, but it didn't\nXML: %s", exp, xmlStr)
+		}
+	}
+
+	// the installment fields take the ippTransaction, installmentPeriod and interestType
+	// positions of the signed string; installmentBankFilter is not signed
+	strToHash := strings.Join([]string{
+		"9.4", "1707210770", "MERCHANT123", "INV1707210770", "Television", "000000060000", "764",
+		"", "", "", // paymentChannel, storeCardUniqueID, panBank
+		"TH", "John Doe",
+		"", "", // cardholderEmail, payCategoryID
+		"", "", "", "", "", // userDefined1-5
+		"",            // storeCard
+		"Y", "6", "M", // ippTransaction, installmentPeriod, interestType
+		"", "", "", "", "", "", "", "", "", // recurring ... promotion
+		"Y",        // request3DS
+		"", "", "", // statementDescriptor, agentCode, channelCode
+		"", "", "", // paymentExpiry, mobileNo, tokenizeWithoutAuthorization
+		"ENCRYPTED_CARD_DATA",
+	}, "")
+	mac := hmac.New(sha1.New, []byte("SECRET456"))
+	mac.Write([]byte(strToHash))
+	expectedHash := strings.ToUpper(hex.EncodeToString(mac.Sum(nil)))
+	if !strings.Contains(xmlStr, "<secureHash>"+expectedHash+"</secureHash>") {
+		t.Errorf("Expected secureHash %q\nXML: %s", expectedHash, xmlStr)
+	}
+
+	// without an installment period the installment elements are omitted
+	paymentDetails.InstallmentPeriodMonths = 0
+	payload, err = CreateSecureFieldsPaymentPayload("http://localhost:8080", "MERCHANT123", "SECRET456", "1707210770", "INV1707210770", paymentDetails, form)
+	if err != nil {
+		t.Fatalf("Failed to create payment payload: %v", err)
+	}
+	xmlBytes, err = base64.StdEncoding.DecodeString(payload.FormFields["paymentRequest"])
+	if err != nil {
+		t.Fatalf("Failed to decode base64: %v", err)
+	}
+	for _, unexpected := range []string{"ippTransaction", "installmentPeriod", "interestType", "installmentBankFilter"} {
+		if strings.Contains(string(xmlBytes), unexpected) {
+			t.Errorf("Expected XML without %s\nXML: %s", unexpected, xmlBytes)
+		}
+	}
+}
+
+func TestSecureFieldsConfigScriptURLs(t *testing.T) {
+	defaultJS, defaultPay := SecureFieldsScriptURLs(false)
+
+	// empty overrides fall back to defaults
+	js, pay := SecureFieldsConfig{}.ScriptURLs()
+	if js != defaultJS || pay != defaultPay {
+		t.Errorf("Expected default URLs %q %q, got %q %q", defaultJS, defaultPay, js, pay)
+	}
+
+	cfg := SecureFieldsConfig{
+		JSURL:        "https://cdn.example.com/secureFields.js",
+		SecurePayURL: "https://cdn.example.com/securePay.js",
+	}
+	js, pay = cfg.ScriptURLs()
+	if js != cfg.JSURL || pay != cfg.SecurePayURL {
+		t.Errorf("Expected override URLs %q %q, got %q %q", cfg.JSURL, cfg.SecurePayURL, js, pay)
+	}
+
+	html := SecureFieldsFormHTMLWithConfig("JT01", "secret", "/process-payment", cfg)
+	for _, want := range []string{cfg.JSURL, cfg.SecurePayURL} {
+		if !strings.Contains(html, `src="`+want+`"`) {
+			t.Errorf("Expected form HTML to load %q", want)
+		}
+	}
+}
+
+func TestCreatePaymentPayloadLoyalty(t *testing.T) {
+	form := mockFormValuer{
+		values: map[string]string{
+			"encryptedCardInfo": "ENCRYPTED_CARD_DATA",
+		},
+	}
+	testCases := []struct {
+		name     string
+		details  SecureFieldsPaymentDetails
+		expected []string
+	}{
+		{
+			name: "defaults",
+			details: SecureFieldsPaymentDetails{
+				AmountCents:      9910,
+				CurrencyCode:     "702",
+				IsLoyaltyPayment: true,
+			},
+			expected: []string{
+				"<loyaltyProvider>MCCY</loyaltyProvider>",
+				"<redeemAmt>99.10</redeemAmt>",
+				"<redeemCurrency>SGD</redeemCurrency>",
+				"<quantity>99.10</quantity>",
+			},
+		},
+		{
+			name: "custom provider and currency",
+			details: SecureFieldsPaymentDetails{
+				AmountCents:         9910,
+				CurrencyCode:        "764",
+				IsLoyaltyPayment:    true,
+				LoyaltyProvider:     "TRUE",
+				RedeemCurrency:      "THB",
+				RewardQuantityCents: 500,
+			},
+			expected: []string{
+				"<loyaltyProvider>TRUE</loyaltyProvider>",
+				"<redeemAmt>99.10</redeemAmt>",
+				"<redeemCurrency>THB</redeemCurrency>",
+				"<quantity>5.00</quantity>",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			payload, err := CreateSecureFieldsPaymentPayload("http://localhost:8080", "MERCHANT123", "SECRET456", "1707210770", "INV1707210770", tc.details, form)
+			if err != nil {
+				t.Fatalf("Failed to create payment payload: %v", err)
+			}
+			xmlBytes, err := base64.StdEncoding.DecodeString(payload.FormFields["paymentRequest"])
+			if err != nil {
+				t.Fatalf("Failed to decode base64: %v", err)
+			}
+			xmlStr := string(xmlBytes)
+			for _, exp := range tc.expected {
+				if !strings.Contains(xmlStr, exp) {
+					t.Errorf("Expected XML to contain %q, but it didn't\nXML: %s", exp, xmlStr)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildSecurePaymentForm(t *testing.T) {
+	cfg := testConfig()
+	cfg.SecretKey = "SECRET456"
+	cfg.MerchantID = "MERCH123"
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	paymentDetails := SecureFieldsPaymentDetails{
+		AmountCents:  9910,
+		CurrencyCode: "702",
+		Description:  "1 room for 2 nights",
+		CustomerName: "John Doe",
+		CountryCode:  "SG",
+	}
+	form := mockFormValuer{
+		values: map[string]string{
+			"encryptedCardInfo": "ENCRYPTED_CARD_DATA",
+		},
+	}
+
+	got, err := client.BuildSecurePaymentFormWithOptions(paymentDetails, "ENCRYPTED_CARD_DATA", SecurePaymentFormOptions{
+		Timestamp: "1707210770",
+		InvoiceNo: "INV1707210770",
+	})
+	if err != nil {
+		t.Fatalf("Failed to build payment form: %v", err)
+	}
+	want, err := CreateSecureFieldsPaymentPayload("https://frontend.example.com", "MERCH123", "SECRET456", "1707210770", "INV1707210770", paymentDetails, form)
+	if err != nil {
+		t.Fatalf("Failed to create payment payload: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected payload %#v, got %#v", want, got)
+	}
+}
+
+func TestBuildSecurePaymentFormClock(t *testing.T) {
+	cfg := testConfig()
+	cfg.SecretKey = "SECRET456"
+	cfg.MerchantID = "MERCH123"
+	cfg.Clock = func() time.Time { return time.Unix(1707210770, 0) }
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	paymentDetails := SecureFieldsPaymentDetails{
+		AmountCents:  9910,
+		CurrencyCode: "702",
+		Description:  "1 room for 2 nights",
+		CustomerName: "John Doe",
+		CountryCode:  "SG",
+	}
+	form := mockFormValuer{
+		values: map[string]string{
+			"encryptedCardInfo": "ENCRYPTED_CARD_DATA",
+		},
+	}
+
+	want, err := CreateSecureFieldsPaymentPayload("https://frontend.example.com", "MERCH123", "SECRET456", "1707210770", "INV1707210770", paymentDetails, form)
+	if err != nil {
+		t.Fatalf("Failed to create payment payload: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		got, err := client.BuildSecurePaymentFormWithOptions(paymentDetails, "ENCRYPTED_CARD_DATA", SecurePaymentFormOptions{InvoiceNo: "INV1707210770"})
+		if err != nil {
+			t.Fatalf("Failed to build payment form: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected payload %#v, got %#v", want, got)
+		}
+	}
+
+	// without options, the timestamp and invoice number come from the client's Clock and InvoiceNoGenerator
+	got, err := client.BuildSecurePaymentForm(paymentDetails, "ENCRYPTED_CARD_DATA")
+	if err != nil {
+		t.Fatalf("Failed to build payment form: %v", err)
+	}
+	xmlData, err := base64.StdEncoding.DecodeString(got.FormFields["paymentRequest"])
+	if err != nil {
+		t.Fatalf("Failed to decode payment request: %v", err)
+	}
+	var request PaymentRequest
+	if err := xml.Unmarshal(xmlData, &request); err != nil {
+		t.Fatalf("Failed to unmarshal payment request: %v", err)
+	}
+	if request.TimeStamp != "1707210770" {
+		t.Errorf("Expected timeStamp 1707210770, got %s", request.TimeStamp)
+	}
+	if !strings.HasPrefix(request.UniqueTransactionCode, "INV1707210770") {
+		t.Errorf("Expected uniqueTransactionCode to start with INV1707210770, got %s", request.UniqueTransactionCode)
+	}
+}
+
+func TestVerifySecureHash(t *testing.T) {
+	fields := []KeyValue{
+		{Key: "version", Value: "9.4"},
+		{Key: "merchantID", Value: "MERCHANT123"},
+		{Key: "respCode", Value: "00"},
+	}
+	valid, err := createHMAC(HashAlgorithmSHA1, "9.4MERCHANT12300", "SECRET456")
+	if err != nil {
+		t.Fatalf("Failed to create HMAC: %v", err)
+	}
+	valid256, err := createHMAC(HashAlgorithmSHA256, "9.4MERCHANT12300", "SECRET456")
+	if err != nil {
+		t.Fatalf("Failed to create HMAC: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		secret   string
+		expected string
+		want     bool
+	}{
+		{"matching", "SECRET456", valid, true},
+		{"matching lowercase", "SECRET456", strings.ToLower(valid), true},
+		{"wrong secret", "WRONG", valid, false},
+		{"mismatching", "SECRET456", strings.Repeat("0", len(valid)), false},
+		{"shorter", "SECRET456", valid[:len(valid)-2], false},
+		{"longer", "SECRET456", valid + "00", false},
+		{"not hex", "SECRET456", "not-a-hash", false},
+		{"empty", "SECRET456", "", false},
+		{"matching SHA256", "SECRET456", valid256, true},
+		{"SHA256 wrong secret", "WRONG", valid256, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := VerifySecureHash(fields, tc.secret, tc.expected); got != tc.want {
+				t.Errorf("Expected VerifySecureHash %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCreatePaymentPayloadStoreCard(t *testing.T) {
+	form := mockFormValuer{values: map[string]string{"encryptedCardInfo": "ENCRYPTED_CARD_DATA"}}
+	for _, storeCard := range []string{"", "Y", "N"} {
+		details := SecureFieldsPaymentDetails{AmountCents: 100, StoreCard: storeCard}
+		if _, err := CreateSecureFieldsPaymentPayload("https://example.com", "JT01", "secret", "1707210770", "INV1", details, form); err != nil {
+			t.Errorf("Expected storeCard %q to be valid, got %v", storeCard, err)
+		}
+	}
+
+	details := SecureFieldsPaymentDetails{AmountCents: 100, StoreCard: "true"}
+	want := `invalid storeCard "true", expected Y or N`
+	if _, err := CreateSecureFieldsPaymentPayload("https://example.com", "JT01", "secret", "1707210770", "INV1", details, form); err == nil || err.Error() != want {
+		t.Errorf("Expected error %q, got %v", want, err)
+	}
+}
+
+func TestSecureFieldsResponseExpiry(t *testing.T) {
+	asOf := time.Date(2025, 12, 31, 23, 59, 0, 0, time.UTC)
+	testCases := []struct {
+		name        string
+		month, year string
+		wantMonth   int
+		wantYear    int
+		wantErr     string
+		wantExpired bool
+	}{
+		{name: "4-digit year", month: "12", year: "2025", wantMonth: 12, wantYear: 2025},
+		{name: "2-digit year", month: "12", year: "25", wantMonth: 12, wantYear: 2025},
+		{name: "previous month", month: "11", year: "25", wantMonth: 11, wantYear: 2025, wantExpired: true},
+		{name: "month 0", month: "0", year: "2025", wantErr: `invalid expiry month "0"`, wantExpired: true},
+		{name: "month 13", month: "13", year: "2025", wantErr: `invalid expiry month "13"`, wantExpired: true},
+		{name: "3-digit year", month: "12", year: "025", wantErr: `invalid expiry year "025"`, wantExpired: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := SecureFieldsResponse{ExpMonthCardInfo: tc.month, ExpYearCardInfo: tc.year}
+			month, year, err := resp.ExpiryDate()
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Errorf("Expected error %q, got %v", tc.wantErr, err)
+				}
+			} else if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			} else if month != tc.wantMonth || year != tc.wantYear {
+				t.Errorf("Expected %d/%d, got %d/%d", tc.wantMonth, tc.wantYear, month, year)
+			}
+			if got := resp.IsExpired(asOf); got != tc.wantExpired {
+				t.Errorf("Expected IsExpired %v, got %v", tc.wantExpired, got)
+			}
+		})
+	}
+
+	if resp := (SecureFieldsResponse{ExpMonthCardInfo: "12", ExpYearCardInfo: "25"}); !resp.IsExpired(asOf.Add(time.Minute)) {
+		t.Error("Expected card to expire after the end of its expiry month")
+	}
+}
@@ -0,0 +1,61 @@
+package api2c2p
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// Maximum lengths of the userDefined1 to userDefined5 fields
+const (
+	paymentTokenUserDefinedMaxLength = 255
+	secureFieldsUserDefinedMaxLength = 150
+)
+
+// SetUserDefined sets UserDefinedN, where n is 1 to 5
+func (r *PaymentTokenRequest) SetUserDefined(n int, value string) error {
+	return setUserDefined(r.userDefinedFields(), paymentTokenUserDefinedMaxLength, n, value)
+}
+
+// UserDefinedMap returns the non-empty UserDefinedN fields keyed by n
+func (r *PaymentTokenRequest) UserDefinedMap() map[int]string {
+	return userDefinedMap(r.userDefinedFields())
+}
+
+func (r *PaymentTokenRequest) userDefinedFields() [5]*string {
+	return [5]*string{&r.UserDefined1, &r.UserDefined2, &r.UserDefined3, &r.UserDefined4, &r.UserDefined5}
+}
+
+// SetUserDefined sets UserDefinedN, where n is 1 to 5
+func (details *SecureFieldsPaymentDetails) SetUserDefined(n int, value string) error {
+	return setUserDefined(details.userDefinedFields(), secureFieldsUserDefinedMaxLength, n, value)
+}
+
+// UserDefinedMap returns the non-empty UserDefinedN fields keyed by n
+func (details *SecureFieldsPaymentDetails) UserDefinedMap() map[int]string {
+	return userDefinedMap(details.userDefinedFields())
+}
+
+func (details *SecureFieldsPaymentDetails) userDefinedFields() [5]*string {
+	return [5]*string{&details.UserDefined1, &details.UserDefined2, &details.UserDefined3, &details.UserDefined4, &details.UserDefined5}
+}
+
+func setUserDefined(fields [5]*string, maxLength, n int, value string) error {
+	if n < 1 || n > len(fields) {
+		return fmt.Errorf("userDefined%d out of range, expected 1 to %d", n, len(fields))
+	}
+	if length := utf8.RuneCountInString(value); length > maxLength {
+		return fmt.Errorf("userDefined%d is %d characters, maximum is %d", n, length, maxLength)
+	}
+	*fields[n-1] = value
+	return nil
+}
+
+func userDefinedMap(fields [5]*string) map[int]string {
+	m := map[int]string{}
+	for i, field := range fields {
+		if *field != "" {
+			m[i+1] = *field
+		}
+	}
+	return m
+}
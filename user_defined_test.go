@@ -0,0 +1,62 @@
+package api2c2p
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPaymentTokenRequestSetUserDefined(t *testing.T) {
+	testCases := []struct {
+		name    string
+		n       int
+		value   string
+		wantErr string
+	}{
+		{name: "first", n: 1, value: "order-1"},
+		{name: "last at max length", n: 5, value: strings.Repeat("é", 255)},
+		{name: "zero", n: 0, value: "x", wantErr: "userDefined0 out of range, expected 1 to 5"},
+		{name: "six", n: 6, value: "x", wantErr: "userDefined6 out of range, expected 1 to 5"},
+		{name: "too long", n: 2, value: strings.Repeat("x", 256), wantErr: "userDefined2 is 256 characters, maximum is 255"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var req PaymentTokenRequest
+			err := req.SetUserDefined(tc.n, tc.value)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Errorf("Expected error %q, got %v", tc.wantErr, err)
+				}
+				if got := req.UserDefinedMap(); len(got) != 0 {
+					t.Errorf("Expected no field set, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetUserDefined failed: %v", err)
+			}
+			if got, want := req.UserDefinedMap(), map[int]string{tc.n: tc.value}; !reflect.DeepEqual(got, want) {
+				t.Errorf("Expected %v, got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestSecureFieldsPaymentDetailsSetUserDefined(t *testing.T) {
+	details := SecureFieldsPaymentDetails{UserDefined1: "a"}
+	if err := details.SetUserDefined(3, "c"); err != nil {
+		t.Fatalf("SetUserDefined failed: %v", err)
+	}
+	if details.UserDefined3 != "c" {
+		t.Errorf("Expected UserDefined3 c, got %q", details.UserDefined3)
+	}
+	if got, want := details.UserDefinedMap(), map[int]string{1: "a", 3: "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	// SecureFields allows fewer characters than payment token
+	if err := details.SetUserDefined(4, strings.Repeat("x", 151)); err == nil || !strings.Contains(err.Error(), "maximum is 150") {
+		t.Errorf("Expected maximum length error, got %v", err)
+	}
+}
@@ -8,12 +8,15 @@ import (
 
 // VoidCancelRequest represents a void/cancel request
 type VoidCancelRequest struct {
-	InvoiceNo       string  `xml:"invoiceNo"`
-	MerchantID      string  `xml:"merchantID,omitempty"`
-	ActionAmount    Dollars `xml:"actionAmount"`
-	ProcessType     string  `xml:"processType"` // Always "V" for void/cancel
-	IdempotencyID   *string `xml:"idempotencyID,omitempty"`
-	ChildMerchantID *string `xml:"childMerchantID,omitempty"`
+	InvoiceNo       string      `xml:"invoiceNo"`
+	MerchantID      string      `xml:"merchantID,omitempty"`
+	ActionAmount    Dollars     `xml:"actionAmount"`
+	ProcessType     ProcessType `xml:"processType"` // Always ProcessVoid
+	IdempotencyID   *string     `xml:"idempotencyID,omitempty"`
+	ChildMerchantID *string     `xml:"childMerchantID,omitempty"`
+
+	// IncludeTimestamp sends the current time as timeStamp, for configurations that require one
+	IncludeTimestamp bool `xml:"-"`
 }
 
 // VoidCancelResponse represents the response from a void/cancel request
@@ -31,6 +34,9 @@ type VoidCancelResponse struct {
 	ReferenceNo    string   `xml:"referenceNo,omitempty"`
 	TransactionID  string   `xml:"transactionID,omitempty"`
 	TransactionRef string   `xml:"transactionRef,omitempty"`
+
+	// IdempotencyID is the idempotency ID sent with the request, e.g. generated by AutoIdempotency
+	IdempotencyID string `xml:"-"`
 }
 
 // VoidCancel processes a void/cancel request for a previously successful payment
@@ -45,8 +51,15 @@ func (c *Client) VoidCancel(ctx context.Context, req *VoidCancelRequest) (*VoidC
 		req.MerchantID = c.MerchantID
 	}
 
-	// Always set process type to "V" for void/cancel
-	req.ProcessType = "V"
+	// Always set process type to void/cancel
+	req.ProcessType = ProcessVoid
+	// Generate into a local copy, so reusing req generates a new idempotency ID
+	idempotencyID := req.IdempotencyID
+	if idempotencyID == nil || *idempotencyID == "" {
+		if id := c.idempotencyID(""); id != "" {
+			idempotencyID = &id
+		}
+	}
 
 	// Create payment process request
 	processReq := &PaymentProcessRequest{
@@ -55,15 +68,28 @@ func (c *Client) VoidCancel(ctx context.Context, req *VoidCancelRequest) (*VoidC
 		InvoiceNo:       req.InvoiceNo,
 		ActionAmount:    req.ActionAmount,
 		ProcessType:     req.ProcessType,
-		IdempotencyID:   req.IdempotencyID,
+		IdempotencyID:   idempotencyID,
 		ChildMerchantID: req.ChildMerchantID,
 	}
+	if req.IncludeTimestamp {
+		processReq.TimeStamp = c.timeStamp()
+	}
 
 	// Process the request
 	var resp VoidCancelResponse
 	if err := c.PerformPaymentProcess(ctx, processReq, &resp); err != nil {
 		return nil, fmt.Errorf("failed to process void/cancel request: %w", err)
 	}
+	if idempotencyID != nil {
+		resp.IdempotencyID = *idempotencyID
+	}
+	if !PaymentResponseCode(resp.RespCode).IsSuccess() {
+		return &resp, &APIError{
+			Endpoint: "voidCancel",
+			RespCode: PaymentResponseCode(resp.RespCode),
+			RespDesc: resp.RespDesc,
+		}
+	}
 
 	return &resp, nil
 }
@@ -3,10 +3,15 @@ package api2c2p
 import (
 	"context"
 	"encoding/xml"
+	"io"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 func TestVoidCancel(t *testing.T) {
@@ -66,15 +71,10 @@ func TestVoidCancel(t *testing.T) {
 	defer ts.Close()
 
 	// Create client with test server URL
-	client, err = NewClient(Config{
-		SecretKey:                "your_secret_key",
-		MerchantID:               "JT01",
-		PaymentGatewayURL:        "https://pgw.example.com",
-		FrontendURL:              ts.URL,
-		CombinedPEM:              "testdata/combined_private_public.pem",
-		ServerJWTPublicKeyFile:   "testdata/public_cert.pem", // we have to decrypt what we encrypted in this test
-		ServerPKCS7PublicKeyFile: "testdata/server.pkcs7.public_cert.pem",
-	})
+	cfg := testConfig()
+	cfg.FrontendURL = ts.URL
+	cfg.ServerJWTPublicKeyFile = "testdata/public_cert.pem" // we have to decrypt what we encrypted in this test
+	client, err = NewClient(cfg)
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
 	}
@@ -128,3 +128,96 @@ func TestVoidCancel(t *testing.T) {
 		})
 	}
 }
+
+func TestVoidCancelIncludeTimestamp(t *testing.T) {
+	var client *Client
+	var gotRequest []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("Failed to read request body: %v", err)
+			return
+		}
+		gotRequest, err = client.verifyJWSAndDecryptJWE(string(body))
+		if err != nil {
+			t.Errorf("Failed to verify and decrypt request: %v", err)
+			return
+		}
+		signedJWE, err := client.encryptJWEAndSignJWS([]byte(`<PaymentProcessResponse><respCode>0000</respCode></PaymentProcessResponse>`))
+		if err != nil {
+			t.Errorf("Failed to encrypt response: %v", err)
+			return
+		}
+		w.Write([]byte(signedJWE))
+	}))
+	defer ts.Close()
+
+	var err error
+	cfg := testConfig()
+	cfg.FrontendURL = ts.URL
+	cfg.ServerJWTPublicKeyFile = "testdata/public_cert.pem" // we have to decrypt what we encrypted in this test
+	cfg.Clock = func() time.Time {
+		return time.Date(2025, 2, 12, 9, 2, 35, 0, time.UTC)
+	}
+	client, err = NewClient(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	if _, err := client.VoidCancel(ctx, &VoidCancelRequest{InvoiceNo: "INV123", ActionAmount: Cents(100).ToDollars()}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if strings.Contains(string(gotRequest), "timeStamp") {
+		t.Errorf("Expected no timeStamp by default, got %s", gotRequest)
+	}
+
+	if _, err := client.VoidCancel(ctx, &VoidCancelRequest{InvoiceNo: "INV123", ActionAmount: Cents(100).ToDollars(), IncludeTimestamp: true}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(string(gotRequest), "<timeStamp>120225090235</timeStamp>") {
+		t.Errorf("Expected timeStamp 120225090235, got %s", gotRequest)
+	}
+}
+
+func TestVoidCancelAutoIdempotency(t *testing.T) {
+	var client *Client
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signedJWE, err := client.encryptJWEAndSignJWS([]byte(`<PaymentProcessResponse><respCode>0000</respCode></PaymentProcessResponse>`))
+		if err != nil {
+			t.Errorf("Failed to encrypt response: %v", err)
+			return
+		}
+		w.Write([]byte(signedJWE))
+	}))
+	defer ts.Close()
+
+	var err error
+	cfg := testConfig()
+	cfg.FrontendURL = ts.URL
+	cfg.ServerJWTPublicKeyFile = "testdata/public_cert.pem" // we have to decrypt what we encrypted in this test
+	cfg.AutoIdempotency = true
+	client, err = NewClient(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	// the generated ID is not written back, so reusing the request generates a new one
+	req := &VoidCancelRequest{InvoiceNo: "INV123", ActionAmount: Cents(100).ToDollars()}
+	first, err := client.VoidCancel(ctx, req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := uuid.Parse(first.IdempotencyID); err != nil {
+		t.Errorf("Expected a UUID idempotencyID, got %q", first.IdempotencyID)
+	}
+	if req.IdempotencyID != nil {
+		t.Errorf("Expected request idempotencyID to be unchanged, got %q", *req.IdempotencyID)
+	}
+	second, err := client.VoidCancel(ctx, req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if second.IdempotencyID == first.IdempotencyID {
+		t.Errorf("Expected a new idempotencyID for a reused request, got %q twice", second.IdempotencyID)
+	}
+}